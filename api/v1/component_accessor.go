@@ -10,6 +10,7 @@ import (
 // +kubebuilder:object:root=false
 // +kubebuilder:object:generate=false
 type ComponentAccessor interface {
+	Image() string
 	ImagePullPolicy() corev1.PullPolicy
 	ImagePullSecrets() []corev1.LocalObjectReference
 	HostNetwork() bool
@@ -27,6 +28,8 @@ type ComponentAccessor interface {
 }
 
 type componentAccessorImpl struct {
+	image                     string
+	version                   string
 	imagePullPolicy           corev1.PullPolicy
 	imagePullSecrets          []corev1.LocalObjectReference
 	hostNetwork               *bool
@@ -56,6 +59,15 @@ func (a *componentAccessorImpl) StatefulSetUpdateStrategy() appsv1.StatefulSetUp
 	return appsv1.RollingUpdateStatefulSetStrategyType
 }
 
+// Image is the cluster-level image with the tag replaced by the component or cluster-level version
+func (a *componentAccessorImpl) Image() string {
+	version := a.version
+	if a.ComponentSpec.Version != nil && *a.ComponentSpec.Version != "" {
+		version = *a.ComponentSpec.Version
+	}
+	return ImageWithVersion(a.image, version)
+}
+
 func (a *componentAccessorImpl) ImagePullPolicy() corev1.PullPolicy {
 	pp := a.ComponentSpec.ImagePullPolicy
 	if pp == nil {
@@ -176,6 +188,8 @@ func (a *componentAccessorImpl) TerminationGracePeriodSeconds() *int64 {
 
 func buildSeaweedComponentAccessor(spec *SeaweedSpec, componentSpec *ComponentSpec) ComponentAccessor {
 	return &componentAccessorImpl{
+		image:                     spec.Image,
+		version:                   spec.Version,
 		imagePullPolicy:           spec.ImagePullPolicy,
 		imagePullSecrets:          spec.ImagePullSecrets,
 		hostNetwork:               spec.HostNetwork,
@@ -205,7 +219,16 @@ func (s *Seaweed) BaseVolumeSpec() ComponentAccessor {
 	return buildSeaweedComponentAccessor(&s.Spec, &s.Spec.Volume.ComponentSpec)
 }
 
+// BaseVolumePoolSpec provides merged spec of the volumes of a pool
+func (s *Seaweed) BaseVolumePoolSpec(pool *VolumePoolSpec) ComponentAccessor {
+	return buildSeaweedComponentAccessor(&s.Spec, &pool.ComponentSpec)
+}
+
 // BaseGatewaySpec provides merged spec of filers
 func (s *Seaweed) BaseGatewaySpec() ComponentAccessor {
-	return buildSeaweedComponentAccessor(&s.Spec, &s.Spec.Gateway.ComponentSpec)
+	accessor := buildSeaweedComponentAccessor(&s.Spec, &s.Spec.Gateway.ComponentSpec).(*componentAccessorImpl)
+	// the gateway is not SeaweedFS, the cluster-level image and version do not apply
+	accessor.image = s.Spec.Gateway.Image
+	accessor.version = ""
+	return accessor
 }
@@ -0,0 +1,57 @@
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetCondition returns the condition of the given type, or nil if it is not present
+func (s *SeaweedStatus) GetCondition(conditionType SeaweedConditionType) *SeaweedCondition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == conditionType {
+			return &s.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// SetCondition adds or replaces the condition of the same type.
+// LastTransitionTime is only moved forward when the status changes.
+func (s *SeaweedStatus) SetCondition(condition SeaweedCondition) {
+	existing := s.GetCondition(condition.Type)
+	if existing == nil {
+		if condition.LastTransitionTime.IsZero() {
+			condition.LastTransitionTime = metav1.Now()
+		}
+		s.Conditions = append(s.Conditions, condition)
+		return
+	}
+
+	if existing.Status != condition.Status {
+		existing.Status = condition.Status
+		existing.LastTransitionTime = condition.LastTransitionTime
+		if existing.LastTransitionTime.IsZero() {
+			existing.LastTransitionTime = metav1.Now()
+		}
+	}
+	existing.Reason = condition.Reason
+	existing.Message = condition.Message
+	existing.ObservedGeneration = condition.ObservedGeneration
+}
+
+// RemoveCondition drops the condition of the given type
+func (s *SeaweedStatus) RemoveCondition(conditionType SeaweedConditionType) {
+	conditions := s.Conditions[:0]
+	for _, c := range s.Conditions {
+		if c.Type != conditionType {
+			conditions = append(conditions, c)
+		}
+	}
+	s.Conditions = conditions
+}
+
+// IsConditionTrue reports whether the condition of the given type is present and True
+func (s *SeaweedStatus) IsConditionTrue(conditionType SeaweedConditionType) bool {
+	c := s.GetCondition(conditionType)
+	return c != nil && c.Status == corev1.ConditionTrue
+}
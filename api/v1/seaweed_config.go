@@ -0,0 +1,173 @@
+package v1
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+)
+
+// filerStores are the sections of filer.toml the filer knows as stores
+var filerStores = map[string]bool{
+	"cassandra": true, "elastic7": true, "etcd": true, "hbase": true,
+	"leveldb": true, "leveldb2": true, "leveldb3": true, "mongodb": true,
+	"mysql": true, "mysql2": true, "postgres": true, "postgres2": true,
+	"redis": true, "redis2": true, "redis3": true,
+	"redis_cluster": true, "redis_cluster2": true, "redis_cluster3": true,
+	"rocksdb": true, "sqlite": true,
+}
+
+// tomlErrorPosition matches the "(line, column): " go-toml puts in front of its syntax errors
+var tomlErrorPosition = regexp.MustCompile(`^\((\d+), (\d+)\): `)
+
+// loadConfig parses the raw toml of the field, a syntax error names its line and column
+func loadConfig(field, config string) (*toml.Tree, error) {
+	tree, err := toml.Load(config)
+	if err == nil {
+		return tree, nil
+	}
+	msg := err.Error()
+	if match := tomlErrorPosition.FindStringSubmatch(msg); match != nil {
+		return nil, fmt.Errorf("%s line %s, column %s: %s", field, match[1], match[2], msg[len(match[0]):])
+	}
+	return nil, fmt.Errorf("%s: %s", field, msg)
+}
+
+// configError reports an error at the line and column of the key in the raw toml of the field
+func configError(field string, tree *toml.Tree, key []string, format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	if position := tree.GetPositionPath(key); !position.Invalid() {
+		return fmt.Errorf("%s line %d, column %d: %s", field, position.Line, position.Col, msg)
+	}
+	return fmt.Errorf("%s: %s", field, msg)
+}
+
+// validateConfigs parses master.toml and filer.toml, and checks them against the fields of the spec
+func (r *Seaweed) validateConfigs() []error {
+	var errs []error
+	if r.Spec.Master != nil && r.Spec.Master.Config != nil {
+		errs = append(errs, r.validateMasterConfig(*r.Spec.Master.Config)...)
+	}
+	if r.Spec.Filer != nil && r.Spec.Filer.Config != nil {
+		errs = append(errs, r.validateFilerConfig(*r.Spec.Filer.Config)...)
+	}
+	return errs
+}
+
+func (r *Seaweed) validateMasterConfig(config string) []error {
+	const field = "spec.master.config"
+	tree, err := loadConfig(field, config)
+	if err != nil {
+		return []error{err}
+	}
+
+	var errs []error
+	scripts := []string{"master", "maintenance", "scripts"}
+	if r.Spec.Maintenance != nil && len(r.Spec.Maintenance.Scripts) > 0 && tree.HasPath(scripts) {
+		errs = append(errs, configError(field, tree, scripts,
+			"master.maintenance.scripts conflicts with spec.maintenance, the scripts would run twice"))
+	}
+	return errs
+}
+
+func (r *Seaweed) validateFilerConfig(config string) []error {
+	const field = "spec.filer.config"
+	tree, err := loadConfig(field, config)
+	if err != nil {
+		return []error{err}
+	}
+
+	var errs []error
+	var enabled []string
+	for _, section := range tree.Keys() {
+		table, ok := tree.Get(section).(*toml.Tree)
+		if !ok || !table.Has("enabled") {
+			continue
+		}
+		if !filerStores[section] {
+			errs = append(errs, configError(field, tree, []string{section, "enabled"},
+				"unknown filer store %s, expecting one of %s", section, strings.Join(sortedStoreNames(), ", ")))
+			continue
+		}
+		if on, _ := table.Get("enabled").(bool); on {
+			enabled = append(enabled, section)
+		}
+	}
+	sort.Strings(enabled)
+
+	store := r.Spec.Filer.Store
+	if store == nil || store.StoreName() == "" {
+		if len(enabled) > 1 {
+			// reported where the last of them is enabled
+			last := enabled[0]
+			for _, section := range enabled {
+				if tree.GetPositionPath([]string{section, "enabled"}).Line > tree.GetPositionPath([]string{last, "enabled"}).Line {
+					last = section
+				}
+			}
+			errs = append(errs, configError(field, tree, []string{last, "enabled"},
+				"enables more than one filer store: %s", strings.Join(enabled, ", ")))
+		}
+		return errs
+	}
+
+	name := store.StoreName()
+	for _, section := range enabled {
+		if section != name {
+			errs = append(errs, configError(field, tree, []string{section, "enabled"},
+				"enables %s next to %s of spec.filer.store, the filer runs only one store", section, name))
+		}
+	}
+	if on, isBool := tree.GetPath([]string{name, "enabled"}).(bool); isBool && !on {
+		errs = append(errs, configError(field, tree, []string{name, "enabled"},
+			"disables %s of spec.filer.store", name))
+	}
+	for _, key := range renderedKeys(store.render(r.Spec.Filer.Storage)) {
+		if key[len(key)-1] != "enabled" && tree.HasPath(key) {
+			errs = append(errs, configError(field, tree, key,
+				"%s is rendered from spec.filer.store, the config would override it", strings.Join(key, ".")))
+		}
+	}
+	secretOptions := store.SecretOptions()
+	var options []string
+	for option := range secretOptions {
+		options = append(options, option)
+	}
+	sort.Strings(options)
+	for _, option := range options {
+		if key := []string{name, option}; tree.HasPath(key) {
+			errs = append(errs, configError(field, tree, key,
+				"%s.%s is overridden by the Secret reference in spec.filer.store", name, option))
+		}
+	}
+	return errs
+}
+
+// renderedKeys are the paths of the values in the rendered toml tables, sorted
+func renderedKeys(rendered map[string]interface{}) [][]string {
+	var keys [][]string
+	for k, v := range rendered {
+		if table, ok := v.(map[string]interface{}); ok {
+			for _, key := range renderedKeys(table) {
+				keys = append(keys, append([]string{k}, key...))
+			}
+			continue
+		}
+		keys = append(keys, []string{k})
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return strings.Join(keys[i], ".") < strings.Join(keys[j], ".")
+	})
+	return keys
+}
+
+func sortedStoreNames() []string {
+	var names []string
+	for name := range filerStores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,281 @@
+package v1
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	postgresUpsertQuery = `INSERT INTO "%[1]s" (dirhash,name,directory,meta) VALUES($1,$2,$3,$4) ON CONFLICT (dirhash,name) DO UPDATE SET meta = EXCLUDED.meta WHERE "%[1]s".meta != EXCLUDED.meta`
+	mysqlUpsertQuery    = "INSERT INTO `%s` (dirhash,name,directory,meta) VALUES(?,?,?,?) ON DUPLICATE KEY UPDATE meta = VALUES(meta)"
+)
+
+// FilerToml renders the store into filer.toml and merges config over it.
+// Without a store, config is used verbatim.
+func (s *FilerSpec) FilerToml() (string, error) {
+	config := ""
+	if s.Config != nil {
+		config = *s.Config
+	}
+	if s.Store == nil {
+		return config, nil
+	}
+
+	rendered := s.Store.render(s.Storage)
+	if strings.TrimSpace(config) != "" {
+		tree, err := toml.Load(config)
+		if err != nil {
+			return "", fmt.Errorf("can not parse the filer config: %v", err)
+		}
+		mergeTomlMaps(rendered, tree.ToMap())
+	}
+	tree, err := toml.TreeFromMap(rendered)
+	if err != nil {
+		return "", err
+	}
+	return tree.ToTomlString()
+}
+
+// StoreName is the filer.toml section of the store, empty if no store is set
+func (s *FilerStoreSpec) StoreName() string {
+	names := s.storeNames()
+	if len(names) == 0 {
+		return ""
+	}
+	return names[0]
+}
+
+func (s *FilerStoreSpec) storeNames() []string {
+	var names []string
+	if s.LevelDB2 != nil {
+		names = append(names, "leveldb2")
+	}
+	if s.Postgres != nil {
+		names = append(names, "postgres")
+	}
+	if s.MySQL != nil {
+		names = append(names, "mysql")
+	}
+	if s.Redis != nil {
+		names = append(names, "redis2")
+	}
+	if s.Cassandra != nil {
+		names = append(names, "cassandra")
+	}
+	if s.Etcd != nil {
+		names = append(names, "etcd")
+	}
+	if s.MongoDB != nil {
+		names = append(names, "mongodb")
+	}
+	if s.Elastic != nil {
+		names = append(names, "elastic7")
+	}
+	return names
+}
+
+// render builds the filer.toml section of the store with the defaults of "weed scaffold -config=filer",
+// the stores read a missing option as its zero value
+func (s *FilerStoreSpec) render(storage *StorageSpec) map[string]interface{} {
+	section := map[string]interface{}{"enabled": true}
+	switch {
+	case s.LevelDB2 != nil:
+		section["dir"] = s.LevelDB2.DirOrDefault(storage)
+	case s.Postgres != nil:
+		p := s.Postgres
+		section["enableUpsert"] = true
+		section["upsertQuery"] = postgresUpsertQuery
+		section["hostname"] = p.Hostname
+		section["port"] = int64(int32Value(p.Port, 5432))
+		section["database"] = stringOrDefault(p.Database, "postgres")
+		section["schema"] = p.Schema
+		section["sslmode"] = stringOrDefault(p.SSLMode, "disable")
+		section["connection_max_idle"] = int64(int32Value(p.ConnectionMaxIdle, 100))
+		section["connection_max_open"] = int64(int32Value(p.ConnectionMaxOpen, 100))
+		section["connection_max_lifetime_seconds"] = int64(int32Value(p.ConnectionMaxLifetimeSeconds, 0))
+		p.StoreCredentials.render(section, "postgres")
+	case s.MySQL != nil:
+		p := s.MySQL
+		section["enableUpsert"] = true
+		section["upsertQuery"] = mysqlUpsertQuery
+		section["hostname"] = p.Hostname
+		section["port"] = int64(int32Value(p.Port, 3306))
+		section["database"] = p.Database
+		section["connection_max_idle"] = int64(int32Value(p.ConnectionMaxIdle, 2))
+		section["connection_max_open"] = int64(int32Value(p.ConnectionMaxOpen, 100))
+		section["connection_max_lifetime_seconds"] = int64(int32Value(p.ConnectionMaxLifetimeSeconds, 0))
+		section["interpolateParams"] = p.InterpolateParams != nil && *p.InterpolateParams
+		p.StoreCredentials.render(section, "root")
+	case s.Redis != nil:
+		section["address"] = s.Redis.Address
+		section["database"] = int64(int32Value(s.Redis.Database, 0))
+		section["superLargeDirectories"] = stringSlice(s.Redis.SuperLargeDirectories)
+	case s.Cassandra != nil:
+		section["hosts"] = stringSlice(s.Cassandra.Hosts)
+		section["keyspace"] = stringOrDefault(s.Cassandra.Keyspace, "seaweedfs")
+		section["localDC"] = s.Cassandra.LocalDC
+		section["superLargeDirectories"] = stringSlice(s.Cassandra.SuperLargeDirectories)
+		s.Cassandra.StoreCredentials.render(section, "")
+	case s.Etcd != nil:
+		section["servers"] = s.Etcd.Servers
+		section["timeout"] = stringOrDefault(s.Etcd.Timeout, "3s")
+	case s.MongoDB != nil:
+		section["uri"] = s.MongoDB.URI
+		section["database"] = stringOrDefault(s.MongoDB.Database, "seaweedfs")
+		section["option_pool_size"] = int64(int32Value(s.MongoDB.OptionPoolSize, 0))
+	case s.Elastic != nil:
+		section["servers"] = stringSlice(s.Elastic.Servers)
+		section["sniff_enabled"] = s.Elastic.SniffEnabled != nil && *s.Elastic.SniffEnabled
+		section["healthcheck_enabled"] = s.Elastic.HealthcheckEnabled != nil && *s.Elastic.HealthcheckEnabled
+		section["index"] = map[string]interface{}{
+			"max_result_window": int64(int32Value(s.Elastic.MaxResultWindow, 10000)),
+		}
+		s.Elastic.StoreCredentials.render(section, "")
+	default:
+		return map[string]interface{}{}
+	}
+	return map[string]interface{}{s.StoreName(): section}
+}
+
+// SecretOptions are the options of the store whose values come from Secrets, the filer reads them from
+// WEED_<STORE>_<OPTION> environment variables
+func (s *FilerStoreSpec) SecretOptions() map[string]*corev1.SecretKeySelector {
+	options := make(map[string]*corev1.SecretKeySelector)
+	add := func(option string, selector *corev1.SecretKeySelector) {
+		if selector != nil {
+			options[option] = selector
+		}
+	}
+	credentials := func(c StoreCredentials) {
+		add("username", c.UsernameSecretRef)
+		add("password", c.PasswordSecretRef)
+	}
+	switch {
+	case s.Postgres != nil:
+		credentials(s.Postgres.StoreCredentials)
+	case s.MySQL != nil:
+		credentials(s.MySQL.StoreCredentials)
+	case s.Cassandra != nil:
+		credentials(s.Cassandra.StoreCredentials)
+	case s.Elastic != nil:
+		credentials(s.Elastic.StoreCredentials)
+	case s.Redis != nil:
+		add("password", s.Redis.PasswordSecretRef)
+	case s.MongoDB != nil:
+		add("uri", s.MongoDB.URISecretRef)
+	}
+	return options
+}
+
+// render puts the plain username into the section, the Secret references are passed in environment variables
+func (c *StoreCredentials) render(section map[string]interface{}, defaultUsername string) {
+	if c.UsernameSecretRef == nil {
+		section["username"] = stringOrDefault(c.Username, defaultUsername)
+	}
+}
+
+// DirOrDefault is the dir of the database, filerldb2 below the mountPath of the storage by default
+func (s *LevelDB2StoreSpec) DirOrDefault(storage *StorageSpec) string {
+	switch {
+	case s.Dir != "":
+		return s.Dir
+	case storage != nil:
+		return path.Join(storage.MountPathOrDefault(), "filerldb2")
+	default:
+		return "./filerldb2"
+	}
+}
+
+// validate checks only one store is set and it has the options to connect
+func (s *FilerStoreSpec) validate(field string) []error {
+	var errs []error
+	if names := s.storeNames(); len(names) > 1 {
+		errs = append(errs, fmt.Errorf("%s sets more than one store: %s", field, strings.Join(names, ", ")))
+	}
+	switch {
+	case s.Postgres != nil:
+		errs = append(errs, validateStoreHost(field+".postgres", s.Postgres.Hostname, s.Postgres.Port)...)
+	case s.MySQL != nil:
+		errs = append(errs, validateStoreHost(field+".mysql", s.MySQL.Hostname, s.MySQL.Port)...)
+	case s.Redis != nil:
+		if s.Redis.Address == "" {
+			errs = append(errs, fmt.Errorf("%s.redis.address is required", field))
+		}
+	case s.Cassandra != nil:
+		if len(s.Cassandra.Hosts) == 0 {
+			errs = append(errs, fmt.Errorf("%s.cassandra.hosts is required", field))
+		}
+	case s.Etcd != nil:
+		if s.Etcd.Servers == "" {
+			errs = append(errs, fmt.Errorf("%s.etcd.servers is required", field))
+		}
+	case s.MongoDB != nil:
+		if s.MongoDB.URI == "" && s.MongoDB.URISecretRef == nil {
+			errs = append(errs, fmt.Errorf("%s.mongodb needs uri or uriSecretRef", field))
+		}
+	case s.Elastic != nil:
+		if len(s.Elastic.Servers) == 0 {
+			errs = append(errs, fmt.Errorf("%s.elastic.servers is required", field))
+		}
+	}
+	return errs
+}
+
+func validateStoreHost(field, hostname string, port *int32) []error {
+	var errs []error
+	if hostname == "" {
+		errs = append(errs, fmt.Errorf("%s.hostname is required", field))
+	}
+	if port != nil && *port <= 0 {
+		errs = append(errs, fmt.Errorf("%s.port must be positive", field))
+	}
+	return errs
+}
+
+// validateFilerStore checks the typed filer store, config is checked against it by validateConfigs
+func (r *Seaweed) validateFilerStore() []error {
+	if r.Spec.Filer == nil || r.Spec.Filer.Store == nil {
+		return nil
+	}
+	return r.Spec.Filer.Store.validate("spec.filer.store")
+}
+
+// mergeTomlMaps merges the tables of src into dst, the values of src win
+func mergeTomlMaps(dst, src map[string]interface{}) {
+	for k, v := range src {
+		srcTable, srcIsTable := v.(map[string]interface{})
+		dstTable, dstIsTable := dst[k].(map[string]interface{})
+		if srcIsTable && dstIsTable {
+			mergeTomlMaps(dstTable, srcTable)
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+func int32Value(i *int32, defaultValue int32) int32 {
+	if i == nil {
+		return defaultValue
+	}
+	return *i
+}
+
+func stringOrDefault(s, defaultValue string) string {
+	if s == "" {
+		return defaultValue
+	}
+	return s
+}
+
+// stringSlice converts to the array type go-toml writes
+func stringSlice(s []string) []interface{} {
+	values := make([]interface{}, 0, len(s))
+	for _, v := range s {
+		values = append(values, v)
+	}
+	return values
+}
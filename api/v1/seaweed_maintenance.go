@@ -0,0 +1,93 @@
+package v1
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Location returns the time zone of the schedules and windows
+func (m *MaintenanceSpec) Location() (*time.Location, error) {
+	if m.TimeZone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(m.TimeZone)
+}
+
+// InWindow reports whether scripts may start at t
+func (m *MaintenanceSpec) InWindow(t time.Time) (bool, error) {
+	if len(m.Windows) == 0 {
+		return true, nil
+	}
+	loc, err := m.Location()
+	if err != nil {
+		return false, err
+	}
+	for _, w := range m.Windows {
+		in, err := w.Contains(t.In(loc))
+		if err != nil {
+			return false, err
+		}
+		if in {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Contains reports whether the clock time of t is within the window
+func (w *MaintenanceWindow) Contains(t time.Time) (bool, error) {
+	start, err := parseClock(w.Start)
+	if err != nil {
+		return false, err
+	}
+	end, err := parseClock(w.End)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	if start <= end {
+		return start <= now && now < end, nil
+	}
+	return now >= start || now < end, nil
+}
+
+// ParseSchedule parses the cron schedule of the script
+func (s *MaintenanceScript) ParseSchedule() (cron.Schedule, error) {
+	return cron.ParseStandard(s.Schedule)
+}
+
+// parseClock parses "15:04" into the time since midnight
+func parseClock(clock string) (time.Duration, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expecting HH:MM", clock)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (m *MaintenanceSpec) validate() []error {
+	var errs []error
+	if _, err := m.Location(); err != nil {
+		errs = append(errs, fmt.Errorf("maintenance time zone: %v", err))
+	}
+	for _, w := range m.Windows {
+		if _, err := w.Contains(time.Now()); err != nil {
+			errs = append(errs, fmt.Errorf("maintenance window: %v", err))
+		}
+	}
+	names := make(map[string]bool)
+	for _, s := range m.Scripts {
+		if names[s.Name] {
+			errs = append(errs, fmt.Errorf("maintenance script %s is defined twice", s.Name))
+		}
+		names[s.Name] = true
+		if _, err := s.ParseSchedule(); err != nil {
+			errs = append(errs, fmt.Errorf("maintenance script %s schedule: %v", s.Name, err))
+		}
+		errs = append(errs, validateCommands(fmt.Sprintf("maintenance script %s commands", s.Name), s.Commands)...)
+	}
+	return errs
+}
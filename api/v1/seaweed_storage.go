@@ -0,0 +1,145 @@
+package v1
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+)
+
+// DefaultStorageMountPath is where a StorageSpec without mountPath is mounted
+const DefaultStorageMountPath = "/data"
+
+// embeddedFilerStores are the filer stores which keep the metadata on the local disk of the filer,
+// mapped to their option naming the location
+var embeddedFilerStores = map[string]string{
+	"leveldb2": "dir",
+	"leveldb3": "dir",
+	"rocksdb":  "dir",
+	"sqlite":   "dbFile",
+}
+
+// MountPathOrDefault is the mountPath, or /data if it is empty
+func (s *StorageSpec) MountPathOrDefault() string {
+	if s.MountPath == "" {
+		return DefaultStorageMountPath
+	}
+	return s.MountPath
+}
+
+// validate checks the PVC of the storage can be provisioned
+func (s *StorageSpec) validate(field string) []error {
+	var errs []error
+	if s.Size.Sign() <= 0 {
+		errs = append(errs, fmt.Errorf("%s.size must be positive", field))
+	}
+	if s.MountPath != "" && !path.IsAbs(s.MountPath) {
+		errs = append(errs, fmt.Errorf("%s.mountPath %q must be absolute", field, s.MountPath))
+	}
+	return errs
+}
+
+// validateUpdate refuses to change the PVC, the volumeClaimTemplates of a StatefulSet are immutable.
+// Adding or removing the storage is allowed, the operator migrates the pods.
+func (s *StorageSpec) validateUpdate(field string, old *StorageSpec) []error {
+	if s == nil || old == nil {
+		return nil
+	}
+	var errs []error
+	if s.Size.Cmp(old.Size) != 0 {
+		errs = append(errs, fmt.Errorf("%s.size can not be changed from %s", field, old.Size.String()))
+	}
+	if stringValue(s.StorageClassName) != stringValue(old.StorageClassName) {
+		errs = append(errs, fmt.Errorf("%s.storageClassName can not be changed from %q", field, stringValue(old.StorageClassName)))
+	}
+	if s.MountPathOrDefault() != old.MountPathOrDefault() {
+		errs = append(errs, fmt.Errorf("%s.mountPath can not be changed from %s", field, old.MountPathOrDefault()))
+	}
+	return errs
+}
+
+// validateStorage checks the storage of the masters and filers
+func (r *Seaweed) validateStorage() []error {
+	var errs []error
+	if r.Spec.Master != nil && r.Spec.Master.Storage != nil {
+		errs = append(errs, r.Spec.Master.Storage.validate("spec.master.storage")...)
+	}
+	if r.Spec.Filer != nil && r.Spec.Filer.Storage != nil {
+		errs = append(errs, r.Spec.Filer.Storage.validate("spec.filer.storage")...)
+	}
+	return errs
+}
+
+// validateStorageUpdate checks the storage of the masters and filers did not change
+func (r *Seaweed) validateStorageUpdate(old *Seaweed) []error {
+	var errs []error
+	if r.Spec.Master != nil && old.Spec.Master != nil {
+		errs = append(errs, r.Spec.Master.Storage.validateUpdate("spec.master.storage", old.Spec.Master.Storage)...)
+	}
+	if r.Spec.Filer != nil && old.Spec.Filer != nil {
+		errs = append(errs, r.Spec.Filer.Storage.validateUpdate("spec.filer.storage", old.Spec.Filer.Storage)...)
+	}
+	return errs
+}
+
+// FilerStoreWarnings reports the embedded filer stores enabled in filer.toml which would lose their
+// metadata when a filer restarts, because spec.filer.storage is not set or the store is not below its mountPath
+func (r *Seaweed) FilerStoreWarnings() []string {
+	if r.Spec.Filer == nil {
+		return nil
+	}
+	config, err := r.Spec.Filer.FilerToml()
+	if err != nil {
+		return nil
+	}
+	tree, err := toml.Load(config)
+	if err != nil {
+		return nil
+	}
+
+	var warnings []string
+	for _, store := range sortedKeys(embeddedFilerStores) {
+		if enabled, _ := tree.Get(store + ".enabled").(bool); !enabled {
+			continue
+		}
+		option := embeddedFilerStores[store]
+		location, _ := tree.Get(store + "." + option).(string)
+		storage := r.Spec.Filer.Storage
+		switch {
+		case storage == nil:
+			warnings = append(warnings, fmt.Sprintf("filer store %s keeps its metadata in the container filesystem, "+
+				"it is lost whenever a filer restarts unless spec.filer.storage is set", store))
+		case !isBelow(location, storage.MountPathOrDefault()):
+			warnings = append(warnings, fmt.Sprintf("filer store %s keeps its metadata in %s=%q, "+
+				"which is not below spec.filer.storage.mountPath %s", store, option, location, storage.MountPathOrDefault()))
+		}
+	}
+	return warnings
+}
+
+// isBelow reports whether the file is inside the directory
+func isBelow(file, dir string) bool {
+	if !path.IsAbs(file) {
+		return false
+	}
+	dir = path.Clean(dir)
+	return strings.HasPrefix(path.Clean(file), strings.TrimSuffix(dir, "/")+"/")
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
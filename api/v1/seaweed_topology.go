@@ -0,0 +1,104 @@
+package v1
+
+import (
+	"fmt"
+)
+
+const (
+	// DefaultDataCenter is the data center of volume servers started without -dataCenter
+	DefaultDataCenter = "DefaultDataCenter"
+	// DefaultRack is the rack of volume servers started without -rack
+	DefaultRack = "DefaultRack"
+)
+
+// GetDataCenterLabel returns the node label holding the data center
+func (t *VolumeTopologySpec) GetDataCenterLabel() string {
+	if t.DataCenterLabel != nil && *t.DataCenterLabel != "" {
+		return *t.DataCenterLabel
+	}
+	return "topology.kubernetes.io/region"
+}
+
+// GetRackLabel returns the node label holding the rack
+func (t *VolumeTopologySpec) GetRackLabel() string {
+	if t.RackLabel != nil && *t.RackLabel != "" {
+		return *t.RackLabel
+	}
+	return "topology.kubernetes.io/zone"
+}
+
+// ReplicaPlacement is a parsed "xyz" replication string:
+// x copies in other data centers, y copies in other racks of the same data center,
+// and z copies on other servers of the same rack
+type ReplicaPlacement struct {
+	DiffDataCenterCount int
+	DiffRackCount       int
+	SameRackCount       int
+}
+
+// ParseReplicaPlacement parses a replication string like "010"
+func ParseReplicaPlacement(s string) (ReplicaPlacement, error) {
+	if len(s) != 3 {
+		return ReplicaPlacement{}, fmt.Errorf("replication %q must have 3 digits", s)
+	}
+	var counts [3]int
+	for i, c := range s {
+		if c < '0' || c > '9' {
+			return ReplicaPlacement{}, fmt.Errorf("replication %q must have 3 digits", s)
+		}
+		counts[i] = int(c - '0')
+	}
+	return ReplicaPlacement{
+		DiffDataCenterCount: counts[0],
+		DiffRackCount:       counts[1],
+		SameRackCount:       counts[2],
+	}, nil
+}
+
+// Copies is the number of copies of every volume
+func (rp ReplicaPlacement) Copies() int {
+	return rp.DiffDataCenterCount + rp.DiffRackCount + rp.SameRackCount + 1
+}
+
+// validateDefaultReplication checks the replication can be placed at all with the volume servers of the spec
+func (r *Seaweed) validateDefaultReplication() []error {
+	if r.Spec.Master == nil || r.Spec.Master.DefaultReplication == nil {
+		return nil
+	}
+	replication := *r.Spec.Master.DefaultReplication
+	rp, err := ParseReplicaPlacement(replication)
+	if err != nil {
+		return []error{fmt.Errorf("master defaultReplication: %v", err)}
+	}
+
+	var errs []error
+	if count := r.volumeServerCount(); int(count) < rp.Copies() {
+		errs = append(errs, fmt.Errorf("master defaultReplication %s needs %d volume servers, only %d are requested",
+			replication, rp.Copies(), count))
+	}
+	if (rp.DiffDataCenterCount > 0 || rp.DiffRackCount > 0) && !r.hasVolumeTopology() {
+		errs = append(errs, fmt.Errorf("master defaultReplication %s places copies in other data centers or racks, "+
+			"which needs volume.topology or volume pools with a data center or rack", replication))
+	}
+	return errs
+}
+
+// CanPlace reports whether a volume can be placed on the servers, counted per rack per data center.
+// Like the master, it needs a data center with enough racks, one of them with enough servers,
+// and enough other data centers.
+func (rp ReplicaPlacement) CanPlace(servers map[string]map[string]int) bool {
+	if len(servers) < rp.DiffDataCenterCount+1 {
+		return false
+	}
+	for _, racks := range servers {
+		if len(racks) < rp.DiffRackCount+1 {
+			continue
+		}
+		for _, count := range racks {
+			if count >= rp.SameRackCount+1 {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -19,6 +19,7 @@ package v1
 import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -72,6 +73,14 @@ type SeaweedSpec struct {
 	// Persistent volume reclaim policy
 	PVReclaimPolicy *corev1.PersistentVolumeReclaimPolicy `json:"pvReclaimPolicy,omitempty"`
 
+	// DeletionPolicy decides what happens to the PVCs, PVs and generated Secrets/ConfigMaps when the Seaweed is deleted
+	// +kubebuilder:default:=Retain
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// VolumeSnapshotClassName is used for the snapshots taken by the Snapshot deletion policy.
+	// The default VolumeSnapshotClass is used if empty.
+	VolumeSnapshotClassName *string `json:"volumeSnapshotClassName,omitempty"`
+
 	// ImagePullPolicy of pods
 	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
 
@@ -103,17 +112,477 @@ type SeaweedSpec struct {
 
 	VolumeServerDiskCount int32 `json:"volumeServerDiskCount,omitempty"`
 
+	// VolumePools are additional groups of volume servers, each with its own StatefulSet.
+	// They register with the same masters as the volume servers of spec.volume.
+	VolumePools []VolumePoolSpec `json:"volumePools,omitempty"`
+
 	// Ingresses
 	HostSuffix *string `json:"hostSuffix,omitempty"`
 
 	// Gateway
 	Gateway *GatewaySpec `json:"gateway,omitempty"`
+
+	// Maintenance runs weed shell scripts on a schedule
+	Maintenance *MaintenanceSpec `json:"maintenance,omitempty"`
+}
+
+// MaintenanceSpec configures scheduled weed shell scripts
+type MaintenanceSpec struct {
+	// TimeZone of the schedules and windows, e.g. "Europe/Berlin", UTC if empty
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Windows limit when scripts may start, scripts start at any time if empty
+	Windows []MaintenanceWindow `json:"windows,omitempty"`
+
+	// Scripts to run
+	Scripts []MaintenanceScript `json:"scripts,omitempty"`
+}
+
+// MaintenanceWindow is a daily time range, it spans midnight if End is before Start
+type MaintenanceWindow struct {
+	// Start in "15:04" format
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+
+	// End in "15:04" format
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	End string `json:"end"`
+}
+
+// MaintenanceScript is a list of weed shell commands run under the shell lock on a cron schedule
+type MaintenanceScript struct {
+	// Name identifies the script in the status
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Schedule in cron format, e.g. "0 3 * * *"
+	Schedule string `json:"schedule"`
+
+	// Commands are weed shell commands, e.g. "volume.vacuum" or "volume.fix.replication"
+	// +kubebuilder:validation:MinItems=1
+	Commands []string `json:"commands"`
+}
+
+// DeletionPolicy describes what happens to the data of a cluster when it is deleted
+// +kubebuilder:validation:Enum=Retain;Delete;Snapshot
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyRetain keeps the PVCs, switches their PVs to the Retain reclaim policy
+	// and orphans the generated Secrets and ConfigMaps
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+	// DeletionPolicyDelete deletes the PVCs together with their PVs, and the generated Secrets and ConfigMaps
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicySnapshot takes a VolumeSnapshot of every PVC before deleting the PVCs,
+	// the generated Secrets and ConfigMaps are orphaned so the cluster can be restored
+	DeletionPolicySnapshot DeletionPolicy = "Snapshot"
+)
+
+// SeaweedPhase is a summary of the cluster state
+type SeaweedPhase string
+
+const (
+	// SeaweedPhaseCreating means the cluster has never been fully ready yet
+	SeaweedPhaseCreating SeaweedPhase = "Creating"
+	// SeaweedPhaseRunning means all components have all their replicas ready
+	SeaweedPhaseRunning SeaweedPhase = "Running"
+	// SeaweedPhaseUpgrading means some component is rolling out a new revision
+	SeaweedPhaseUpgrading SeaweedPhase = "Upgrading"
+	// SeaweedPhaseDegraded means some replicas are not ready but the cluster still serves
+	SeaweedPhaseDegraded SeaweedPhase = "Degraded"
+	// SeaweedPhaseFailed means the reconciliation failed with an error a retry does not fix or which persists,
+	// or the masters lost their quorum
+	SeaweedPhaseFailed SeaweedPhase = "Failed"
+	// SeaweedPhaseDeleting means the deletion policy is being applied
+	SeaweedPhaseDeleting SeaweedPhase = "Deleting"
+)
+
+// SeaweedConditionType is the type of a SeaweedCondition
+type SeaweedConditionType string
+
+const (
+	// MastersReady indicates whether all master replicas are ready
+	MastersReady SeaweedConditionType = "MastersReady"
+	// VolumesReady indicates whether all volume server replicas are ready
+	VolumesReady SeaweedConditionType = "VolumesReady"
+	// FilersReady indicates whether all filer replicas are ready
+	FilersReady SeaweedConditionType = "FilersReady"
+	// GatewayReady indicates whether all s3 gateway replicas are ready
+	GatewayReady SeaweedConditionType = "GatewayReady"
+	// IngressReady indicates whether the ingress has been created
+	IngressReady SeaweedConditionType = "IngressReady"
+	// Degraded indicates that the masters have no leader or volume servers stopped heartbeating
+	Degraded SeaweedConditionType = "Degraded"
+	// ReplicationSatisfiable indicates whether the heartbeating volume servers span enough
+	// data centers, racks and servers for the default replication of the masters
+	ReplicationSatisfiable SeaweedConditionType = "ReplicationSatisfiable"
+	// UpgradeHealthy indicates whether the masters report the components an upgrade waits for as healthy,
+	// Unknown while they can not tell and the upgrade relies on the readiness of the replicas
+	UpgradeHealthy SeaweedConditionType = "UpgradeHealthy"
+	// Reconciled indicates whether the last reconciliation succeeded.
+	// While it is False, its LastTransitionTime is when the consecutive reconcile errors began.
+	Reconciled SeaweedConditionType = "Reconciled"
+)
+
+// SeaweedCondition describes one aspect of the cluster state.
+// It follows the layout of metav1.Condition, which is not available in the apimachinery version we build against.
+type SeaweedCondition struct {
+	// Type of the condition
+	Type SeaweedConditionType `json:"type"`
+
+	// Status of the condition, one of True, False, Unknown
+	Status corev1.ConditionStatus `json:"status"`
+
+	// ObservedGeneration is the .metadata.generation the condition was set upon
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastTransitionTime is the last time the condition changed its status
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+
+	// Reason is a CamelCase reason for the last transition
+	Reason string `json:"reason,omitempty"`
+
+	// Message is a human readable message about the last transition
+	Message string `json:"message,omitempty"`
+}
+
+// ComponentStatus is the observed state of the pods of one component
+type ComponentStatus struct {
+	// Replicas is the desired number of replicas
+	Replicas int32 `json:"replicas"`
+
+	// ReadyReplicas is the number of replicas with a Ready condition
+	ReadyReplicas int32 `json:"readyReplicas"`
+
+	// UpdatedReplicas is the number of replicas running the latest revision
+	UpdatedReplicas int32 `json:"updatedReplicas,omitempty"`
+
+	// Version is the image tag every replica runs, it changes once an upgrade of the component finished
+	Version string `json:"version,omitempty"`
+}
+
+// UpgradeStatus is the progress of a version upgrade, which goes through the masters,
+// the volume servers pool by pool, the filers and the gateway in this order
+type UpgradeStatus struct {
+	// Component being upgraded: master, volume, volume-<pool>, filer or gateway
+	Component string `json:"component"`
+
+	// FromVersion is the version the component ran before
+	FromVersion string `json:"fromVersion,omitempty"`
+
+	// ToVersion is the version the component is upgraded to
+	ToVersion string `json:"toVersion,omitempty"`
+
+	// StartTime is when the upgrade of the cluster started
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// Message describes the current step
+	Message string `json:"message,omitempty"`
+}
+
+// DeletionStatus is the progress of the deletion policy
+type DeletionStatus struct {
+	// Policy being applied
+	Policy DeletionPolicy `json:"policy"`
+
+	// StartTime is when the deletion was first observed
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// PendingPersistentVolumeClaims are the PVCs the policy has not been applied to yet
+	PendingPersistentVolumeClaims []string `json:"pendingPersistentVolumeClaims,omitempty"`
+
+	// Snapshots are the VolumeSnapshots taken by the Snapshot policy
+	Snapshots []string `json:"snapshots,omitempty"`
+
+	// Message describes the current step
+	Message string `json:"message,omitempty"`
+}
+
+// VolumeScaleInStatus is the progress of draining the departing volume servers before the StatefulSet is scaled in
+type VolumeScaleInStatus struct {
+	// Pool is the volume pool being scaled in, empty for spec.volume
+	Pool string `json:"pool,omitempty"`
+
+	// FromReplicas is the replica count before the scale-in
+	FromReplicas int32 `json:"fromReplicas"`
+
+	// ToReplicas is the requested replica count
+	ToReplicas int32 `json:"toReplicas"`
+
+	// StartTime is when the drain started
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// DrainingServers are the volume servers being emptied
+	DrainingServers []string `json:"drainingServers,omitempty"`
+
+	// ReadonlyVolumes are the volume ids marked readonly for the move, they are marked writable again afterwards
+	ReadonlyVolumes []uint32 `json:"readonlyVolumes,omitempty"`
+
+	// RemainingVolumes is the number of volumes and EC shards still on the draining servers
+	RemainingVolumes int32 `json:"remainingVolumes"`
+
+	// Message describes the current step
+	Message string `json:"message,omitempty"`
+}
+
+// VolumeExpansionStatus is the progress of growing the PVCs of a volume pool
+type VolumeExpansionStatus struct {
+	// Pool is the volume pool being expanded, empty for spec.volume
+	Pool string `json:"pool,omitempty"`
+
+	// StartTime is when the larger size was first observed
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// PendingPersistentVolumeClaims are the PVCs which are not resized yet
+	PendingPersistentVolumeClaims []string `json:"pendingPersistentVolumeClaims,omitempty"`
+
+	// Failed is true when the PVCs can not be expanded, e.g. because the StorageClass does not allow it
+	Failed bool `json:"failed,omitempty"`
+
+	// Message describes the current step or the failure
+	Message string `json:"message,omitempty"`
+}
+
+// VolumeDiskMigrationStatus is the progress of adding or removing disks of a volume pool
+type VolumeDiskMigrationStatus struct {
+	// Pool is the volume pool being migrated, empty for spec.volume
+	Pool string `json:"pool,omitempty"`
+
+	// AddedDisks get new PVCs
+	AddedDisks []string `json:"addedDisks,omitempty"`
+
+	// RemovedDisks are evacuated before they are dropped from the volume servers
+	RemovedDisks []string `json:"removedDisks,omitempty"`
+
+	// StartTime is when the changed disks were first observed
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// Evacuated is true once no volume is left on the removed disks
+	Evacuated bool `json:"evacuated,omitempty"`
+
+	// Message describes the current step or the failure
+	Message string `json:"message,omitempty"`
+}
+
+// VolumeRolloutStatus is the progress of the operator restarting the volume servers of a pool
+// which uses the OnDelete update strategy
+type VolumeRolloutStatus struct {
+	// Pool is the volume pool being rolled out, empty for spec.volume
+	Pool string `json:"pool,omitempty"`
+
+	// Revision of the StatefulSet the pods are restarted into
+	Revision string `json:"revision,omitempty"`
+
+	// StartTime is when the outdated pods were first observed
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// RestartingServer is the volume server restarted last, until it registered with the master again
+	RestartingServer string `json:"restartingServer,omitempty"`
+
+	// Volumes were on the restarting server, each of them must have a replica again before the next restart
+	Volumes []uint32 `json:"volumes,omitempty"`
+
+	// UpdatedReplicas is the number of volume servers running the revision
+	UpdatedReplicas int32 `json:"updatedReplicas,omitempty"`
+
+	// Message describes the current step
+	Message string `json:"message,omitempty"`
+}
+
+// MasterRolloutStatus reports the masters restarted by the operator with the OnDelete update strategy
+type MasterRolloutStatus struct {
+	// Revision of the StatefulSet the masters are restarted into
+	Revision string `json:"revision,omitempty"`
+
+	// StartTime is when the outdated masters were first observed
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// Blocked is true while the rollout can not restart the next master
+	Blocked bool `json:"blocked,omitempty"`
+
+	// Message describes the current step or why the rollout is blocked
+	Message string `json:"message,omitempty"`
+}
+
+// VolumeBalanceStatus is the outcome of the automatic volume.balance
+type VolumeBalanceStatus struct {
+	// BalancedReplicas is the number of volume servers the data was last balanced over
+	BalancedReplicas int32 `json:"balancedReplicas"`
+
+	// Running is true while a balance is in progress
+	Running bool `json:"running,omitempty"`
+
+	// LastBalanceTime is when the last balance finished
+	LastBalanceTime *metav1.Time `json:"lastBalanceTime,omitempty"`
+
+	// LastResult is Succeeded or Failed
+	LastResult string `json:"lastResult,omitempty"`
+
+	// Message describes the last result
+	Message string `json:"message,omitempty"`
+}
+
+// MaintenanceScriptStatus is the last run of a maintenance script
+type MaintenanceScriptStatus struct {
+	// Name of the script
+	Name string `json:"name"`
+
+	// LastScheduleTime is the last schedule tick that was handled, run or skipped
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+
+	// Running is true while the script is in progress
+	Running bool `json:"running,omitempty"`
+
+	// LastRunTime is when the last run started
+	LastRunTime *metav1.Time `json:"lastRunTime,omitempty"`
+
+	// LastDuration is how long the last run took
+	LastDuration *metav1.Duration `json:"lastDuration,omitempty"`
+
+	// LastResult is Succeeded or Failed
+	LastResult string `json:"lastResult,omitempty"`
+
+	// Message describes the last result or why a tick was skipped
+	Message string `json:"message,omitempty"`
+}
+
+// ClusterHealth is the cluster state reported by the masters
+type ClusterHealth struct {
+	// LastProbeTime is when the masters were last queried
+	LastProbeTime *metav1.Time `json:"lastProbeTime,omitempty"`
+
+	// Leader is the address of the raft leader, empty if there is none
+	Leader string `json:"leader,omitempty"`
+
+	// Peers are the raft peers known to the leader
+	Peers []string `json:"peers,omitempty"`
+
+	// RespondingMasters is the number of masters answering /cluster/status
+	RespondingMasters int32 `json:"respondingMasters"`
+
+	// MaxVolumeID is the largest volume id assigned so far
+	MaxVolumeID uint32 `json:"maxVolumeId,omitempty"`
+
+	// VolumeSlots is the total number of volumes the volume servers can hold
+	VolumeSlots int64 `json:"volumeSlots"`
+
+	// FreeVolumeSlots is the number of volumes that can still be created
+	FreeVolumeSlots int64 `json:"freeVolumeSlots"`
+
+	// VolumeServers are the volume servers heartbeating to the leader
+	VolumeServers []VolumeServerHealth `json:"volumeServers,omitempty"`
+
+	// MissingVolumeServers are the volume servers of the StatefulSet the leader has no heartbeat from
+	MissingVolumeServers []string `json:"missingVolumeServers,omitempty"`
+
+	// DataCenters is the capacity per data center
+	DataCenters []DataCenterCapacity `json:"dataCenters,omitempty"`
+
+	// Message describes why the masters could not be queried
+	Message string `json:"message,omitempty"`
+}
+
+// VolumeServerHealth is a volume server as reported by its heartbeats
+type VolumeServerHealth struct {
+	// Address the volume server registered with
+	Address string `json:"address"`
+
+	// DataCenter and Rack of the volume server
+	DataCenter string `json:"dataCenter,omitempty"`
+	Rack       string `json:"rack,omitempty"`
+
+	// Volumes is the number of volumes on the server
+	Volumes int64 `json:"volumes"`
+
+	// EcShards is the number of EC shards on the server
+	EcShards int64 `json:"ecShards,omitempty"`
+
+	// MaxVolumes is the number of volume slots of the server
+	MaxVolumes int64 `json:"maxVolumes"`
+}
+
+// DataCenterCapacity sums up the volume servers of a data center
+type DataCenterCapacity struct {
+	// Name of the data center
+	Name string `json:"name"`
+
+	// VolumeServers is the number of heartbeating volume servers
+	VolumeServers int32 `json:"volumeServers"`
+
+	// Volumes is the number of volumes
+	Volumes int64 `json:"volumes"`
+
+	// VolumeSlots is the number of volume slots
+	VolumeSlots int64 `json:"volumeSlots"`
+}
+
+// VolumePoolStatus is the observed state of the pods of a volume pool
+type VolumePoolStatus struct {
+	// Name of the pool
+	Name string `json:"name"`
+
+	ComponentStatus `json:",inline"`
 }
 
 // SeaweedStatus defines the observed state of Seaweed
 type SeaweedStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// ObservedGeneration is the most recent generation reconciled without error
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Phase is a summary of the cluster state
+	Phase SeaweedPhase `json:"phase,omitempty"`
+
+	// Conditions of the cluster
+	Conditions []SeaweedCondition `json:"conditions,omitempty"`
+
+	// Master status
+	Master ComponentStatus `json:"master,omitempty"`
+
+	// Volume status
+	Volume ComponentStatus `json:"volume,omitempty"`
+
+	// VolumePools status
+	VolumePools []VolumePoolStatus `json:"volumePools,omitempty"`
+
+	// Filer status
+	Filer ComponentStatus `json:"filer,omitempty"`
+
+	// Gateway status
+	Gateway ComponentStatus `json:"gateway,omitempty"`
+
+	// Upgrade reports the version upgrade in progress
+	Upgrade *UpgradeStatus `json:"upgrade,omitempty"`
+
+	// Deletion reports the progress of the deletion policy once the Seaweed is being deleted
+	Deletion *DeletionStatus `json:"deletion,omitempty"`
+
+	// VolumeScaleIn reports the progress of a volume server scale-in
+	VolumeScaleIn *VolumeScaleInStatus `json:"volumeScaleIn,omitempty"`
+
+	// DrainedVolumeServers are the volume servers emptied by a completed scale-in.
+	// Only their PVCs are reclaimed with EnablePVReclaim.
+	DrainedVolumeServers []string `json:"drainedVolumeServers,omitempty"`
+
+	// VolumeExpansions report the PVC expansions in progress
+	VolumeExpansions []VolumeExpansionStatus `json:"volumeExpansions,omitempty"`
+
+	// VolumeDiskMigrations report the disks being added to or removed from volume servers
+	VolumeDiskMigrations []VolumeDiskMigrationStatus `json:"volumeDiskMigrations,omitempty"`
+
+	// VolumeRollouts report the volume servers restarted by the operator with the OnDelete update strategy
+	VolumeRollouts []VolumeRolloutStatus `json:"volumeRollouts,omitempty"`
+
+	// MasterRollout reports the masters restarted by the operator with the OnDelete update strategy
+	MasterRollout *MasterRolloutStatus `json:"masterRollout,omitempty"`
+
+	// VolumeBalance reports the automatic volume.balance
+	VolumeBalance *VolumeBalanceStatus `json:"volumeBalance,omitempty"`
+
+	// Maintenance reports the scheduled scripts
+	Maintenance []MaintenanceScriptStatus `json:"maintenance,omitempty"`
+
+	// Health is the cluster state reported by the masters
+	Health *ClusterHealth `json:"health,omitempty"`
 }
 
 // MasterSpec is the spec for masters
@@ -126,7 +595,7 @@ type MasterSpec struct {
 	Replicas int32        `json:"replicas"`
 	Service  *ServiceSpec `json:"service,omitempty"`
 
-	// Config in raw toml string
+	// Config in raw toml string, "${secret:<name>/<key>}" is replaced by the key of the Secret
 	Config *string `json:"config,omitempty"`
 
 	// Master-specific settings
@@ -138,6 +607,27 @@ type MasterSpec struct {
 	DefaultReplication *string `json:"defaultReplication,omitempty"`
 	// only for testing
 	ConcurrentStart *bool `json:"concurrentStart,omitempty"`
+
+	// RolloutWithoutQuorum lets the operator restart the masters of a new revision with fewer than 3 replicas.
+	// The cluster has no master leader while one of them restarts.
+	RolloutWithoutQuorum bool `json:"rolloutWithoutQuorum,omitempty"`
+
+	// Storage keeps the raft state and the sequence of the masters in a PVC passed to -mdir.
+	// Without it the masters store them in the container filesystem.
+	Storage *StorageSpec `json:"storage,omitempty"`
+}
+
+// StorageSpec is a PVC per pod of a component
+type StorageSpec struct {
+	// Size of the PVC
+	Size resource.Quantity `json:"size"`
+
+	// StorageClassName of the PVC, the default StorageClass is used if empty
+	StorageClassName *string `json:"storageClassName,omitempty"`
+
+	// MountPath of the PVC, defaults to /data
+	// +optional
+	MountPath string `json:"mountPath,omitempty"`
 }
 
 // VolumeSpec is the spec for volume servers
@@ -160,6 +650,90 @@ type VolumeSpec struct {
 	IdleTimeout         *int32 `json:"idleTimeout,omitempty"`
 	MaxVolumeCounts     *int32 `json:"maxVolumeCounts,omitempty"`
 	MinFreeSpacePercent *int32 `json:"minFreeSpacePercent,omitempty"`
+
+	// Balance runs volume.balance after new volume servers joined
+	Balance *VolumeBalanceSpec `json:"balance,omitempty"`
+
+	// Disks configures every data directory of a volume server with its own PVC.
+	// It replaces volumeServerDiskCount and the storage request.
+	Disks []VolumeDiskSpec `json:"disks,omitempty"`
+
+	// Topology starts every volume server with the data center and rack of its node,
+	// so the replica placement can spread copies across failure domains
+	Topology *VolumeTopologySpec `json:"topology,omitempty"`
+}
+
+// VolumePoolSpec is a named group of volume servers, e.g. on SSD nodes
+type VolumePoolSpec struct {
+	// Name of the pool, the StatefulSet is named <seaweed>-volume-<name>
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=24
+	Name string `json:"name"`
+
+	VolumeSpec `json:",inline"`
+
+	// DiskCount is the number of disks per volume server, defaults to volumeServerDiskCount
+	// +kubebuilder:validation:Minimum=1
+	DiskCount *int32 `json:"diskCount,omitempty"`
+
+	// DiskType tags the disks with -disk, e.g. ssd, so that collections can be placed on them
+	DiskType *string `json:"diskType,omitempty"`
+
+	// DataCenter is passed as -dataCenter, unless topology reads it from the nodes
+	DataCenter *string `json:"dataCenter,omitempty"`
+
+	// Rack is passed as -rack, unless topology reads it from the nodes
+	Rack *string `json:"rack,omitempty"`
+}
+
+// VolumeDiskSpec is one data directory of a volume server, backed by its own PVC
+type VolumeDiskSpec struct {
+	// Name of the volumeClaimTemplate, defaults to mount<index>
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Size of the PVC
+	Size resource.Quantity `json:"size"`
+
+	// StorageClassName of the PVC, defaults to the storageClassName of the volume servers
+	StorageClassName *string `json:"storageClassName,omitempty"`
+
+	// MountPath of the data directory, defaults to /data<index>
+	// +optional
+	MountPath string `json:"mountPath,omitempty"`
+
+	// DiskType is passed to -disk for this directory: hdd, ssd or a custom tag
+	// +optional
+	DiskType string `json:"diskType,omitempty"`
+
+	// MaxVolumes is passed to -max for this directory, 0 derives it from the free space
+	// +kubebuilder:validation:Minimum=0
+	MaxVolumes *int32 `json:"maxVolumes,omitempty"`
+}
+
+// VolumeTopologySpec maps Kubernetes node labels to the SeaweedFS data center and rack of the volume servers.
+// Nodes without the label are placed into DefaultDataCenter and DefaultRack.
+type VolumeTopologySpec struct {
+	// DataCenterLabel is the node label used as -dataCenter, defaults to topology.kubernetes.io/region
+	DataCenterLabel *string `json:"dataCenterLabel,omitempty"`
+
+	// RackLabel is the node label used as -rack, defaults to topology.kubernetes.io/zone
+	RackLabel *string `json:"rackLabel,omitempty"`
+}
+
+// VolumeBalanceSpec configures the automatic volume.balance after a volume server scale-out
+type VolumeBalanceSpec struct {
+	// Enabled turns the automatic balance on
+	Enabled bool `json:"enabled,omitempty"`
+
+	// CooldownSeconds is the minimum time between two balances, 600 if not set
+	// +kubebuilder:validation:Minimum=0
+	CooldownSeconds *int32 `json:"cooldownSeconds,omitempty"`
+
+	// Collections to balance one after another, all collections are balanced each on its own if empty.
+	// ALL_COLLECTIONS balances across collections.
+	Collections []string `json:"collections,omitempty"`
 }
 
 // FilerSpec is the spec for filers
@@ -172,7 +746,7 @@ type FilerSpec struct {
 	Replicas int32        `json:"replicas"`
 	Service  *ServiceSpec `json:"service,omitempty"`
 
-	// Config in raw toml string
+	// Config in raw toml string, "${secret:<name>/<key>}" is replaced by the key of the Secret
 	Config *string `json:"config,omitempty"`
 
 	// Filer-specific settings
@@ -181,6 +755,132 @@ type FilerSpec struct {
 
 	// +kubebuilder:default:=false
 	S3 *bool `json:"s3,omitempty"`
+
+	// Storage keeps the metadata of an embedded filer store, e.g. leveldb2, in a PVC.
+	// The dir of the store in config has to be below its mountPath.
+	Storage *StorageSpec `json:"storage,omitempty"`
+
+	// Store is rendered into filer.toml, config is merged into it afterwards
+	Store *FilerStoreSpec `json:"store,omitempty"`
+}
+
+// FilerStoreSpec configures the filer store, only one of the stores may be set
+type FilerStoreSpec struct {
+	LevelDB2  *LevelDB2StoreSpec  `json:"leveldb2,omitempty"`
+	Postgres  *PostgresStoreSpec  `json:"postgres,omitempty"`
+	MySQL     *MySQLStoreSpec     `json:"mysql,omitempty"`
+	Redis     *RedisStoreSpec     `json:"redis,omitempty"`
+	Cassandra *CassandraStoreSpec `json:"cassandra,omitempty"`
+	Etcd      *EtcdStoreSpec      `json:"etcd,omitempty"`
+	MongoDB   *MongoDBStoreSpec   `json:"mongodb,omitempty"`
+	Elastic   *ElasticStoreSpec   `json:"elastic,omitempty"`
+}
+
+// StoreCredentials are passed to the filer in environment variables, the secrets never end up in filer.toml
+type StoreCredentials struct {
+	// Username to connect with, overridden by usernameSecretRef
+	Username string `json:"username,omitempty"`
+
+	// UsernameSecretRef selects the username from a Secret
+	UsernameSecretRef *corev1.SecretKeySelector `json:"usernameSecretRef,omitempty"`
+
+	// PasswordSecretRef selects the password from a Secret
+	PasswordSecretRef *corev1.SecretKeySelector `json:"passwordSecretRef,omitempty"`
+}
+
+// LevelDB2StoreSpec keeps the metadata on the local disk of every filer
+type LevelDB2StoreSpec struct {
+	// Dir of the database, defaults to filerldb2 below the mountPath of spec.filer.storage
+	Dir string `json:"dir,omitempty"`
+}
+
+// PostgresStoreSpec keeps the metadata in PostgreSQL, CockroachDB or YugabyteDB
+type PostgresStoreSpec struct {
+	StoreCredentials `json:",inline"`
+
+	Hostname string `json:"hostname"`
+	// +kubebuilder:validation:Minimum=1
+	Port     *int32 `json:"port,omitempty"`
+	Database string `json:"database,omitempty"`
+	Schema   string `json:"schema,omitempty"`
+	SSLMode  string `json:"sslMode,omitempty"`
+
+	ConnectionMaxIdle            *int32 `json:"connectionMaxIdle,omitempty"`
+	ConnectionMaxOpen            *int32 `json:"connectionMaxOpen,omitempty"`
+	ConnectionMaxLifetimeSeconds *int32 `json:"connectionMaxLifetimeSeconds,omitempty"`
+}
+
+// MySQLStoreSpec keeps the metadata in MySQL, MemSQL or TiDB
+type MySQLStoreSpec struct {
+	StoreCredentials `json:",inline"`
+
+	Hostname string `json:"hostname"`
+	// +kubebuilder:validation:Minimum=1
+	Port     *int32 `json:"port,omitempty"`
+	Database string `json:"database,omitempty"`
+
+	ConnectionMaxIdle            *int32 `json:"connectionMaxIdle,omitempty"`
+	ConnectionMaxOpen            *int32 `json:"connectionMaxOpen,omitempty"`
+	ConnectionMaxLifetimeSeconds *int32 `json:"connectionMaxLifetimeSeconds,omitempty"`
+	InterpolateParams            *bool  `json:"interpolateParams,omitempty"`
+}
+
+// RedisStoreSpec keeps the metadata in Redis, rendered as the redis2 store
+type RedisStoreSpec struct {
+	// Address is host:port of the Redis server
+	Address  string `json:"address"`
+	Database *int32 `json:"database,omitempty"`
+
+	// PasswordSecretRef selects the password from a Secret
+	PasswordSecretRef *corev1.SecretKeySelector `json:"passwordSecretRef,omitempty"`
+
+	// SuperLargeDirectories changes the data layout, directories may only be added
+	SuperLargeDirectories []string `json:"superLargeDirectories,omitempty"`
+}
+
+// CassandraStoreSpec keeps the metadata in Cassandra
+type CassandraStoreSpec struct {
+	StoreCredentials `json:",inline"`
+
+	// Hosts are host:port of the Cassandra nodes
+	// +kubebuilder:validation:MinItems=1
+	Hosts    []string `json:"hosts"`
+	Keyspace string   `json:"keyspace,omitempty"`
+	LocalDC  string   `json:"localDC,omitempty"`
+
+	// SuperLargeDirectories changes the data layout, directories may only be added
+	SuperLargeDirectories []string `json:"superLargeDirectories,omitempty"`
+}
+
+// EtcdStoreSpec keeps the metadata in etcd
+type EtcdStoreSpec struct {
+	// Servers are the comma separated host:port of the etcd servers
+	Servers string `json:"servers"`
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// MongoDBStoreSpec keeps the metadata in MongoDB
+type MongoDBStoreSpec struct {
+	// URI of the MongoDB server, overridden by uriSecretRef
+	URI string `json:"uri,omitempty"`
+
+	// URISecretRef selects the URI with the credentials from a Secret
+	URISecretRef *corev1.SecretKeySelector `json:"uriSecretRef,omitempty"`
+
+	Database       string `json:"database,omitempty"`
+	OptionPoolSize *int32 `json:"optionPoolSize,omitempty"`
+}
+
+// ElasticStoreSpec keeps the metadata in Elasticsearch 7, rendered as the elastic7 store
+type ElasticStoreSpec struct {
+	StoreCredentials `json:",inline"`
+
+	// Servers are the URLs of the Elasticsearch nodes
+	// +kubebuilder:validation:MinItems=1
+	Servers            []string `json:"servers"`
+	SniffEnabled       *bool    `json:"sniffEnabled,omitempty"`
+	HealthcheckEnabled *bool    `json:"healthcheckEnabled,omitempty"`
+	MaxResultWindow    *int32   `json:"maxResultWindow,omitempty"`
 }
 
 // ComponentSpec is the base spec of each component, the fields should always accessed by the Basic<Component>Spec() method to respect the cluster-level properties
@@ -278,6 +978,16 @@ type GatewaySpec struct {
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Masters",type="integer",JSONPath=".status.master.readyReplicas",description="Ready masters"
+// +kubebuilder:printcolumn:name="Volumes",type="integer",JSONPath=".status.volume.readyReplicas",description="Ready volume servers"
+// +kubebuilder:printcolumn:name="Filers",type="integer",JSONPath=".status.filer.readyReplicas",description="Ready filers"
+// +kubebuilder:printcolumn:name="Version",type="string",JSONPath=".status.master.version",priority=1
+// +kubebuilder:printcolumn:name="Leader",type="string",JSONPath=".status.health.leader",priority=1
+// +kubebuilder:printcolumn:name="Desired-Masters",type="integer",JSONPath=".status.master.replicas",priority=1
+// +kubebuilder:printcolumn:name="Desired-Volumes",type="integer",JSONPath=".status.volume.replicas",priority=1
+// +kubebuilder:printcolumn:name="Desired-Filers",type="integer",JSONPath=".status.filer.replicas",priority=1
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // Seaweed is the Schema for the seaweeds API
 type Seaweed struct {
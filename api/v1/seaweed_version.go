@@ -0,0 +1,78 @@
+package v1
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultImageRepository is used when a version is set without an image
+const DefaultImageRepository = "chrislusf/seaweedfs"
+
+var versionReg = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+
+// ImageWithVersion replaces the tag or digest of the image with the version, the image is unchanged if version is empty
+func ImageWithVersion(image, version string) string {
+	if version == "" {
+		return image
+	}
+	if image == "" {
+		image = DefaultImageRepository
+	}
+	return imageRepository(image) + ":" + version
+}
+
+// ImageVersion is the tag or digest of the image, "latest" if it has neither
+func ImageVersion(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		return image[i+1:]
+	}
+	repository := imageRepository(image)
+	if len(repository) < len(image) {
+		return image[len(repository)+1:]
+	}
+	return "latest"
+}
+
+// imageRepository strips the tag and digest, a colon before the last slash belongs to the registry port
+func imageRepository(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
+
+// validateVersions checks the versions are valid image tags
+func (r *Seaweed) validateVersions() []error {
+	type versionField struct {
+		field   string
+		version *string
+	}
+	versions := []versionField{{"spec.version", &r.Spec.Version}}
+	if r.Spec.Master != nil {
+		versions = append(versions, versionField{"spec.master.version", r.Spec.Master.Version})
+	}
+	if r.Spec.Volume != nil {
+		versions = append(versions, versionField{"spec.volume.version", r.Spec.Volume.Version})
+	}
+	for i := range r.Spec.VolumePools {
+		versions = append(versions, versionField{fmt.Sprintf("spec.volumePools[%s].version", r.Spec.VolumePools[i].Name), r.Spec.VolumePools[i].Version})
+	}
+	if r.Spec.Filer != nil {
+		versions = append(versions, versionField{"spec.filer.version", r.Spec.Filer.Version})
+	}
+	if r.Spec.Gateway != nil {
+		versions = append(versions, versionField{"spec.gateway.version", r.Spec.Gateway.Version})
+	}
+
+	var errs []error
+	for _, v := range versions {
+		if v.version != nil && *v.version != "" && !versionReg.MatchString(*v.version) {
+			errs = append(errs, fmt.Errorf("%s %q is not a valid image tag", v.field, *v.version))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,109 @@
+package v1
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// validateVolumePools checks the pool names are unique and every pool requests storage
+func (r *Seaweed) validateVolumePools() []error {
+	var errs []error
+	names := make(map[string]bool)
+	for _, pool := range r.Spec.VolumePools {
+		if names[pool.Name] {
+			errs = append(errs, fmt.Errorf("volume pool %s is defined twice", pool.Name))
+		}
+		names[pool.Name] = true
+		if len(pool.Disks) > 0 {
+			errs = append(errs, validateVolumeDisks("volume pool "+pool.Name, pool.Disks)...)
+			continue
+		}
+		if storage := pool.Requests[corev1.ResourceStorage]; storage.IsZero() {
+			errs = append(errs, fmt.Errorf("volume pool %s storage request cannot be zero", pool.Name))
+		}
+		if pool.DiskCount == nil && r.Spec.VolumeServerDiskCount < 1 {
+			errs = append(errs, fmt.Errorf("volume pool %s needs diskCount or volumeServerDiskCount", pool.Name))
+		}
+	}
+	return errs
+}
+
+// validateVolumePoolsUpdate refuses to remove pools, their volume servers would be dropped without a drain
+func (r *Seaweed) validateVolumePoolsUpdate(old *Seaweed) []error {
+	names := make(map[string]bool)
+	for _, pool := range r.Spec.VolumePools {
+		names[pool.Name] = true
+	}
+	var errs []error
+	for _, pool := range old.Spec.VolumePools {
+		if !names[pool.Name] {
+			errs = append(errs, fmt.Errorf("volume pool %s can not be removed", pool.Name))
+		}
+	}
+	return errs
+}
+
+// volumeServerCount is the number of volume servers of spec.volume and all pools
+func (r *Seaweed) volumeServerCount() int32 {
+	var count int32
+	if r.Spec.Volume != nil {
+		count += r.Spec.Volume.Replicas
+	}
+	for _, pool := range r.Spec.VolumePools {
+		count += pool.Replicas
+	}
+	return count
+}
+
+// hasVolumeTopology reports whether any volume server is started with a data center or rack
+func (r *Seaweed) hasVolumeTopology() bool {
+	if r.Spec.Volume != nil && r.Spec.Volume.Topology != nil {
+		return true
+	}
+	for _, pool := range r.Spec.VolumePools {
+		if pool.Topology != nil || pool.DataCenter != nil || pool.Rack != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// validateVolumeDisks checks the disks have a size and distinct names and mount paths
+func validateVolumeDisks(owner string, disks []VolumeDiskSpec) []error {
+	var errs []error
+	names := make(map[string]bool)
+	mountPaths := make(map[string]bool)
+	for i, disk := range disks {
+		name := disk.Name
+		if name == "" {
+			name = fmt.Sprintf("mount%d", i)
+		}
+		mountPath := strings.TrimSuffix(disk.MountPath, "/")
+		if mountPath == "" {
+			mountPath = fmt.Sprintf("/data%d", i)
+		}
+		if names[name] {
+			errs = append(errs, fmt.Errorf("%s disk %s is defined twice", owner, name))
+		}
+		names[name] = true
+		if mountPaths[mountPath] {
+			errs = append(errs, fmt.Errorf("%s disk %s mounts %s twice", owner, name, mountPath))
+		}
+		mountPaths[mountPath] = true
+		if !strings.HasPrefix(mountPath, "/") || strings.Contains(mountPath, ",") {
+			errs = append(errs, fmt.Errorf("%s disk %s mount path %q must be absolute without commas", owner, name, disk.MountPath))
+		}
+		if strings.Contains(disk.DiskType, ",") {
+			errs = append(errs, fmt.Errorf("%s disk %s type %q must not contain commas", owner, name, disk.DiskType))
+		}
+		if strings.HasPrefix(disk.DiskType, "retiring-") {
+			errs = append(errs, fmt.Errorf("%s disk %s type %q is reserved for removed disks", owner, name, disk.DiskType))
+		}
+		if disk.Size.IsZero() {
+			errs = append(errs, fmt.Errorf("%s disk %s size cannot be zero", owner, name))
+		}
+	}
+	return errs
+}
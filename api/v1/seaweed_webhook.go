@@ -18,6 +18,7 @@ package v1
 
 import (
 	"errors"
+	"reflect"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -67,21 +68,69 @@ func (r *Seaweed) ValidateCreate() error {
 
 	if r.Spec.Volume == nil {
 		errs = append(errs, errors.New("missing volume spec"))
+	} else if len(r.Spec.Volume.Disks) > 0 {
+		errs = append(errs, validateVolumeDisks("volume", r.Spec.Volume.Disks)...)
 	} else {
 		if r.Spec.Volume.Requests[corev1.ResourceStorage].Equal(resource.MustParse("0")) {
 			errs = append(errs, errors.New("volume storage request cannot be zero"))
 		}
 	}
 
+	if r.Spec.Maintenance != nil {
+		errs = append(errs, r.Spec.Maintenance.validate()...)
+	}
+
+	errs = append(errs, r.validateDefaultReplication()...)
+	errs = append(errs, r.validateVolumePools()...)
+	errs = append(errs, r.validateVersions()...)
+	errs = append(errs, r.validateStorage()...)
+	errs = append(errs, r.validateFilerStore()...)
+	errs = append(errs, r.validateConfigs()...)
+	r.logWarnings()
+
 	return utilerrors.NewAggregate(errs)
 }
 
-// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type.
+// Updates which keep the spec, such as the finalizer updates of the operator, and updates during deletion are not checked.
 func (r *Seaweed) ValidateUpdate(old runtime.Object) error {
 	seaweedlog.Info("validate update", "name", r.Name)
 
-	// TODO(user): fill in your validation logic upon object update.
-	return nil
+	oldSeaweed, ok := old.(*Seaweed)
+	if r.DeletionTimestamp != nil || (ok && reflect.DeepEqual(oldSeaweed.Spec, r.Spec)) {
+		return nil
+	}
+
+	errs := []error{}
+
+	if r.Spec.Maintenance != nil {
+		errs = append(errs, r.Spec.Maintenance.validate()...)
+	}
+
+	errs = append(errs, r.validateDefaultReplication()...)
+	errs = append(errs, r.validateVolumePools()...)
+	errs = append(errs, r.validateVersions()...)
+	if r.Spec.Volume != nil {
+		errs = append(errs, validateVolumeDisks("volume", r.Spec.Volume.Disks)...)
+	}
+	errs = append(errs, r.validateStorage()...)
+	errs = append(errs, r.validateFilerStore()...)
+	errs = append(errs, r.validateConfigs()...)
+	if ok {
+		errs = append(errs, r.validateVolumePoolsUpdate(oldSeaweed)...)
+		errs = append(errs, r.validateStorageUpdate(oldSeaweed)...)
+	}
+	r.logWarnings()
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// logWarnings logs the settings which are valid but probably not intended.
+// Admission warnings need a newer controller-runtime, the controller reports them as events as well.
+func (r *Seaweed) logWarnings() {
+	for _, warning := range r.FilerStoreWarnings() {
+		seaweedlog.Info("warning", "name", r.Name, "warning", warning)
+	}
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
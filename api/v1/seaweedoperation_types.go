@@ -0,0 +1,108 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SeaweedOperationSpec defines the weed shell commands to run against a Seaweed
+type SeaweedOperationSpec struct {
+	// SeaweedRef is the name of the Seaweed in the same namespace
+	// +kubebuilder:validation:MinLength=1
+	SeaweedRef string `json:"seaweedRef"`
+
+	// Commands are weed shell commands, e.g. "volume.fix.replication" or "ec.encode -fullPercent=95".
+	// They run in order while holding the shell lock, and the first failing command fails the attempt.
+	// +kubebuilder:validation:MinItems=1
+	Commands []string `json:"commands"`
+
+	// TimeoutSeconds bounds one attempt, 3600 if not set
+	// +kubebuilder:validation:Minimum=1
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+
+	// Retries is how many times a failed attempt is retried
+	// +kubebuilder:validation:Minimum=0
+	Retries int32 `json:"retries,omitempty"`
+}
+
+// SeaweedOperationPhase is the state of a SeaweedOperation
+type SeaweedOperationPhase string
+
+const (
+	// SeaweedOperationPending means the operation waits for its next attempt
+	SeaweedOperationPending SeaweedOperationPhase = "Pending"
+	// SeaweedOperationRunning means an attempt is in progress
+	SeaweedOperationRunning SeaweedOperationPhase = "Running"
+	// SeaweedOperationSucceeded means all commands succeeded
+	SeaweedOperationSucceeded SeaweedOperationPhase = "Succeeded"
+	// SeaweedOperationFailed means the last attempt failed and no retries are left
+	SeaweedOperationFailed SeaweedOperationPhase = "Failed"
+)
+
+// SeaweedOperationStatus defines the observed state of SeaweedOperation
+type SeaweedOperationStatus struct {
+	// Phase of the operation
+	Phase SeaweedOperationPhase `json:"phase,omitempty"`
+
+	// Attempts is the number of attempts started so far
+	Attempts int32 `json:"attempts,omitempty"`
+
+	// StartTime is when the first attempt started
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is when the operation succeeded or finally failed
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Output of the last attempt, truncated to its tail
+	Output string `json:"output,omitempty"`
+
+	// Error of the last failed attempt
+	Error string `json:"error,omitempty"`
+
+	// Message describes the current state
+	Message string `json:"message,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Seaweed",type="string",JSONPath=".spec.seaweedRef"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Attempts",type="integer",JSONPath=".status.attempts"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// SeaweedOperation runs weed shell commands once against a Seaweed
+type SeaweedOperation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SeaweedOperationSpec   `json:"spec,omitempty"`
+	Status SeaweedOperationStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// SeaweedOperationList contains a list of SeaweedOperation
+type SeaweedOperationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SeaweedOperation `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SeaweedOperation{}, &SeaweedOperationList{})
+}
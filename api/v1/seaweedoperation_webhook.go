@@ -0,0 +1,77 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+var seaweedoperationlog = logf.Log.WithName("seaweedoperation-resource")
+
+func (r *SeaweedOperation) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-seaweed-seaweedfs-com-v1-seaweedoperation,mutating=false,failurePolicy=fail,groups=seaweed.seaweedfs.com,resources=seaweedoperations,versions=v1,name=vseaweedoperation.kb.io
+
+var _ webhook.Validator = &SeaweedOperation{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *SeaweedOperation) ValidateCreate() error {
+	seaweedoperationlog.Info("validate create", "name", r.Name)
+	return utilerrors.NewAggregate(validateCommands("spec.commands", r.Spec.Commands))
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *SeaweedOperation) ValidateUpdate(old runtime.Object) error {
+	seaweedoperationlog.Info("validate update", "name", r.Name)
+	return utilerrors.NewAggregate(validateCommands("spec.commands", r.Spec.Commands))
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *SeaweedOperation) ValidateDelete() error {
+	return nil
+}
+
+// validateCommands refuses the commands weed shell would take for several commands:
+// a newline or any other control character would start another command on the shell's stdin, and so would a semi-colon
+func validateCommands(field string, commands []string) []error {
+	var errs []error
+	for i, cmd := range commands {
+		if strings.ContainsRune(cmd, ';') {
+			errs = append(errs, fmt.Errorf("%s[%d] has a semi-colon, use one entry per command", field, i))
+			continue
+		}
+		for _, c := range cmd {
+			if unicode.IsControl(c) {
+				errs = append(errs, fmt.Errorf("%s[%d] has the control character %U", field, i, c))
+				break
+			}
+		}
+	}
+	return errs
+}
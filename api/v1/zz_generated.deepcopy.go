@@ -23,9 +23,75 @@ package v1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CassandraStoreSpec) DeepCopyInto(out *CassandraStoreSpec) {
+	*out = *in
+	in.StoreCredentials.DeepCopyInto(&out.StoreCredentials)
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SuperLargeDirectories != nil {
+		in, out := &in.SuperLargeDirectories, &out.SuperLargeDirectories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CassandraStoreSpec.
+func (in *CassandraStoreSpec) DeepCopy() *CassandraStoreSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CassandraStoreSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterHealth) DeepCopyInto(out *ClusterHealth) {
+	*out = *in
+	if in.LastProbeTime != nil {
+		in, out := &in.LastProbeTime, &out.LastProbeTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Peers != nil {
+		in, out := &in.Peers, &out.Peers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.VolumeServers != nil {
+		in, out := &in.VolumeServers, &out.VolumeServers
+		*out = make([]VolumeServerHealth, len(*in))
+		copy(*out, *in)
+	}
+	if in.MissingVolumeServers != nil {
+		in, out := &in.MissingVolumeServers, &out.MissingVolumeServers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DataCenters != nil {
+		in, out := &in.DataCenters, &out.DataCenters
+		*out = make([]DataCenterCapacity, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterHealth.
+func (in *ClusterHealth) DeepCopy() *ClusterHealth {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterHealth)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ComponentSpec) DeepCopyInto(out *ComponentSpec) {
 	*out = *in
@@ -109,6 +175,116 @@ func (in *ComponentSpec) DeepCopy() *ComponentSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentStatus) DeepCopyInto(out *ComponentStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentStatus.
+func (in *ComponentStatus) DeepCopy() *ComponentStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataCenterCapacity) DeepCopyInto(out *DataCenterCapacity) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataCenterCapacity.
+func (in *DataCenterCapacity) DeepCopy() *DataCenterCapacity {
+	if in == nil {
+		return nil
+	}
+	out := new(DataCenterCapacity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeletionStatus) DeepCopyInto(out *DeletionStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.PendingPersistentVolumeClaims != nil {
+		in, out := &in.PendingPersistentVolumeClaims, &out.PendingPersistentVolumeClaims
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Snapshots != nil {
+		in, out := &in.Snapshots, &out.Snapshots
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeletionStatus.
+func (in *DeletionStatus) DeepCopy() *DeletionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DeletionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ElasticStoreSpec) DeepCopyInto(out *ElasticStoreSpec) {
+	*out = *in
+	in.StoreCredentials.DeepCopyInto(&out.StoreCredentials)
+	if in.Servers != nil {
+		in, out := &in.Servers, &out.Servers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SniffEnabled != nil {
+		in, out := &in.SniffEnabled, &out.SniffEnabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.HealthcheckEnabled != nil {
+		in, out := &in.HealthcheckEnabled, &out.HealthcheckEnabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.MaxResultWindow != nil {
+		in, out := &in.MaxResultWindow, &out.MaxResultWindow
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ElasticStoreSpec.
+func (in *ElasticStoreSpec) DeepCopy() *ElasticStoreSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ElasticStoreSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EtcdStoreSpec) DeepCopyInto(out *EtcdStoreSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EtcdStoreSpec.
+func (in *EtcdStoreSpec) DeepCopy() *EtcdStoreSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(EtcdStoreSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FilerSpec) DeepCopyInto(out *FilerSpec) {
 	*out = *in
@@ -134,6 +310,16 @@ func (in *FilerSpec) DeepCopyInto(out *FilerSpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		*out = new(StorageSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Store != nil {
+		in, out := &in.Store, &out.Store
+		*out = new(FilerStoreSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FilerSpec.
@@ -146,6 +332,61 @@ func (in *FilerSpec) DeepCopy() *FilerSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FilerStoreSpec) DeepCopyInto(out *FilerStoreSpec) {
+	*out = *in
+	if in.LevelDB2 != nil {
+		in, out := &in.LevelDB2, &out.LevelDB2
+		*out = new(LevelDB2StoreSpec)
+		**out = **in
+	}
+	if in.Postgres != nil {
+		in, out := &in.Postgres, &out.Postgres
+		*out = new(PostgresStoreSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MySQL != nil {
+		in, out := &in.MySQL, &out.MySQL
+		*out = new(MySQLStoreSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Redis != nil {
+		in, out := &in.Redis, &out.Redis
+		*out = new(RedisStoreSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Cassandra != nil {
+		in, out := &in.Cassandra, &out.Cassandra
+		*out = new(CassandraStoreSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Etcd != nil {
+		in, out := &in.Etcd, &out.Etcd
+		*out = new(EtcdStoreSpec)
+		**out = **in
+	}
+	if in.MongoDB != nil {
+		in, out := &in.MongoDB, &out.MongoDB
+		*out = new(MongoDBStoreSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Elastic != nil {
+		in, out := &in.Elastic, &out.Elastic
+		*out = new(ElasticStoreSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FilerStoreSpec.
+func (in *FilerStoreSpec) DeepCopy() *FilerStoreSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FilerStoreSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GatewaySpec) DeepCopyInto(out *GatewaySpec) {
 	*out = *in
@@ -168,6 +409,130 @@ func (in *GatewaySpec) DeepCopy() *GatewaySpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LevelDB2StoreSpec) DeepCopyInto(out *LevelDB2StoreSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LevelDB2StoreSpec.
+func (in *LevelDB2StoreSpec) DeepCopy() *LevelDB2StoreSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LevelDB2StoreSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceScript) DeepCopyInto(out *MaintenanceScript) {
+	*out = *in
+	if in.Commands != nil {
+		in, out := &in.Commands, &out.Commands
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceScript.
+func (in *MaintenanceScript) DeepCopy() *MaintenanceScript {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceScript)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceScriptStatus) DeepCopyInto(out *MaintenanceScriptStatus) {
+	*out = *in
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastRunTime != nil {
+		in, out := &in.LastRunTime, &out.LastRunTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastDuration != nil {
+		in, out := &in.LastDuration, &out.LastDuration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceScriptStatus.
+func (in *MaintenanceScriptStatus) DeepCopy() *MaintenanceScriptStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceScriptStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceSpec) DeepCopyInto(out *MaintenanceSpec) {
+	*out = *in
+	if in.Windows != nil {
+		in, out := &in.Windows, &out.Windows
+		*out = make([]MaintenanceWindow, len(*in))
+		copy(*out, *in)
+	}
+	if in.Scripts != nil {
+		in, out := &in.Scripts, &out.Scripts
+		*out = make([]MaintenanceScript, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceSpec.
+func (in *MaintenanceSpec) DeepCopy() *MaintenanceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MasterRolloutStatus) DeepCopyInto(out *MasterRolloutStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MasterRolloutStatus.
+func (in *MasterRolloutStatus) DeepCopy() *MasterRolloutStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MasterRolloutStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MasterSpec) DeepCopyInto(out *MasterSpec) {
 	*out = *in
@@ -213,6 +578,11 @@ func (in *MasterSpec) DeepCopyInto(out *MasterSpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		*out = new(StorageSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MasterSpec.
@@ -226,50 +596,213 @@ func (in *MasterSpec) DeepCopy() *MasterSpec {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Seaweed) DeepCopyInto(out *Seaweed) {
+func (in *MongoDBStoreSpec) DeepCopyInto(out *MongoDBStoreSpec) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	if in.URISecretRef != nil {
+		in, out := &in.URISecretRef, &out.URISecretRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OptionPoolSize != nil {
+		in, out := &in.OptionPoolSize, &out.OptionPoolSize
+		*out = new(int32)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Seaweed.
-func (in *Seaweed) DeepCopy() *Seaweed {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MongoDBStoreSpec.
+func (in *MongoDBStoreSpec) DeepCopy() *MongoDBStoreSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(Seaweed)
+	out := new(MongoDBStoreSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *Seaweed) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SeaweedList) DeepCopyInto(out *SeaweedList) {
+func (in *MySQLStoreSpec) DeepCopyInto(out *MySQLStoreSpec) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]Seaweed, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
-}
-
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SeaweedList.
-func (in *SeaweedList) DeepCopy() *SeaweedList {
-	if in == nil {
-		return nil
+	in.StoreCredentials.DeepCopyInto(&out.StoreCredentials)
+	if in.Port != nil {
+		in, out := &in.Port, &out.Port
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ConnectionMaxIdle != nil {
+		in, out := &in.ConnectionMaxIdle, &out.ConnectionMaxIdle
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ConnectionMaxOpen != nil {
+		in, out := &in.ConnectionMaxOpen, &out.ConnectionMaxOpen
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ConnectionMaxLifetimeSeconds != nil {
+		in, out := &in.ConnectionMaxLifetimeSeconds, &out.ConnectionMaxLifetimeSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.InterpolateParams != nil {
+		in, out := &in.InterpolateParams, &out.InterpolateParams
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MySQLStoreSpec.
+func (in *MySQLStoreSpec) DeepCopy() *MySQLStoreSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MySQLStoreSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresStoreSpec) DeepCopyInto(out *PostgresStoreSpec) {
+	*out = *in
+	in.StoreCredentials.DeepCopyInto(&out.StoreCredentials)
+	if in.Port != nil {
+		in, out := &in.Port, &out.Port
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ConnectionMaxIdle != nil {
+		in, out := &in.ConnectionMaxIdle, &out.ConnectionMaxIdle
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ConnectionMaxOpen != nil {
+		in, out := &in.ConnectionMaxOpen, &out.ConnectionMaxOpen
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ConnectionMaxLifetimeSeconds != nil {
+		in, out := &in.ConnectionMaxLifetimeSeconds, &out.ConnectionMaxLifetimeSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresStoreSpec.
+func (in *PostgresStoreSpec) DeepCopy() *PostgresStoreSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresStoreSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisStoreSpec) DeepCopyInto(out *RedisStoreSpec) {
+	*out = *in
+	if in.Database != nil {
+		in, out := &in.Database, &out.Database
+		*out = new(int32)
+		**out = **in
+	}
+	if in.PasswordSecretRef != nil {
+		in, out := &in.PasswordSecretRef, &out.PasswordSecretRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SuperLargeDirectories != nil {
+		in, out := &in.SuperLargeDirectories, &out.SuperLargeDirectories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisStoreSpec.
+func (in *RedisStoreSpec) DeepCopy() *RedisStoreSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisStoreSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplicaPlacement) DeepCopyInto(out *ReplicaPlacement) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicaPlacement.
+func (in *ReplicaPlacement) DeepCopy() *ReplicaPlacement {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplicaPlacement)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Seaweed) DeepCopyInto(out *Seaweed) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Seaweed.
+func (in *Seaweed) DeepCopy() *Seaweed {
+	if in == nil {
+		return nil
+	}
+	out := new(Seaweed)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Seaweed) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SeaweedCondition) DeepCopyInto(out *SeaweedCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SeaweedCondition.
+func (in *SeaweedCondition) DeepCopy() *SeaweedCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(SeaweedCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SeaweedList) DeepCopyInto(out *SeaweedList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Seaweed, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SeaweedList.
+func (in *SeaweedList) DeepCopy() *SeaweedList {
+	if in == nil {
+		return nil
 	}
 	out := new(SeaweedList)
 	in.DeepCopyInto(out)
@@ -284,6 +817,113 @@ func (in *SeaweedList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SeaweedOperation) DeepCopyInto(out *SeaweedOperation) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SeaweedOperation.
+func (in *SeaweedOperation) DeepCopy() *SeaweedOperation {
+	if in == nil {
+		return nil
+	}
+	out := new(SeaweedOperation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SeaweedOperation) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SeaweedOperationList) DeepCopyInto(out *SeaweedOperationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SeaweedOperation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SeaweedOperationList.
+func (in *SeaweedOperationList) DeepCopy() *SeaweedOperationList {
+	if in == nil {
+		return nil
+	}
+	out := new(SeaweedOperationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SeaweedOperationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SeaweedOperationSpec) DeepCopyInto(out *SeaweedOperationSpec) {
+	*out = *in
+	if in.Commands != nil {
+		in, out := &in.Commands, &out.Commands
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TimeoutSeconds != nil {
+		in, out := &in.TimeoutSeconds, &out.TimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SeaweedOperationSpec.
+func (in *SeaweedOperationSpec) DeepCopy() *SeaweedOperationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SeaweedOperationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SeaweedOperationStatus) DeepCopyInto(out *SeaweedOperationStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SeaweedOperationStatus.
+func (in *SeaweedOperationStatus) DeepCopy() *SeaweedOperationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SeaweedOperationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SeaweedSpec) DeepCopyInto(out *SeaweedSpec) {
 	*out = *in
@@ -307,6 +947,11 @@ func (in *SeaweedSpec) DeepCopyInto(out *SeaweedSpec) {
 		*out = new(corev1.PersistentVolumeReclaimPolicy)
 		**out = **in
 	}
+	if in.VolumeSnapshotClassName != nil {
+		in, out := &in.VolumeSnapshotClassName, &out.VolumeSnapshotClassName
+		*out = new(string)
+		**out = **in
+	}
 	if in.ImagePullSecrets != nil {
 		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
 		*out = make([]corev1.LocalObjectReference, len(*in))
@@ -348,6 +993,13 @@ func (in *SeaweedSpec) DeepCopyInto(out *SeaweedSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.VolumePools != nil {
+		in, out := &in.VolumePools, &out.VolumePools
+		*out = make([]VolumePoolSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.HostSuffix != nil {
 		in, out := &in.HostSuffix, &out.HostSuffix
 		*out = new(string)
@@ -358,6 +1010,11 @@ func (in *SeaweedSpec) DeepCopyInto(out *SeaweedSpec) {
 		*out = new(GatewaySpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Maintenance != nil {
+		in, out := &in.Maintenance, &out.Maintenance
+		*out = new(MaintenanceSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SeaweedSpec.
@@ -373,6 +1030,85 @@ func (in *SeaweedSpec) DeepCopy() *SeaweedSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SeaweedStatus) DeepCopyInto(out *SeaweedStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]SeaweedCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	out.Master = in.Master
+	out.Volume = in.Volume
+	if in.VolumePools != nil {
+		in, out := &in.VolumePools, &out.VolumePools
+		*out = make([]VolumePoolStatus, len(*in))
+		copy(*out, *in)
+	}
+	out.Filer = in.Filer
+	out.Gateway = in.Gateway
+	if in.Upgrade != nil {
+		in, out := &in.Upgrade, &out.Upgrade
+		*out = new(UpgradeStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Deletion != nil {
+		in, out := &in.Deletion, &out.Deletion
+		*out = new(DeletionStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.VolumeScaleIn != nil {
+		in, out := &in.VolumeScaleIn, &out.VolumeScaleIn
+		*out = new(VolumeScaleInStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DrainedVolumeServers != nil {
+		in, out := &in.DrainedVolumeServers, &out.DrainedVolumeServers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.VolumeExpansions != nil {
+		in, out := &in.VolumeExpansions, &out.VolumeExpansions
+		*out = make([]VolumeExpansionStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.VolumeDiskMigrations != nil {
+		in, out := &in.VolumeDiskMigrations, &out.VolumeDiskMigrations
+		*out = make([]VolumeDiskMigrationStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.VolumeRollouts != nil {
+		in, out := &in.VolumeRollouts, &out.VolumeRollouts
+		*out = make([]VolumeRolloutStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MasterRollout != nil {
+		in, out := &in.MasterRollout, &out.MasterRollout
+		*out = new(MasterRolloutStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.VolumeBalance != nil {
+		in, out := &in.VolumeBalance, &out.VolumeBalance
+		*out = new(VolumeBalanceStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Maintenance != nil {
+		in, out := &in.Maintenance, &out.Maintenance
+		*out = make([]MaintenanceScriptStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Health != nil {
+		in, out := &in.Health, &out.Health
+		*out = new(ClusterHealth)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SeaweedStatus.
@@ -417,6 +1153,314 @@ func (in *ServiceSpec) DeepCopy() *ServiceSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageSpec) DeepCopyInto(out *StorageSpec) {
+	*out = *in
+	out.Size = in.Size.DeepCopy()
+	if in.StorageClassName != nil {
+		in, out := &in.StorageClassName, &out.StorageClassName
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageSpec.
+func (in *StorageSpec) DeepCopy() *StorageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StoreCredentials) DeepCopyInto(out *StoreCredentials) {
+	*out = *in
+	if in.UsernameSecretRef != nil {
+		in, out := &in.UsernameSecretRef, &out.UsernameSecretRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PasswordSecretRef != nil {
+		in, out := &in.PasswordSecretRef, &out.PasswordSecretRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StoreCredentials.
+func (in *StoreCredentials) DeepCopy() *StoreCredentials {
+	if in == nil {
+		return nil
+	}
+	out := new(StoreCredentials)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeStatus) DeepCopyInto(out *UpgradeStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeStatus.
+func (in *UpgradeStatus) DeepCopy() *UpgradeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeBalanceSpec) DeepCopyInto(out *VolumeBalanceSpec) {
+	*out = *in
+	if in.CooldownSeconds != nil {
+		in, out := &in.CooldownSeconds, &out.CooldownSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Collections != nil {
+		in, out := &in.Collections, &out.Collections
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeBalanceSpec.
+func (in *VolumeBalanceSpec) DeepCopy() *VolumeBalanceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeBalanceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeBalanceStatus) DeepCopyInto(out *VolumeBalanceStatus) {
+	*out = *in
+	if in.LastBalanceTime != nil {
+		in, out := &in.LastBalanceTime, &out.LastBalanceTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeBalanceStatus.
+func (in *VolumeBalanceStatus) DeepCopy() *VolumeBalanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeBalanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeDiskMigrationStatus) DeepCopyInto(out *VolumeDiskMigrationStatus) {
+	*out = *in
+	if in.AddedDisks != nil {
+		in, out := &in.AddedDisks, &out.AddedDisks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RemovedDisks != nil {
+		in, out := &in.RemovedDisks, &out.RemovedDisks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeDiskMigrationStatus.
+func (in *VolumeDiskMigrationStatus) DeepCopy() *VolumeDiskMigrationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeDiskMigrationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeDiskSpec) DeepCopyInto(out *VolumeDiskSpec) {
+	*out = *in
+	out.Size = in.Size.DeepCopy()
+	if in.StorageClassName != nil {
+		in, out := &in.StorageClassName, &out.StorageClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.MaxVolumes != nil {
+		in, out := &in.MaxVolumes, &out.MaxVolumes
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeDiskSpec.
+func (in *VolumeDiskSpec) DeepCopy() *VolumeDiskSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeDiskSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeExpansionStatus) DeepCopyInto(out *VolumeExpansionStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.PendingPersistentVolumeClaims != nil {
+		in, out := &in.PendingPersistentVolumeClaims, &out.PendingPersistentVolumeClaims
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeExpansionStatus.
+func (in *VolumeExpansionStatus) DeepCopy() *VolumeExpansionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeExpansionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumePoolSpec) DeepCopyInto(out *VolumePoolSpec) {
+	*out = *in
+	in.VolumeSpec.DeepCopyInto(&out.VolumeSpec)
+	if in.DiskCount != nil {
+		in, out := &in.DiskCount, &out.DiskCount
+		*out = new(int32)
+		**out = **in
+	}
+	if in.DiskType != nil {
+		in, out := &in.DiskType, &out.DiskType
+		*out = new(string)
+		**out = **in
+	}
+	if in.DataCenter != nil {
+		in, out := &in.DataCenter, &out.DataCenter
+		*out = new(string)
+		**out = **in
+	}
+	if in.Rack != nil {
+		in, out := &in.Rack, &out.Rack
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumePoolSpec.
+func (in *VolumePoolSpec) DeepCopy() *VolumePoolSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumePoolSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumePoolStatus) DeepCopyInto(out *VolumePoolStatus) {
+	*out = *in
+	out.ComponentStatus = in.ComponentStatus
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumePoolStatus.
+func (in *VolumePoolStatus) DeepCopy() *VolumePoolStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumePoolStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeRolloutStatus) DeepCopyInto(out *VolumeRolloutStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]uint32, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeRolloutStatus.
+func (in *VolumeRolloutStatus) DeepCopy() *VolumeRolloutStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeRolloutStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeScaleInStatus) DeepCopyInto(out *VolumeScaleInStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.DrainingServers != nil {
+		in, out := &in.DrainingServers, &out.DrainingServers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ReadonlyVolumes != nil {
+		in, out := &in.ReadonlyVolumes, &out.ReadonlyVolumes
+		*out = make([]uint32, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeScaleInStatus.
+func (in *VolumeScaleInStatus) DeepCopy() *VolumeScaleInStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeScaleInStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeServerHealth) DeepCopyInto(out *VolumeServerHealth) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeServerHealth.
+func (in *VolumeServerHealth) DeepCopy() *VolumeServerHealth {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeServerHealth)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VolumeSpec) DeepCopyInto(out *VolumeSpec) {
 	*out = *in
@@ -462,6 +1506,23 @@ func (in *VolumeSpec) DeepCopyInto(out *VolumeSpec) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.Balance != nil {
+		in, out := &in.Balance, &out.Balance
+		*out = new(VolumeBalanceSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Disks != nil {
+		in, out := &in.Disks, &out.Disks
+		*out = make([]VolumeDiskSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Topology != nil {
+		in, out := &in.Topology, &out.Topology
+		*out = new(VolumeTopologySpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeSpec.
@@ -473,3 +1534,28 @@ func (in *VolumeSpec) DeepCopy() *VolumeSpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeTopologySpec) DeepCopyInto(out *VolumeTopologySpec) {
+	*out = *in
+	if in.DataCenterLabel != nil {
+		in, out := &in.DataCenterLabel, &out.DataCenterLabel
+		*out = new(string)
+		**out = **in
+	}
+	if in.RackLabel != nil {
+		in, out := &in.RackLabel, &out.RackLabel
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeTopologySpec.
+func (in *VolumeTopologySpec) DeepCopy() *VolumeTopologySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeTopologySpec)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/controllers/label"
+)
+
+// configHash hashes the rendered ConfigMaps of a component and the Secrets its pod spec or their placeholders reference.
+// The Secrets generated from the ConfigMaps are covered by the ConfigMaps and their placeholders.
+// It is empty if there is nothing to hash.
+func (r *SeaweedReconciler) configHash(m *seaweedv1.Seaweed, podSpec *corev1.PodSpec, configMaps ...*corev1.ConfigMap) (string, error) {
+	generated := make(map[string]bool)
+	var secretNames []string
+	for _, configMap := range configMaps {
+		generated[configMap.Name] = true
+		secretNames = append(secretNames, placeholderSecrets(configMap.Data)...)
+	}
+	for _, name := range referencedSecrets(podSpec) {
+		if !generated[name] {
+			secretNames = append(secretNames, name)
+		}
+	}
+	secretNames = sortedUnique(secretNames)
+	if len(configMaps) == 0 && len(secretNames) == 0 {
+		return "", nil
+	}
+
+	h := sha256.New()
+	for _, configMap := range configMaps {
+		fmt.Fprintf(h, "configmap %s\n", configMap.Name)
+		writeSortedData(h, configMap.Data, nil)
+	}
+	for _, name := range secretNames {
+		secret := &corev1.Secret{}
+		err := r.Get(context.Background(), types.NamespacedName{Namespace: m.Namespace, Name: name}, secret)
+		if errors.IsNotFound(err) {
+			// the pods can not start before the Secret exists, it is hashed once it does
+			fmt.Fprintf(h, "missing secret %s\n", name)
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "secret %s\n", name)
+		writeSortedData(h, secret.StringData, secret.Data)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+func writeSortedData(w io.Writer, data map[string]string, binaryData map[string][]byte) {
+	var keys []string
+	for k := range data {
+		keys = append(keys, k)
+	}
+	for k := range binaryData {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if v, found := binaryData[k]; found {
+			fmt.Fprintf(w, "%s=%x\n", k, v)
+		} else {
+			fmt.Fprintf(w, "%s=%q\n", k, data[k])
+		}
+	}
+}
+
+// referencedSecrets lists the Secrets the containers read from env and the pod mounts, sorted
+func referencedSecrets(podSpec *corev1.PodSpec) []string {
+	seen := make(map[string]bool)
+	for _, container := range append(append([]corev1.Container{}, podSpec.InitContainers...), podSpec.Containers...) {
+		for _, env := range container.Env {
+			if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
+				seen[env.ValueFrom.SecretKeyRef.Name] = true
+			}
+		}
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.SecretRef != nil {
+				seen[envFrom.SecretRef.Name] = true
+			}
+		}
+	}
+	for _, volume := range podSpec.Volumes {
+		if volume.Secret != nil {
+			seen[volume.Secret.SecretName] = true
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if source.Secret != nil {
+					seen[source.Secret.Name] = true
+				}
+			}
+		}
+	}
+
+	var names []string
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// setConfigHash puts the hash on the pod template, or removes it if the hash is empty
+func setConfigHash(template *corev1.PodTemplateSpec, hash string) {
+	if hash == "" {
+		delete(template.Annotations, label.ConfigHashAnnotationKey)
+		return
+	}
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations[label.ConfigHashAnnotationKey] = hash
+}
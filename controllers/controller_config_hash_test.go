@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+func TestConfigHash(t *testing.T) {
+	r := &SeaweedReconciler{}
+	config := "[leveldb2]\nenabled = true\n"
+	m := testSeaweed(1, 0)
+	m.Spec.Filer = &seaweedv1.FilerSpec{Replicas: 1, Config: &config}
+	podSpec := &corev1.PodSpec{}
+	filerConfigMap := func() *corev1.ConfigMap {
+		configMap, err := r.createFilerConfigMap(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return configMap
+	}
+
+	if hash, err := r.configHash(m, podSpec); err != nil || hash != "" {
+		t.Errorf("hash without config = %q, %v, want none", hash, err)
+	}
+	before, err := r.configHash(m, podSpec, filerConfigMap())
+	if err != nil || before == "" {
+		t.Fatalf("hash = %q, %v", before, err)
+	}
+	again, _ := r.configHash(m, podSpec, filerConfigMap())
+	config = "[leveldb2]\nenabled = false\n"
+	after, _ := r.configHash(m, podSpec, filerConfigMap())
+	if again != before || after == before {
+		t.Errorf("hashes %s, %s, %s: want the first two equal and the last different", before, again, after)
+	}
+}
+
+func TestReferencedSecrets(t *testing.T) {
+	podSpec := &corev1.PodSpec{
+		Containers: []corev1.Container{{
+			Env: []corev1.EnvVar{
+				{Name: "PLAIN", Value: "x"},
+				{Name: "KEY", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "s3-admin"}, Key: "key",
+				}}},
+			},
+			EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "env"}}}},
+		}},
+		Volumes: []corev1.Volume{
+			{Name: "config", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{}}},
+			{Name: "tls", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "tls"}}},
+			{Name: "again", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "s3-admin"}}},
+		},
+	}
+
+	if secrets := referencedSecrets(podSpec); !reflect.DeepEqual(secrets, []string{"env", "s3-admin", "tls"}) {
+		t.Errorf("secrets = %v", secrets)
+	}
+}
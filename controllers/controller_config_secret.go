@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+// secretPlaceholder references a key of a Secret in the namespace of the cluster: ${secret:<name>/<key>}
+var secretPlaceholder = regexp.MustCompile(`\$\{secret:([^/}]+)/([^}]+)\}`)
+
+// hasSecretPlaceholders tells whether the config has to be resolved into a Secret instead of a ConfigMap
+func hasSecretPlaceholders(config string) bool {
+	return secretPlaceholder.MatchString(config)
+}
+
+// placeholderSecrets lists the Secrets referenced by the placeholders in the data
+func placeholderSecrets(data map[string]string) []string {
+	var names []string
+	for _, v := range data {
+		for _, match := range secretPlaceholder.FindAllStringSubmatch(v, -1) {
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
+
+// configVolumeSource mounts the ConfigMap of a component, or the Secret generated in its place when the config has placeholders
+func configVolumeSource(name, config string) corev1.VolumeSource {
+	if hasSecretPlaceholders(config) {
+		return corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{SecretName: name},
+		}
+	}
+	return corev1.VolumeSource{
+		ConfigMap: &corev1.ConfigMapVolumeSource{
+			LocalObjectReference: corev1.LocalObjectReference{Name: name},
+		},
+	}
+}
+
+// createConfigSecret resolves the placeholders of the ConfigMap into a Secret of the same name.
+// The values are escaped for a TOML basic string, so placeholders go between double quotes.
+func (r *SeaweedReconciler) createConfigSecret(m *seaweedv1.Seaweed, configMap *corev1.ConfigMap) (*corev1.Secret, error) {
+	secrets := make(map[string]*corev1.Secret)
+	data := make(map[string][]byte, len(configMap.Data))
+	for file, config := range configMap.Data {
+		var resolveErr error
+		resolved := secretPlaceholder.ReplaceAllStringFunc(config, func(placeholder string) string {
+			match := secretPlaceholder.FindStringSubmatch(placeholder)
+			name, key := match[1], match[2]
+			secret, found := secrets[name]
+			if !found {
+				secret = &corev1.Secret{}
+				if err := r.Get(context.Background(), types.NamespacedName{Namespace: m.Namespace, Name: name}, secret); err != nil {
+					if resolveErr == nil {
+						resolveErr = fmt.Errorf("secret %s referenced in %s: %v", name, file, err)
+					}
+					return placeholder
+				}
+				secrets[name] = secret
+			}
+			value, found := secret.Data[key]
+			if !found {
+				if resolveErr == nil {
+					resolveErr = fmt.Errorf("secret %s referenced in %s has no key %s", name, file, key)
+				}
+				return placeholder
+			}
+			return escapeTomlString(string(value))
+		})
+		if resolveErr != nil {
+			return nil, resolveErr
+		}
+		data[file] = []byte(resolved)
+	}
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      configMap.Name,
+			Namespace: configMap.Namespace,
+			Labels:    configMap.Labels,
+		},
+		Data: data,
+	}, nil
+}
+
+// ensureConfig creates the ConfigMap of a component, or the Secret generated from it when its config has placeholders.
+// The other one is deleted, so switching between them does not leave stale config behind.
+func (r *SeaweedReconciler) ensureConfig(m *seaweedv1.Seaweed, configMap *corev1.ConfigMap) error {
+	var stale runtime.Object
+	templated := false
+	for _, config := range configMap.Data {
+		templated = templated || hasSecretPlaceholders(config)
+	}
+	if templated {
+		secret, err := r.createConfigSecret(m, configMap)
+		if err != nil {
+			return err
+		}
+		if err := controllerutil.SetControllerReference(m, secret, r.Scheme); err != nil {
+			return err
+		}
+		if _, err := r.CreateOrUpdateSecret(secret); err != nil {
+			return err
+		}
+		stale = &corev1.ConfigMap{}
+	} else {
+		if err := controllerutil.SetControllerReference(m, configMap, r.Scheme); err != nil {
+			return err
+		}
+		if _, err := r.CreateOrUpdateConfigMap(configMap); err != nil {
+			return err
+		}
+		stale = &corev1.Secret{}
+	}
+
+	err := r.Get(context.Background(), types.NamespacedName{Namespace: m.Namespace, Name: configMap.Name}, stale)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(stale.(metav1.Object), m) {
+		return nil
+	}
+	r.Log.Info("delete the replaced config", "name", configMap.Name, "templated", templated)
+	if err := r.Delete(context.Background(), stale); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// escapeTomlString escapes the value for a TOML basic string
+func escapeTomlString(value string) string {
+	var b strings.Builder
+	for _, c := range value {
+		switch c {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if c < 0x20 || c == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, c)
+			} else {
+				b.WriteRune(c)
+			}
+		}
+	}
+	return b.String()
+}
+
+func sortedUnique(names []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+func TestConfigSecret(t *testing.T) {
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "pg", Namespace: "default"},
+		Data:       map[string][]byte{"password": []byte(`p"a\ss`)},
+	}
+	r := &SeaweedReconciler{Client: fake.NewFakeClientWithScheme(scheme.Scheme, source)}
+	s3 := false
+	config := "[postgres]\nenabled = true\npassword = \"${secret:pg/password}\"\n"
+	m := testSeaweed(1, 0)
+	m.Spec.Filer = &seaweedv1.FilerSpec{Replicas: 1, Config: &config, S3: &s3}
+
+	configMap, err := r.createFilerConfigMap(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := r.createConfigSecret(m, configMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[postgres]\nenabled = true\npassword = \"p\\\"a\\\\ss\"\n"
+	if got := string(secret.Data["filer.toml"]); got != want {
+		t.Errorf("filer.toml = %q, want %q", got, want)
+	}
+	if source := configVolumeSource(m.Name+"-filer", config); source.Secret == nil || source.Secret.SecretName != "sw-filer" {
+		t.Errorf("config with placeholders is not mounted from the generated Secret: %+v", source)
+	}
+	if source := configVolumeSource(m.Name+"-filer", "[leveldb2]\n"); source.ConfigMap == nil {
+		t.Errorf("config without placeholders is not mounted from the ConfigMap: %+v", source)
+	}
+
+	// the source Secret is hashed, the generated one is not
+	statefulSet := r.createFilerStatefulSet(m)
+	before, err := r.configHash(m, &statefulSet.Spec.Template.Spec, configMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source.Data["password"] = []byte("rotated")
+	if err := r.Update(context.Background(), source); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := r.configHash(m, &statefulSet.Spec.Template.Spec, configMap); after == before {
+		t.Errorf("hash did not change with the source Secret")
+	}
+
+	config = "[postgres]\npassword = \"${secret:pg/missing}\"\n"
+	configMap, _ = r.createFilerConfigMap(m)
+	if _, err := r.createConfigSecret(m, configMap); err == nil {
+		t.Errorf("missing key is not reported")
+	}
+}
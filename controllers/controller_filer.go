@@ -6,6 +6,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
@@ -29,6 +30,10 @@ func (r *SeaweedReconciler) ensureFilerServers(seaweedCR *seaweedv1.Seaweed) (do
 		return
 	}
 
+	if done, result, err = r.ensureFilerStorageMigrated(seaweedCR); done {
+		return
+	}
+
 	if done, result, err = r.ensureFilerStatefulSet(seaweedCR); done {
 		return
 	}
@@ -39,16 +44,39 @@ func (r *SeaweedReconciler) ensureFilerServers(seaweedCR *seaweedv1.Seaweed) (do
 func (r *SeaweedReconciler) ensureFilerStatefulSet(seaweedCR *seaweedv1.Seaweed) (bool, ctrl.Result, error) {
 	log := r.Log.WithValues("sw-filer-statefulset", seaweedCR.Name)
 
+	// reported once per change of the spec
+	if seaweedCR.Generation != seaweedCR.Status.ObservedGeneration {
+		for _, warning := range seaweedCR.FilerStoreWarnings() {
+			r.Recorder.Event(seaweedCR, corev1.EventTypeWarning, "FilerStoreNotPersistent", warning)
+		}
+	}
+
 	filerStatefulSet := r.createFilerStatefulSet(seaweedCR)
+	image, err := r.componentImage(seaweedCR, "filer")
+	if err != nil {
+		return ReconcileResult(err)
+	}
+	filerStatefulSet.Spec.Template.Spec.Containers[0].Image = image
+	filerConfigMap, err := r.createFilerConfigMap(seaweedCR)
+	if err != nil {
+		return ReconcileResult(err)
+	}
+	hash, err := r.configHash(seaweedCR, &filerStatefulSet.Spec.Template.Spec, filerConfigMap)
+	if err != nil {
+		return ReconcileResult(err)
+	}
+	setConfigHash(&filerStatefulSet.Spec.Template, hash)
 	if err := controllerutil.SetControllerReference(seaweedCR, filerStatefulSet, r.Scheme); err != nil {
 		return ReconcileResult(err)
 	}
-	_, err := r.CreateOrUpdate(filerStatefulSet, func(existing, desired runtime.Object) error {
+	_, err = r.CreateOrUpdate(filerStatefulSet, func(existing, desired runtime.Object) error {
 		existingStatefulSet := existing.(*appsv1.StatefulSet)
 		desiredStatefulSet := desired.(*appsv1.StatefulSet)
 
+		existingStatefulSet.Spec.UpdateStrategy = desiredStatefulSet.Spec.UpdateStrategy
 		existingStatefulSet.Spec.Replicas = desiredStatefulSet.Spec.Replicas
 		existingStatefulSet.Spec.Template.Spec = desiredStatefulSet.Spec.Template.Spec
+		setConfigHash(&existingStatefulSet.Spec.Template, hash)
 		return nil
 	})
 	log.Info("ensure filer stateful set " + filerStatefulSet.Name)
@@ -88,11 +116,11 @@ func (r *SeaweedReconciler) ensureFilerService(seaweedCR *seaweedv1.Seaweed) (bo
 func (r *SeaweedReconciler) ensureFilerConfigMap(seaweedCR *seaweedv1.Seaweed) (bool, ctrl.Result, error) {
 	log := r.Log.WithValues("sw-filer-configmap", seaweedCR.Name)
 
-	filerConfigMap := r.createFilerConfigMap(seaweedCR)
-	if err := controllerutil.SetControllerReference(seaweedCR, filerConfigMap, r.Scheme); err != nil {
+	filerConfigMap, err := r.createFilerConfigMap(seaweedCR)
+	if err != nil {
 		return ReconcileResult(err)
 	}
-	_, err := r.CreateOrUpdateConfigMap(filerConfigMap)
+	err = r.ensureConfig(seaweedCR, filerConfigMap)
 
 	log.Info("Get filer ConfigMap " + filerConfigMap.Name)
 	return ReconcileResult(err)
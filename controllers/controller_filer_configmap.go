@@ -7,12 +7,12 @@ import (
 	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
 )
 
-func (r *SeaweedReconciler) createFilerConfigMap(m *seaweedv1.Seaweed) *corev1.ConfigMap {
+func (r *SeaweedReconciler) createFilerConfigMap(m *seaweedv1.Seaweed) (*corev1.ConfigMap, error) {
 	labels := labelsForFiler(m.Name)
 
-	toml := ""
-	if m.Spec.Filer.Config != nil {
-		toml = *m.Spec.Filer.Config
+	toml, err := m.Spec.Filer.FilerToml()
+	if err != nil {
+		return nil, err
 	}
 
 	dep := &corev1.ConfigMap{
@@ -25,5 +25,5 @@ func (r *SeaweedReconciler) createFilerConfigMap(m *seaweedv1.Seaweed) *corev1.C
 			"filer.toml": toml,
 		},
 	}
-	return dep
+	return dep, nil
 }
@@ -59,24 +59,26 @@ func (r *SeaweedReconciler) createAllIngress(m *seaweedv1.Seaweed) *extensionsv1
 		},
 	}
 
-	// add ingress for volume servers
-	for i := 0; i < int(m.Spec.Volume.Replicas); i++ {
-		dep.Spec.Rules = append(dep.Spec.Rules, extensionsv1beta1.IngressRule{
-			Host: fmt.Sprintf("%s-volume-%d.%s", m.Name, i, *m.Spec.HostSuffix),
-			IngressRuleValue: extensionsv1beta1.IngressRuleValue{
-				HTTP: &extensionsv1beta1.HTTPIngressRuleValue{
-					Paths: []extensionsv1beta1.HTTPIngressPath{
-						{
-							Path: "/",
-							Backend: extensionsv1beta1.IngressBackend{
-								ServiceName: fmt.Sprintf("%s-volume-%d", m.Name, i),
-								ServicePort: intstr.FromInt(seaweedv1.VolumeHTTPPort),
+	// add ingress for volume servers, matching their -publicUrl
+	for _, pool := range volumePools(m) {
+		for i := 0; i < int(pool.spec.Replicas); i++ {
+			dep.Spec.Rules = append(dep.Spec.Rules, extensionsv1beta1.IngressRule{
+				Host: fmt.Sprintf("%s.%s", pool.serviceName(m, i), *m.Spec.HostSuffix),
+				IngressRuleValue: extensionsv1beta1.IngressRuleValue{
+					HTTP: &extensionsv1beta1.HTTPIngressRuleValue{
+						Paths: []extensionsv1beta1.HTTPIngressPath{
+							{
+								Path: "/",
+								Backend: extensionsv1beta1.IngressBackend{
+									ServiceName: pool.serviceName(m, i),
+									ServicePort: intstr.FromInt(seaweedv1.VolumeHTTPPort),
+								},
 							},
 						},
 					},
 				},
-			},
-		})
+			})
+		}
 	}
 
 	// Set master instance as the owner and controller
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
@@ -28,7 +29,6 @@ func buildFilerStartupScript(m *seaweedv1.Seaweed) string {
 func (r *SeaweedReconciler) createFilerStatefulSet(m *seaweedv1.Seaweed) *appsv1.StatefulSet {
 	labels := labelsForFiler(m.Name)
 	replicas := int32(m.Spec.Filer.Replicas)
-	rollingUpdatePartition := int32(0)
 	enableServiceLinks := false
 
 	requestCPU := m.Spec.Filer.Requests[corev1.ResourceCPU]
@@ -62,32 +62,37 @@ func (r *SeaweedReconciler) createFilerStatefulSet(m *seaweedv1.Seaweed) *appsv1
 		}
 	}
 
+	// a config which does not render is reported by ensureFilerConfigMap
+	filerToml, _ := m.Spec.Filer.FilerToml()
 	filerPodSpec := m.BaseFilerSpec().BuildPodSpec()
 	filerPodSpec.Volumes = []corev1.Volume{
 		{
-			Name: "filer-config",
-			VolumeSource: corev1.VolumeSource{
-				ConfigMap: &corev1.ConfigMapVolumeSource{
-					LocalObjectReference: corev1.LocalObjectReference{
-						Name: m.Name + "-filer",
-					},
-				},
-			},
+			Name:         "filer-config",
+			VolumeSource: configVolumeSource(m.Name+"-filer", filerToml),
+		},
+	}
+	volumeMounts := []corev1.VolumeMount{
+		{
+			Name:      "filer-config",
+			ReadOnly:  true,
+			MountPath: "/etc/seaweedfs",
 		},
 	}
+	var persistentVolumeClaims []corev1.PersistentVolumeClaim
+	if storage := m.Spec.Filer.Storage; storage != nil {
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      filerDataVolumeName,
+			MountPath: storage.MountPathOrDefault(),
+		})
+		persistentVolumeClaims = append(persistentVolumeClaims, storagePersistentVolumeClaim(filerDataVolumeName, storage))
+	}
 	filerPodSpec.EnableServiceLinks = &enableServiceLinks
 	filerPodSpec.Containers = []corev1.Container{{
 		Name:            "filer",
-		Image:           m.Spec.Image,
+		Image:           m.BaseFilerSpec().Image(),
 		ImagePullPolicy: m.BaseFilerSpec().ImagePullPolicy(),
-		Env:             append(m.BaseFilerSpec().Env(), kubernetesEnvVars...),
-		VolumeMounts: []corev1.VolumeMount{
-			{
-				Name:      "filer-config",
-				ReadOnly:  true,
-				MountPath: "/etc/seaweedfs",
-			},
-		},
+		Env:             append(append(m.BaseFilerSpec().Env(), kubernetesEnvVars...), filerStoreEnvVars(m.Spec.Filer.Store)...),
+		VolumeMounts:    volumeMounts,
 		Command: []string{
 			"/bin/sh",
 			"-ec",
@@ -147,12 +152,7 @@ func (r *SeaweedReconciler) createFilerStatefulSet(m *seaweedv1.Seaweed) *appsv1
 			ServiceName:         m.Name + "-filer-peer",
 			PodManagementPolicy: appsv1.ParallelPodManagement,
 			Replicas:            &replicas,
-			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
-				Type: appsv1.RollingUpdateStatefulSetStrategyType,
-				RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{
-					Partition: &rollingUpdatePartition,
-				},
-			},
+			UpdateStrategy:      statefulSetUpdateStrategy(m.BaseFilerSpec()),
 			Selector: &metav1.LabelSelector{
 				MatchLabels: labels,
 			},
@@ -162,7 +162,31 @@ func (r *SeaweedReconciler) createFilerStatefulSet(m *seaweedv1.Seaweed) *appsv1
 				},
 				Spec: filerPodSpec,
 			},
+			VolumeClaimTemplates: persistentVolumeClaims,
 		},
 	}
 	return dep
 }
+
+// filerStoreEnvVars passes the options of the filer store which come from Secrets.
+// The filer reads WEED_<STORE>_<OPTION> over the option in filer.toml.
+func filerStoreEnvVars(store *seaweedv1.FilerStoreSpec) []corev1.EnvVar {
+	if store == nil {
+		return nil
+	}
+	options := store.SecretOptions()
+	var names []string
+	for option := range options {
+		names = append(names, option)
+	}
+	sort.Strings(names)
+
+	var envVars []corev1.EnvVar
+	for _, option := range names {
+		envVars = append(envVars, corev1.EnvVar{
+			Name:      "WEED_" + strings.ToUpper(store.StoreName()+"_"+option),
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: options[option]},
+		})
+	}
+	return envVars
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/pelletier/go-toml"
+	corev1 "k8s.io/api/core/v1"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+func TestFilerStore(t *testing.T) {
+	r := &SeaweedReconciler{}
+	config := "[filer.options]\nrecursive_delete = true\n"
+	port := int32(26257)
+	password := &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "pg"}, Key: "password"}
+	m := testSeaweed(1, 1)
+	m.Spec.Filer = &seaweedv1.FilerSpec{
+		Replicas: 1,
+		Config:   &config,
+		Store: &seaweedv1.FilerStoreSpec{Postgres: &seaweedv1.PostgresStoreSpec{
+			StoreCredentials: seaweedv1.StoreCredentials{Username: "seaweed", PasswordSecretRef: password},
+			Hostname:         "pg.default",
+			Port:             &port,
+		}},
+	}
+
+	configMap, err := r.createFilerConfigMap(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := toml.Load(configMap.Data["filer.toml"])
+	if err != nil {
+		t.Fatalf("rendered filer.toml does not parse: %v", err)
+	}
+	for key, want := range map[string]interface{}{
+		"postgres.enabled":               true,
+		"postgres.hostname":              "pg.default",
+		"postgres.port":                  int64(26257),
+		"postgres.username":              "seaweed",
+		"postgres.sslmode":               "disable",
+		"filer.options.recursive_delete": true,
+	} {
+		if got := tree.Get(key); got != want {
+			t.Errorf("%s = %v, want %v", key, got, want)
+		}
+	}
+	if tree.Has("postgres.password") {
+		t.Errorf("the password is rendered into filer.toml")
+	}
+
+	envVars := filerStoreEnvVars(m.Spec.Filer.Store)
+	if len(envVars) != 1 || envVars[0].Name != "WEED_POSTGRES_PASSWORD" || envVars[0].ValueFrom.SecretKeyRef != password {
+		t.Errorf("env = %+v, want WEED_POSTGRES_PASSWORD from the Secret", envVars)
+	}
+
+	if err := m.ValidateCreate(); err != nil {
+		t.Errorf("valid store refused: %v", err)
+	}
+	valid := config
+	config += "\n[postgres]\nport = 5432\n"
+	if err := m.ValidateCreate(); err == nil {
+		t.Errorf("config overriding the port of the store is accepted")
+	}
+	config = valid + "\n[leveldb2]\nenabled = true\n"
+	if err := m.ValidateCreate(); err == nil {
+		t.Errorf("config enabling a second store is accepted")
+	}
+	m.Spec.Filer.Config = nil
+	m.Spec.Filer.Store.Redis = &seaweedv1.RedisStoreSpec{Address: "redis:6379"}
+	if err := m.ValidateCreate(); err == nil {
+		t.Errorf("two stores are accepted")
+	}
+}
@@ -66,10 +66,20 @@ func (r *SeaweedReconciler) ensureGatewayDeployment(seaweedCR *seaweedv1.Seaweed
 	log := r.Log.WithValues("sw-s3-gateway-deployment", seaweedCR.Name)
 
 	gatewayDeployment := r.createGatewayDeployment(seaweedCR)
+	image, err := r.componentImage(seaweedCR, "gateway")
+	if err != nil {
+		return ReconcileResult(err)
+	}
+	gatewayDeployment.Spec.Template.Spec.Containers[0].Image = image
+	hash, err := r.configHash(seaweedCR, &gatewayDeployment.Spec.Template.Spec)
+	if err != nil {
+		return ReconcileResult(err)
+	}
+	setConfigHash(&gatewayDeployment.Spec.Template, hash)
 	if err := controllerutil.SetControllerReference(seaweedCR, gatewayDeployment, r.Scheme); err != nil {
 		return ReconcileResult(err)
 	}
-	_, err := r.CreateOrUpdateDeployment(gatewayDeployment)
+	_, err = r.CreateOrUpdateDeployment(gatewayDeployment)
 
 	log.Info("ensure s3 gateway deployment " + gatewayDeployment.Name)
 
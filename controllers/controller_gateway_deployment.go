@@ -66,7 +66,7 @@ func (r *SeaweedReconciler) createGatewayDeployment(m *seaweedv1.Seaweed) *appsv
 	gatewayPodSpec := m.BaseGatewaySpec().BuildPodSpec()
 	gatewayPodSpec.Containers = []corev1.Container{{
 		Name:            "s3-gateway",
-		Image:           m.Spec.Gateway.Image,
+		Image:           m.BaseGatewaySpec().Image(),
 		ImagePullPolicy: m.BaseGatewaySpec().ImagePullPolicy(),
 		Env:             envs,
 
@@ -31,10 +31,18 @@ func (r *SeaweedReconciler) ensureMaster(seaweedCR *seaweedv1.Seaweed) (done boo
 		return
 	}
 
+	if done, result, err = r.ensureMasterStorageMigrated(seaweedCR); done {
+		return
+	}
+
 	if done, result, err = r.ensureMasterStatefulSet(seaweedCR); done {
 		return
 	}
 
+	if done, result, err = r.ensureMastersRolledOut(seaweedCR); done {
+		return
+	}
+
 	if seaweedCR.Spec.Master.ConcurrentStart == nil || !*seaweedCR.Spec.Master.ConcurrentStart {
 		if done, result, err = r.waitForMasterStatefulSet(seaweedCR); done {
 			return
@@ -86,15 +94,22 @@ func (r *SeaweedReconciler) ensureMasterStatefulSet(seaweedCR *seaweedv1.Seaweed
 	log := r.Log.WithValues("sw-master-statefulset", seaweedCR.Name)
 
 	masterStatefulSet := r.createMasterStatefulSet(seaweedCR)
+	hash, err := r.configHash(seaweedCR, &masterStatefulSet.Spec.Template.Spec, r.createMasterConfigMap(seaweedCR))
+	if err != nil {
+		return ReconcileResult(err)
+	}
+	setConfigHash(&masterStatefulSet.Spec.Template, hash)
 	if err := controllerutil.SetControllerReference(seaweedCR, masterStatefulSet, r.Scheme); err != nil {
 		return ReconcileResult(err)
 	}
-	_, err := r.CreateOrUpdate(masterStatefulSet, func(existing, desired runtime.Object) error {
+	_, err = r.CreateOrUpdate(masterStatefulSet, func(existing, desired runtime.Object) error {
 		existingStatefulSet := existing.(*appsv1.StatefulSet)
 		desiredStatefulSet := desired.(*appsv1.StatefulSet)
 
+		existingStatefulSet.Spec.UpdateStrategy = desiredStatefulSet.Spec.UpdateStrategy
 		existingStatefulSet.Spec.Replicas = desiredStatefulSet.Spec.Replicas
 		existingStatefulSet.Spec.Template.Spec = desiredStatefulSet.Spec.Template.Spec
+		setConfigHash(&existingStatefulSet.Spec.Template, hash)
 		return nil
 	})
 	log.Info("ensure master stateful set " + masterStatefulSet.Name)
@@ -105,10 +120,7 @@ func (r *SeaweedReconciler) ensureMasterConfigMap(seaweedCR *seaweedv1.Seaweed)
 	log := r.Log.WithValues("sw-master-configmap", seaweedCR.Name)
 
 	masterConfigMap := r.createMasterConfigMap(seaweedCR)
-	if err := controllerutil.SetControllerReference(seaweedCR, masterConfigMap, r.Scheme); err != nil {
-		return ReconcileResult(err)
-	}
-	_, err := r.CreateOrUpdateConfigMap(masterConfigMap)
+	err := r.ensureConfig(seaweedCR, masterConfigMap)
 
 	log.Info("Get master ConfigMap " + masterConfigMap.Name)
 	return ReconcileResult(err)
@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/controllers/swadmin"
+)
+
+// ensureMastersRolledOut restarts the masters which do not run the latest revision of the StatefulSet.
+// The StatefulSet uses the OnDelete strategy, so the operator restarts one master at a time, the followers first
+// and the raft leader last, and only once every master has rejoined the raft cluster behind the same leader.
+func (r *SeaweedReconciler) ensureMastersRolledOut(m *seaweedv1.Seaweed) (bool, ctrl.Result, error) {
+	log := r.Log.WithValues("sw-master-rollout", m.Name)
+
+	statefulSet := &appsv1.StatefulSet{}
+	err := r.Get(context.Background(), types.NamespacedName{Namespace: m.Namespace, Name: m.Name + "-master"}, statefulSet)
+	if errors.IsNotFound(err) {
+		return ReconcileResult(nil)
+	}
+	if err != nil {
+		return ReconcileResult(err)
+	}
+	if statefulSet.Status.ObservedGeneration < statefulSet.Generation || statefulSet.Status.UpdateRevision == "" {
+		return ReconcileResult(nil)
+	}
+
+	podList := &corev1.PodList{}
+	if err := r.List(context.Background(), podList, client.InNamespace(m.Namespace), client.MatchingLabels(labelsForMaster(m.Name))); err != nil {
+		return ReconcileResult(err)
+	}
+	var outdated []corev1.Pod
+	for _, pod := range podList.Items {
+		if pod.Labels[appsv1.StatefulSetRevisionLabel] != statefulSet.Status.UpdateRevision {
+			outdated = append(outdated, pod)
+		}
+	}
+	if len(outdated) == 0 {
+		m.Status.MasterRollout = nil
+		return ReconcileResult(nil)
+	}
+	rollout := m.Status.MasterRollout
+	if rollout == nil || rollout.Revision != statefulSet.Status.UpdateRevision {
+		now := metav1.Now()
+		rollout = &seaweedv1.MasterRolloutStatus{Revision: statefulSet.Status.UpdateRevision, StartTime: &now}
+		m.Status.MasterRollout = rollout
+	}
+
+	if m.Spec.Master.Replicas < 3 && !m.Spec.Master.RolloutWithoutQuorum {
+		message := fmt.Sprintf("%d masters can not keep a quorum while one of them restarts, delete the outdated master pods or set spec.master.rolloutWithoutQuorum", m.Spec.Master.Replicas)
+		if !rollout.Blocked || rollout.Message != message {
+			log.Info("refuse to restart the masters", "replicas", m.Spec.Master.Replicas)
+			r.Recorder.Event(m, corev1.EventTypeWarning, "MasterRolloutBlocked", message)
+		}
+		rollout.Blocked = true
+		rollout.Message = message
+		return ReconcileResult(nil)
+	}
+
+	for _, pod := range podList.Items {
+		if pod.DeletionTimestamp != nil || !podReady(&pod) {
+			log.Info("wait for master pod before restarting the next one", "pod", pod.Name)
+			rollout.Blocked = false
+			rollout.Message = fmt.Sprintf("wait for master %s to be ready", pod.Name)
+			return ReconcileResult(nil)
+		}
+	}
+	if int32(len(podList.Items)) < m.Spec.Master.Replicas {
+		log.Info("wait for all master pods before restarting one", "pods", len(podList.Items))
+		rollout.Blocked = false
+		rollout.Message = fmt.Sprintf("wait for %d master pods, %d exist", m.Spec.Master.Replicas, len(podList.Items))
+		return ReconcileResult(nil)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), swadminTimeout)
+	defer cancel()
+	leader, err := masterRaftLeader(ctx, r.seaweedAdmin(m))
+	if err != nil {
+		log.Info("wait for the masters to rejoin the raft cluster before restarting one", "reason", err.Error())
+		rollout.Blocked = true
+		rollout.Message = fmt.Sprintf("wait for the masters to rejoin the raft cluster: %v", err)
+		return ReconcileResult(nil)
+	}
+
+	pod := nextMasterToRestart(outdated, leader)
+	isLeader := isMasterPod(leader, pod.Name)
+	log.Info("restart master", "pod", pod.Name, "leader", isLeader)
+	if err := r.Delete(context.Background(), pod); err != nil && !errors.IsNotFound(err) {
+		return ReconcileResult(err)
+	}
+	role := "follower"
+	if isLeader {
+		role = "leader"
+	}
+	rollout.Blocked = false
+	rollout.Message = fmt.Sprintf("restarted master %s %s, %d outdated masters left", role, pod.Name, len(outdated)-1)
+	r.Recorder.Eventf(m, corev1.EventTypeNormal, "MasterRestarted", "Restarted master %s %s to run revision %s", role, pod.Name, statefulSet.Status.UpdateRevision)
+	return ReconcileResult(nil)
+}
+
+// masterRaftLeader reads the raft state of every master through the admin and returns the leader they agree on.
+// It fails unless every master responds and follows the same leader, so one master can be restarted
+// while the others keep the quorum.
+func masterRaftLeader(ctx context.Context, admin swadmin.Admin) (string, error) {
+	statuses, errs := admin.ClusterStatus(ctx)
+	return raftLeader(strings.Split(admin.Masters(), ","), statuses, errs)
+}
+
+func raftLeader(masters []string, statuses []*swadmin.ClusterStatus, errs []error) (string, error) {
+	leader := ""
+	for i, status := range statuses {
+		if errs[i] != nil {
+			return "", fmt.Errorf("master %s does not respond: %v", masters[i], errs[i])
+		}
+		follows := status.Leader
+		if status.IsLeader {
+			follows = masters[i]
+		}
+		switch {
+		case follows == "":
+			return "", fmt.Errorf("master %s has no leader", masters[i])
+		case leader == "":
+			leader = follows
+		case follows != leader:
+			return "", fmt.Errorf("master %s follows %s instead of %s", masters[i], follows, leader)
+		}
+	}
+	if leader == "" {
+		return "", fmt.Errorf("no master")
+	}
+	return leader, nil
+}
+
+// nextMasterToRestart picks the follower with the highest ordinal, and the leader once it is the only one left
+func nextMasterToRestart(outdated []corev1.Pod, leader string) *corev1.Pod {
+	sort.Slice(outdated, func(i, j int) bool {
+		iLeader := isMasterPod(leader, outdated[i].Name)
+		jLeader := isMasterPod(leader, outdated[j].Name)
+		if iLeader != jLeader {
+			return jLeader
+		}
+		return podOrdinal(outdated[i].Name) > podOrdinal(outdated[j].Name)
+	})
+	return &outdated[0]
+}
+
+// isMasterPod tells whether the master address host:port belongs to the pod
+func isMasterPod(address, podName string) bool {
+	return strings.HasPrefix(address, podName+".")
+}
+
+// podOrdinal parses the ordinal out of the "<statefulset>-<ordinal>" pod name
+func podOrdinal(podName string) int {
+	ordinal, _ := strconv.Atoi(podName[strings.LastIndex(podName, "-")+1:])
+	return ordinal
+}
+
+func podReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
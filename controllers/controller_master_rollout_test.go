@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/seaweedfs/seaweedfs-operator/controllers/swadmin"
+)
+
+func TestRaftLeader(t *testing.T) {
+	masters := getMasterAddresses("default", "sw", 3)
+	follower := func(leader string) *swadmin.ClusterStatus { return &swadmin.ClusterStatus{Leader: leader} }
+	leader := &swadmin.ClusterStatus{IsLeader: true}
+
+	got, err := raftLeader(masters, []*swadmin.ClusterStatus{follower(masters[1]), leader, follower(masters[1])}, make([]error, 3))
+	if err != nil || got != masters[1] {
+		t.Errorf("leader = %q, %v, want %q", got, err, masters[1])
+	}
+
+	for name, tc := range map[string]struct {
+		statuses []*swadmin.ClusterStatus
+		errs     []error
+	}{
+		"restarting": {[]*swadmin.ClusterStatus{follower(masters[1]), leader, nil}, []error{nil, nil, fmt.Errorf("connection refused")}},
+		"electing":   {[]*swadmin.ClusterStatus{follower(""), follower(""), follower("")}, make([]error, 3)},
+		"rejoining":  {[]*swadmin.ClusterStatus{follower(masters[1]), leader, follower(masters[0])}, make([]error, 3)},
+	} {
+		if got, err := raftLeader(masters, tc.statuses, tc.errs); err == nil {
+			t.Errorf("%s: leader = %q, want an error", name, got)
+		}
+	}
+}
+
+func TestNextMasterToRestart(t *testing.T) {
+	masters := getMasterAddresses("default", "sw", 11)
+	pods := func(ordinals ...int) []corev1.Pod {
+		var pods []corev1.Pod
+		for _, i := range ordinals {
+			pods = append(pods, corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("sw-master-%d", i)}})
+		}
+		return pods
+	}
+
+	for _, tc := range []struct {
+		outdated []corev1.Pod
+		leader   string
+		want     string
+	}{
+		{pods(0, 1, 2), masters[2], "sw-master-1"},
+		{pods(2, 9, 10), masters[10], "sw-master-9"},
+		{pods(0, 2), masters[0], "sw-master-2"},
+		{pods(0), masters[0], "sw-master-0"},
+	} {
+		if got := nextMasterToRestart(tc.outdated, tc.leader).Name; got != tc.want {
+			t.Errorf("next of %d outdated with leader %s = %s, want %s", len(tc.outdated), tc.leader, got, tc.want)
+		}
+	}
+}
+
+func TestEnsureMastersRolledOut(t *testing.T) {
+	m := testSeaweed(1, 1)
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "sw-master", Namespace: "default"},
+		Status:     appsv1.StatefulSetStatus{UpdateRevision: "sw-master-v2"},
+	}
+	objects := []runtime.Object{statefulSet}
+	for i := 0; i < 3; i++ {
+		labels := labelsForMaster("sw")
+		labels[appsv1.StatefulSetRevisionLabel] = "sw-master-v1"
+		objects = append(objects, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("sw-master-%d", i), Namespace: "default", Labels: labels},
+			Status:     corev1.PodStatus{Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}},
+		})
+	}
+	r := newFakeReconciler(objects...)
+	masters := getMasterAddresses("default", "sw", 3)
+	admin := swadmin.NewFakeAdmin("")
+	useFakeAdmin(r, admin)
+	podExists := func(name string) bool {
+		err := r.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, &corev1.Pod{})
+		if err != nil && !errors.IsNotFound(err) {
+			t.Fatal(err)
+		}
+		return err == nil
+	}
+
+	// a single master is not restarted without the opt-in
+	if done, _, err := r.ensureMastersRolledOut(m); done || err != nil {
+		t.Fatalf("done = %v, err = %v", done, err)
+	}
+	if rollout := m.Status.MasterRollout; rollout == nil || !rollout.Blocked || rollout.Revision != "sw-master-v2" {
+		t.Fatalf("rollout = %+v, want blocked", rollout)
+	}
+	if !podExists("sw-master-0") {
+		t.Errorf("master restarted without a quorum")
+	}
+	if len(r.Recorder.(*record.FakeRecorder).Events) != 1 {
+		t.Errorf("blocked rollout recorded %d events, want 1", len(r.Recorder.(*record.FakeRecorder).Events))
+	}
+
+	// the leader is read through the admin, and the rollout waits while a master does not respond
+	m.Spec.Master.Replicas = 3
+	admin.ClusterStatuses = map[string]*swadmin.ClusterStatus{
+		masters[0]: {Leader: masters[1]},
+		masters[1]: {IsLeader: true},
+	}
+	if done, _, err := r.ensureMastersRolledOut(m); done || err != nil {
+		t.Fatalf("done = %v, err = %v", done, err)
+	}
+	if rollout := m.Status.MasterRollout; !rollout.Blocked || !podExists("sw-master-0") || !podExists("sw-master-2") {
+		t.Errorf("rollout = %+v, want blocked on the raft cluster", rollout)
+	}
+
+	admin.ClusterStatuses[masters[2]] = &swadmin.ClusterStatus{Leader: masters[1]}
+	if done, _, err := r.ensureMastersRolledOut(m); done || err != nil {
+		t.Fatalf("done = %v, err = %v", done, err)
+	}
+	if rollout := m.Status.MasterRollout; rollout.Blocked || podExists("sw-master-2") || !podExists("sw-master-1") {
+		t.Errorf("rollout = %+v, want the follower sw-master-2 restarted", rollout)
+	}
+}
@@ -35,6 +35,10 @@ func buildMasterStartupScript(m *seaweedv1.Seaweed) string {
 		command = append(command, fmt.Sprintf("-defaultReplication=%s", *spec.DefaultReplication))
 	}
 
+	if spec.Storage != nil {
+		command = append(command, fmt.Sprintf("-mdir=%s", spec.Storage.MountPathOrDefault()))
+	}
+
 	command = append(command, fmt.Sprintf("-ip=$(POD_NAME).%s-master-peer.%s", m.Name, m.Namespace))
 	command = append(command, fmt.Sprintf("-peers=%s", getMasterPeersString(m)))
 	command = append(command, fmt.Sprintf("-metricsPort=9999"))	
@@ -44,7 +48,6 @@ func buildMasterStartupScript(m *seaweedv1.Seaweed) string {
 func (r *SeaweedReconciler) createMasterStatefulSet(m *seaweedv1.Seaweed) *appsv1.StatefulSet {
 	labels := labelsForMaster(m.Name)
 	replicas := m.Spec.Master.Replicas
-	rollingUpdatePartition := int32(0)
 	enableServiceLinks := false
 
 	requestCPU := m.Spec.Master.Requests[corev1.ResourceCPU]
@@ -81,29 +84,32 @@ func (r *SeaweedReconciler) createMasterStatefulSet(m *seaweedv1.Seaweed) *appsv
 	masterPodSpec := m.BaseMasterSpec().BuildPodSpec()
 	masterPodSpec.Volumes = []corev1.Volume{
 		{
-			Name: "master-config",
-			VolumeSource: corev1.VolumeSource{
-				ConfigMap: &corev1.ConfigMapVolumeSource{
-					LocalObjectReference: corev1.LocalObjectReference{
-						Name: m.Name + "-master",
-					},
-				},
-			},
+			Name:         "master-config",
+			VolumeSource: configVolumeSource(m.Name+"-master", r.createMasterConfigMap(m).Data["master.toml"]),
+		},
+	}
+	volumeMounts := []corev1.VolumeMount{
+		{
+			Name:      "master-config",
+			ReadOnly:  true,
+			MountPath: "/etc/seaweedfs",
 		},
 	}
+	var persistentVolumeClaims []corev1.PersistentVolumeClaim
+	if storage := m.Spec.Master.Storage; storage != nil {
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      masterDataVolumeName,
+			MountPath: storage.MountPathOrDefault(),
+		})
+		persistentVolumeClaims = append(persistentVolumeClaims, storagePersistentVolumeClaim(masterDataVolumeName, storage))
+	}
 	masterPodSpec.EnableServiceLinks = &enableServiceLinks
 	masterPodSpec.Containers = []corev1.Container{{
 		Name:            "master",
-		Image:           m.Spec.Image,
+		Image:           m.BaseMasterSpec().Image(),
 		ImagePullPolicy: m.BaseMasterSpec().ImagePullPolicy(),
 		Env:             append(m.BaseMasterSpec().Env(), kubernetesEnvVars...),
-		VolumeMounts: []corev1.VolumeMount{
-			{
-				Name:      "master-config",
-				ReadOnly:  true,
-				MountPath: "/etc/seaweedfs",
-			},
-		},
+		VolumeMounts:    volumeMounts,
 		Command: []string{
 			"/bin/sh",
 			"-ec",
@@ -159,11 +165,9 @@ func (r *SeaweedReconciler) createMasterStatefulSet(m *seaweedv1.Seaweed) *appsv
 			ServiceName:         m.Name + "-master-peer",
 			PodManagementPolicy: appsv1.ParallelPodManagement,
 			Replicas:            &replicas,
+			// the operator restarts the masters itself, the raft leader last
 			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
-				Type: appsv1.RollingUpdateStatefulSetStrategyType,
-				RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{
-					Partition: &rollingUpdatePartition,
-				},
+				Type: appsv1.OnDeleteStatefulSetStrategyType,
 			},
 			Selector: &metav1.LabelSelector{
 				MatchLabels: labels,
@@ -174,6 +178,7 @@ func (r *SeaweedReconciler) createMasterStatefulSet(m *seaweedv1.Seaweed) *appsv
 				},
 				Spec: masterPodSpec,
 			},
+			VolumeClaimTemplates: persistentVolumeClaims,
 		},
 	}
 	// Set master instance as the owner and controller
@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/controllers/label"
+	"github.com/seaweedfs/seaweedfs-operator/controllers/swadmin"
+)
+
+// ensurePVReclaimPolicy applies Spec.PVReclaimPolicy to the PVs bound to the PVCs of the cluster
+func (r *SeaweedReconciler) ensurePVReclaimPolicy(seaweedCR *seaweedv1.Seaweed) (bool, ctrl.Result, error) {
+	if seaweedCR.Spec.PVReclaimPolicy == nil {
+		return ReconcileResult(nil)
+	}
+
+	pvcs, err := r.listSeaweedPersistentVolumeClaims(seaweedCR)
+	if err != nil {
+		return ReconcileResult(err)
+	}
+
+	for i := range pvcs {
+		if err := r.setPVReclaimPolicy(&pvcs[i], *seaweedCR.Spec.PVReclaimPolicy); err != nil {
+			return ReconcileResult(err)
+		}
+	}
+	return ReconcileResult(nil)
+}
+
+// listSeaweedPersistentVolumeClaims lists the PVCs created from the volumeClaimTemplates of all components.
+// The StatefulSet controller copies the selector labels onto the PVCs, so they carry the instance label.
+func (r *SeaweedReconciler) listSeaweedPersistentVolumeClaims(seaweedCR *seaweedv1.Seaweed) ([]corev1.PersistentVolumeClaim, error) {
+	pvcList := &corev1.PersistentVolumeClaimList{}
+	listOpts := []client.ListOption{
+		client.InNamespace(seaweedCR.Namespace),
+		client.MatchingLabels(labelsForSeaweed(seaweedCR.Name)),
+	}
+	if err := r.List(context.Background(), pvcList, listOpts...); err != nil {
+		return nil, err
+	}
+	return pvcList.Items, nil
+}
+
+// setPVReclaimPolicy changes the reclaim policy of the PV bound to the given PVC, if any
+func (r *SeaweedReconciler) setPVReclaimPolicy(pvc *corev1.PersistentVolumeClaim, policy corev1.PersistentVolumeReclaimPolicy) error {
+	if pvc.Spec.VolumeName == "" {
+		return nil
+	}
+
+	pv := &corev1.PersistentVolume{}
+	if err := r.Get(context.Background(), types.NamespacedName{Name: pvc.Spec.VolumeName}, pv); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	if pv.Spec.PersistentVolumeReclaimPolicy == policy {
+		return nil
+	}
+
+	r.Log.Info("set pv reclaim policy", "pv", pv.Name, "pvc", pvc.Name, "policy", policy)
+	pv.Spec.PersistentVolumeReclaimPolicy = policy
+	return r.Update(context.Background(), pv)
+}
+
+func labelsForSeaweed(name string) map[string]string {
+	return map[string]string{
+		label.ManagedByLabelKey: "seaweedfs-operator",
+		label.NameLabelKey:      "seaweedfs",
+		label.InstanceLabelKey:  name,
+	}
+}
+
+// ensureOrphanPVCsReclaimed deletes the volume server PVCs left behind by a StatefulSet scale-in.
+// Only the PVCs of servers emptied by a completed drain are deleted, and only while the master has no volumes
+// or EC shards registered from the removed server. A server which merely left the topology keeps its PVCs.
+func (r *SeaweedReconciler) ensureOrphanPVCsReclaimed(m *seaweedv1.Seaweed) (bool, ctrl.Result, error) {
+	log := r.Log.WithValues("sw-pvc-reclaim", m.Name)
+
+	if m.Spec.EnablePVReclaim == nil || !*m.Spec.EnablePVReclaim {
+		return ReconcileResult(nil)
+	}
+
+	pvcList := &corev1.PersistentVolumeClaimList{}
+	listOpts := []client.ListOption{
+		client.InNamespace(m.Namespace),
+		client.MatchingLabels(labelsForVolumeServer(m.Name)),
+	}
+	if err := r.List(context.Background(), pvcList, listOpts...); err != nil {
+		return ReconcileResult(err)
+	}
+
+	type orphan struct {
+		pvc    *corev1.PersistentVolumeClaim
+		server string
+	}
+	var orphans []orphan
+	// the servers of the scaled in ordinals which still have PVCs
+	var scaledIn []string
+	for i := range pvcList.Items {
+		pvc := &pvcList.Items[i]
+		if pvc.DeletionTimestamp != nil {
+			continue
+		}
+		for _, pool := range volumePools(m) {
+			ordinal, ok := persistentVolumeClaimOrdinal(pvc.Name, pool.statefulSetName(m))
+			if !ok || ordinal < int(pool.spec.Replicas) {
+				continue
+			}
+			scaledIn = append(scaledIn, pool.serverAddress(m, int32(ordinal)))
+
+			// the pod may still be terminating and holding on to the claim
+			pod := &corev1.Pod{}
+			err := r.Get(context.Background(), types.NamespacedName{Namespace: m.Namespace, Name: pool.serviceName(m, ordinal)}, pod)
+			if err == nil {
+				continue
+			}
+			if !errors.IsNotFound(err) {
+				return ReconcileResult(err)
+			}
+			orphans = append(orphans, orphan{pvc: pvc, server: pool.serverAddress(m, int32(ordinal))})
+		}
+	}
+	// a drained server is forgotten once its PVCs are gone
+	var drained []string
+	for _, server := range m.Status.DrainedVolumeServers {
+		if containsString(scaledIn, server) {
+			drained = append(drained, server)
+		}
+	}
+	m.Status.DrainedVolumeServers = drained
+	if len(orphans) == 0 {
+		return ReconcileResult(nil)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), swadminTimeout)
+	defer cancel()
+	topology, err := r.seaweedAdmin(m).VolumeList(ctx)
+	if err != nil {
+		log.Info("skip reclaiming orphan PVCs, can not read the volume topology", "error", err.Error())
+		return ReconcileResult(nil)
+	}
+
+	for _, o := range orphans {
+		if !containsString(m.Status.DrainedVolumeServers, o.server) {
+			log.Info("keep orphan PVC, its server was not drained before the scale-in", "pvc", o.pvc.Name, "server", o.server)
+			continue
+		}
+		if count := countVolumesOnServer(topology, o.server); count > 0 {
+			log.Info("keep orphan PVC, the master still has volumes on its server", "pvc", o.pvc.Name, "server", o.server, "volumes", count)
+			continue
+		}
+
+		log.Info("reclaim orphan PVC", "pvc", o.pvc.Name)
+		if err := r.Delete(context.Background(), o.pvc); err != nil && !errors.IsNotFound(err) {
+			return ReconcileResult(err)
+		}
+		r.Recorder.Eventf(m, corev1.EventTypeNormal, "PVCReclaimed",
+			"Deleted PVC %s left by the scale-in of volume server %s", o.pvc.Name, o.server)
+	}
+
+	return ReconcileResult(nil)
+}
+
+// persistentVolumeClaimOrdinal parses the pod ordinal out of a "<template>-<statefulset>-<ordinal>" PVC name
+func persistentVolumeClaimOrdinal(pvcName, statefulSetName string) (int, bool) {
+	i := strings.LastIndex(pvcName, "-")
+	if i < 0 || !strings.HasSuffix(pvcName[:i], "-"+statefulSetName) {
+		return 0, false
+	}
+	ordinal, err := strconv.Atoi(pvcName[i+1:])
+	if err != nil {
+		return 0, false
+	}
+	return ordinal, true
+}
+
+// countVolumesOnServer counts the volumes and EC shards the master has registered from the server
+func countVolumesOnServer(topology *swadmin.Topology, server string) int {
+	node := topology.Node(server)
+	if node == nil {
+		return 0
+	}
+	return len(node.Volumes()) + node.ECShardCount()
+}
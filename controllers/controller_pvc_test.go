@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/seaweedfs/seaweedfs-operator/controllers/swadmin"
+)
+
+func TestOrphanPVCsReclaimedAfterDrain(t *testing.T) {
+	enabled := true
+	m := testSeaweed(1, 1)
+	m.Spec.EnablePVReclaim = &enabled
+	m.Status.DrainedVolumeServers = []string{"sw-volume-1.sw-volume-peer.default:8444"}
+	pvc := func(name string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labelsForVolumeServer("sw")}}
+	}
+	r := newFakeReconciler(pvc("mount0-sw-volume-0"), pvc("mount0-sw-volume-1"), pvc("mount0-sw-volume-2"))
+	// the departed servers are no longer in the topology
+	admin := swadmin.NewFakeAdmin("")
+	admin.Topology = testTopology(map[string][]uint32{"sw-volume-0.sw-volume-peer.default:8444": {1}})
+	useFakeAdmin(r, admin)
+
+	for i := 0; i < 2; i++ {
+		if done, _, err := r.ensureOrphanPVCsReclaimed(m); done || err != nil {
+			t.Fatalf("done = %v, err = %v", done, err)
+		}
+	}
+	for name, kept := range map[string]bool{
+		"mount0-sw-volume-0": true,
+		// drained by a scale-in
+		"mount0-sw-volume-1": false,
+		// left the topology without a drain
+		"mount0-sw-volume-2": true,
+	} {
+		err := r.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, &corev1.PersistentVolumeClaim{})
+		if kept && err != nil || !kept && !errors.IsNotFound(err) {
+			t.Errorf("PVC %s: kept = %v, err = %v", name, kept, err)
+		}
+	}
+	if len(m.Status.DrainedVolumeServers) != 0 {
+		t.Errorf("drained servers without PVCs are kept: %v", m.Status.DrainedVolumeServers)
+	}
+}
@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+const (
+	masterDataVolumeName = "master-data"
+	filerDataVolumeName  = "filer-data"
+)
+
+// storagePersistentVolumeClaim is the volumeClaimTemplate of a StorageSpec
+func storagePersistentVolumeClaim(name string, storage *seaweedv1.StorageSpec) corev1.PersistentVolumeClaim {
+	return corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			StorageClassName: storage.StorageClassName,
+			AccessModes: []corev1.PersistentVolumeAccessMode{
+				corev1.ReadWriteOnce,
+			},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: storage.Size,
+				},
+			},
+		},
+	}
+}
+
+// ensureMasterStorageMigrated recreates the master StatefulSet when spec.master.storage is added or removed.
+// ensureMastersRolledOut then restarts the masters one at a time onto the new storage.
+func (r *SeaweedReconciler) ensureMasterStorageMigrated(m *seaweedv1.Seaweed) (bool, ctrl.Result, error) {
+	return r.ensureStorageMigrated(m, r.createMasterStatefulSet(m), m.Spec.Master.Storage)
+}
+
+// ensureFilerStorageMigrated recreates the filer StatefulSet when spec.filer.storage is added or removed,
+// the filers are then restarted by their update strategy
+func (r *SeaweedReconciler) ensureFilerStorageMigrated(m *seaweedv1.Seaweed) (bool, ctrl.Result, error) {
+	return r.ensureStorageMigrated(m, r.createFilerStatefulSet(m), m.Spec.Filer.Storage)
+}
+
+// ensureStorageMigrated recreates a StatefulSet whose volumeClaimTemplates differ from the desired ones,
+// since they can not be changed. The StatefulSet is deleted with the orphan propagation policy,
+// so the pods keep running until they are restarted into the new revision.
+func (r *SeaweedReconciler) ensureStorageMigrated(m *seaweedv1.Seaweed, desired *appsv1.StatefulSet, storage *seaweedv1.StorageSpec) (bool, ctrl.Result, error) {
+	log := r.Log.WithValues("sw-storage", m.Name, "statefulSet", desired.Name)
+
+	statefulSet := &appsv1.StatefulSet{}
+	err := r.Get(context.Background(), types.NamespacedName{Namespace: m.Namespace, Name: desired.Name}, statefulSet)
+	if errors.IsNotFound(err) {
+		return ReconcileResult(nil)
+	}
+	if err != nil {
+		return ReconcileResult(err)
+	}
+	if statefulSet.DeletionTimestamp != nil {
+		return true, ctrl.Result{RequeueAfter: statefulSetRecreateDelay}, nil
+	}
+	if equalStrings(claimTemplateNames(statefulSet), claimTemplateNames(desired)) {
+		return ReconcileResult(nil)
+	}
+
+	log.Info("storage changed, recreate the StatefulSet", "from", claimTemplateNames(statefulSet), "to", claimTemplateNames(desired))
+	err = r.Delete(context.Background(), statefulSet, client.PropagationPolicy(metav1.DeletePropagationOrphan))
+	if err != nil && !errors.IsNotFound(err) {
+		return ReconcileResult(err)
+	}
+	if storage != nil {
+		r.Recorder.Eventf(m, corev1.EventTypeNormal, "StorageMigrating",
+			"Recreating StatefulSet %s with a PVC at %s, the pods are restarted onto it", statefulSet.Name, storage.MountPathOrDefault())
+	} else {
+		r.Recorder.Eventf(m, corev1.EventTypeNormal, "StorageMigrating",
+			"Recreating StatefulSet %s without its PVC, the PVCs are kept", statefulSet.Name)
+	}
+	return true, ctrl.Result{RequeueAfter: statefulSetRecreateDelay}, nil
+}
+
+func claimTemplateNames(statefulSet *appsv1.StatefulSet) []string {
+	var names []string
+	for _, template := range statefulSet.Spec.VolumeClaimTemplates {
+		names = append(names, template.Name)
+	}
+	return names
+}
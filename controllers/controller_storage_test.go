@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+func TestMasterStorage(t *testing.T) {
+	r := &SeaweedReconciler{}
+	m := testSeaweed(3, 0)
+
+	statefulSet := r.createMasterStatefulSet(m)
+	if len(statefulSet.Spec.VolumeClaimTemplates) != 0 || strings.Contains(buildMasterStartupScript(m), "-mdir") {
+		t.Errorf("masters without storage have a PVC or -mdir")
+	}
+
+	m.Spec.Master.Storage = &seaweedv1.StorageSpec{Size: resource.MustParse("10Gi")}
+	statefulSet = r.createMasterStatefulSet(m)
+	if names := claimTemplateNames(statefulSet); !equalStrings(names, []string{masterDataVolumeName}) {
+		t.Errorf("volumeClaimTemplates = %v", names)
+	}
+	mounted := false
+	for _, mount := range statefulSet.Spec.Template.Spec.Containers[0].VolumeMounts {
+		mounted = mounted || (mount.Name == masterDataVolumeName && mount.MountPath == "/data")
+	}
+	if !mounted {
+		t.Errorf("%s is not mounted at /data", masterDataVolumeName)
+	}
+	if script := buildMasterStartupScript(m); !strings.Contains(script, " -mdir=/data ") {
+		t.Errorf("%q is missing -mdir", script)
+	}
+}
+
+func TestFilerStorage(t *testing.T) {
+	r := &SeaweedReconciler{}
+	config := "[leveldb2]\nenabled = true\ndir = \"/data/filerldb2\"\n"
+	m := testSeaweed(1, 0)
+	m.Spec.Filer = &seaweedv1.FilerSpec{Replicas: 1, Config: &config}
+	s3 := false
+	m.Spec.Filer.S3 = &s3
+
+	if warnings := m.FilerStoreWarnings(); len(warnings) != 1 || !strings.Contains(warnings[0], "leveldb2") {
+		t.Errorf("warnings without storage = %v", warnings)
+	}
+
+	m.Spec.Filer.Storage = &seaweedv1.StorageSpec{Size: resource.MustParse("1Gi")}
+	if warnings := m.FilerStoreWarnings(); len(warnings) != 0 {
+		t.Errorf("warnings with storage = %v", warnings)
+	}
+	if names := claimTemplateNames(r.createFilerStatefulSet(m)); !equalStrings(names, []string{filerDataVolumeName}) {
+		t.Errorf("volumeClaimTemplates = %v", names)
+	}
+
+	m.Spec.Filer.Storage.MountPath = "/meta"
+	if warnings := m.FilerStoreWarnings(); len(warnings) != 1 || !strings.Contains(warnings[0], "/meta") {
+		t.Errorf("warnings with the store outside the storage = %v", warnings)
+	}
+}
@@ -17,34 +17,112 @@ func (r *SeaweedReconciler) ensureVolumeServers(seaweedCR *seaweedv1.Seaweed) (d
 	_ = context.Background()
 	_ = r.Log.WithValues("seaweed", seaweedCR.Name)
 
-	if done, result, err = r.ensureVolumeServerPeerService(seaweedCR); done {
+	for _, pool := range volumePools(seaweedCR) {
+		if done, result, err = r.ensureVolumePool(seaweedCR, pool); done {
+			return
+		}
+	}
+
+	// a scale-in of a pool which is no longer in the spec can not finish
+	if scaleIn := seaweedCR.Status.VolumeScaleIn; scaleIn != nil {
+		if _, found := findVolumePool(seaweedCR, scaleIn.Pool); !found {
+			if done, result, err = ReconcileResult(r.cancelVolumeScaleIn(seaweedCR, volumePool{name: scaleIn.Pool})); done {
+				return
+			}
+		}
+	}
+
+	if done, result, err = r.ensureVolumeServerTopology(seaweedCR); done {
 		return
 	}
 
-	if done, result, err = r.ensureVolumeServerServices(seaweedCR); done {
+	if done, result, err = r.ensurePVReclaimPolicy(seaweedCR); done {
 		return
 	}
 
-	if done, result, err = r.ensureVolumeServerStatefulSet(seaweedCR); done {
+	if done, result, err = r.ensureOrphanPVCsReclaimed(seaweedCR); done {
+		return
+	}
+
+	if done, result, err = r.ensureVolumeBalanced(seaweedCR); done {
 		return
 	}
 
 	return
 }
 
-func (r *SeaweedReconciler) ensureVolumeServerStatefulSet(seaweedCR *seaweedv1.Seaweed) (bool, ctrl.Result, error) {
+// ensureVolumePool creates the services and the StatefulSet of a volume pool
+func (r *SeaweedReconciler) ensureVolumePool(seaweedCR *seaweedv1.Seaweed, pool volumePool) (done bool, result ctrl.Result, err error) {
+	if done, result, err = r.ensureVolumePoolLabeled(seaweedCR, pool); done {
+		return
+	}
+
+	if done, result, err = r.ensureVolumeServerPeerService(seaweedCR, pool); done {
+		return
+	}
+
+	if done, result, err = r.ensureVolumeServerServices(seaweedCR, pool); done {
+		return
+	}
+
+	if done, result, err = r.ensureVolumeStorageExpanded(seaweedCR, pool); done {
+		return
+	}
+
+	if done, result, err = r.ensureVolumeDisksMigrated(seaweedCR, pool); done {
+		return
+	}
+
+	if done, result, err = r.ensureVolumeServerStatefulSet(seaweedCR, pool); done {
+		return
+	}
+
+	if done, result, err = r.ensureVolumeServersRolledOut(seaweedCR, pool); done {
+		return
+	}
+
+	return
+}
+
+func (r *SeaweedReconciler) ensureVolumeServerStatefulSet(seaweedCR *seaweedv1.Seaweed, pool volumePool) (bool, ctrl.Result, error) {
 	log := r.Log.WithValues("sw-volume-statefulset", seaweedCR.Name)
 
-	volumeServerStatefulSet := r.createVolumeServerStatefulSet(seaweedCR)
+	volumeServerStatefulSet := r.createVolumeServerStatefulSet(seaweedCR, pool)
+	replicas, err := r.volumeServerReplicas(seaweedCR, pool)
+	if err != nil {
+		return ReconcileResult(err)
+	}
+	image, err := r.componentImage(seaweedCR, upgradeComponentName(pool))
+	if err != nil {
+		return ReconcileResult(err)
+	}
+	volumeServerStatefulSet.Spec.Template.Spec.Containers[0].Image = image
+	hash, err := r.configHash(seaweedCR, &volumeServerStatefulSet.Spec.Template.Spec)
+	if err != nil {
+		return ReconcileResult(err)
+	}
+	setConfigHash(&volumeServerStatefulSet.Spec.Template, hash)
+	volumeServerStatefulSet.Spec.Replicas = &replicas
 	if err := controllerutil.SetControllerReference(seaweedCR, volumeServerStatefulSet, r.Scheme); err != nil {
 		return ReconcileResult(err)
 	}
-	_, err := r.CreateOrUpdate(volumeServerStatefulSet, func(existing, desired runtime.Object) error {
+	_, err = r.CreateOrUpdate(volumeServerStatefulSet, func(existing, desired runtime.Object) error {
 		existingStatefulSet := existing.(*appsv1.StatefulSet)
 		desiredStatefulSet := desired.(*appsv1.StatefulSet)
 
+		if added, removed := volumeDiskChanges(existingStatefulSet, pool.disks()); len(added) > 0 || len(removed) > 0 {
+			// the pods keep the disks of the volumeClaimTemplates until ensureVolumeDisksMigrated recreates the StatefulSet
+			migrating := pool
+			migrating.layout = volumeDiskLayout(existingStatefulSet, pool.disks())
+			desiredStatefulSet = r.createVolumeServerStatefulSet(seaweedCR, migrating)
+			desiredStatefulSet.Spec.Replicas = &replicas
+			desiredStatefulSet.Spec.Template.Spec.Containers[0].Image = image
+		}
+
+		existingStatefulSet.Spec.UpdateStrategy = desiredStatefulSet.Spec.UpdateStrategy
 		existingStatefulSet.Spec.Replicas = desiredStatefulSet.Spec.Replicas
 		existingStatefulSet.Spec.Template.Spec = desiredStatefulSet.Spec.Template.Spec
+		setConfigHash(&existingStatefulSet.Spec.Template, hash)
 		return nil
 	})
 
@@ -52,11 +130,11 @@ func (r *SeaweedReconciler) ensureVolumeServerStatefulSet(seaweedCR *seaweedv1.S
 	return ReconcileResult(err)
 }
 
-func (r *SeaweedReconciler) ensureVolumeServerPeerService(seaweedCR *seaweedv1.Seaweed) (bool, ctrl.Result, error) {
+func (r *SeaweedReconciler) ensureVolumeServerPeerService(seaweedCR *seaweedv1.Seaweed, pool volumePool) (bool, ctrl.Result, error) {
 
 	log := r.Log.WithValues("sw-volume-peer-service", seaweedCR.Name)
 
-	volumeServerPeerService := r.createVolumeServerPeerService(seaweedCR)
+	volumeServerPeerService := r.createVolumeServerPeerService(seaweedCR, pool)
 	if err := controllerutil.SetControllerReference(seaweedCR, volumeServerPeerService, r.Scheme); err != nil {
 		return ReconcileResult(err)
 	}
@@ -66,10 +144,10 @@ func (r *SeaweedReconciler) ensureVolumeServerPeerService(seaweedCR *seaweedv1.S
 	return ReconcileResult(err)
 }
 
-func (r *SeaweedReconciler) ensureVolumeServerServices(seaweedCR *seaweedv1.Seaweed) (bool, ctrl.Result, error) {
+func (r *SeaweedReconciler) ensureVolumeServerServices(seaweedCR *seaweedv1.Seaweed, pool volumePool) (bool, ctrl.Result, error) {
 
-	for i := 0; i < int(seaweedCR.Spec.Volume.Replicas); i++ {
-		done, result, err := r.ensureVolumeServerService(seaweedCR, i)
+	for i := 0; i < int(pool.spec.Replicas); i++ {
+		done, result, err := r.ensureVolumeServerService(seaweedCR, pool, i)
 		if done {
 			return done, result, err
 		}
@@ -78,11 +156,11 @@ func (r *SeaweedReconciler) ensureVolumeServerServices(seaweedCR *seaweedv1.Seaw
 	return ReconcileResult(nil)
 }
 
-func (r *SeaweedReconciler) ensureVolumeServerService(seaweedCR *seaweedv1.Seaweed, i int) (bool, ctrl.Result, error) {
+func (r *SeaweedReconciler) ensureVolumeServerService(seaweedCR *seaweedv1.Seaweed, pool volumePool, i int) (bool, ctrl.Result, error) {
 
 	log := r.Log.WithValues("sw-volume-service", seaweedCR.Name, "index", i)
 
-	volumeServerService := r.createVolumeServerService(seaweedCR, i)
+	volumeServerService := r.createVolumeServerService(seaweedCR, pool, i)
 	if err := controllerutil.SetControllerReference(seaweedCR, volumeServerService, r.Scheme); err != nil {
 		return ReconcileResult(err)
 	}
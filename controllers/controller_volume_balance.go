@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+const (
+	volumeBalanceTaskName = "volume-balance"
+
+	defaultVolumeBalanceCooldown = 600 * time.Second
+)
+
+// ensureVolumeBalanced runs a locked volume.balance once new volume servers are ready,
+// so that existing data spreads over them
+func (r *SeaweedReconciler) ensureVolumeBalanced(m *seaweedv1.Seaweed) (bool, ctrl.Result, error) {
+	log := r.Log.WithValues("sw-volume-balance", m.Name)
+
+	// volume.balance moves volumes between all volume servers, so the replicas of all pools are counted
+	replicas, readyReplicas := int32(0), int32(0)
+	for _, pool := range volumePools(m) {
+		statefulSet := &appsv1.StatefulSet{}
+		err := r.Get(context.Background(), types.NamespacedName{Namespace: m.Namespace, Name: pool.statefulSetName(m)}, statefulSet)
+		if errors.IsNotFound(err) {
+			return ReconcileResult(nil)
+		}
+		if err != nil {
+			return ReconcileResult(err)
+		}
+		if statefulSet.Spec.Replicas != nil {
+			replicas += *statefulSet.Spec.Replicas
+		}
+		readyReplicas += statefulSet.Status.ReadyReplicas
+	}
+
+	status := m.Status.VolumeBalance
+	if status == nil {
+		status = &seaweedv1.VolumeBalanceStatus{BalancedReplicas: replicas}
+		m.Status.VolumeBalance = status
+	}
+
+	if task := r.currentAdminTask(m); task != nil && task.name == volumeBalanceTaskName {
+		finished, taskErr := task.finished()
+		if !finished {
+			status.Running = true
+			return ReconcileResult(nil)
+		}
+		r.clearAdminTask(m)
+
+		finishTime := metav1.NewTime(task.finishTime)
+		status.Running = false
+		status.LastBalanceTime = &finishTime
+		if taskErr != nil {
+			log.Info("volume balance failed", "error", taskErr.Error())
+			status.LastResult = "Failed"
+			status.Message = taskErr.Error()
+			r.Recorder.Eventf(m, corev1.EventTypeWarning, "VolumeBalanceFailed", "volume.balance failed: %v", taskErr)
+		} else {
+			log.Info("volume balance succeeded", "replicas", readyReplicas)
+			status.BalancedReplicas = readyReplicas
+			status.LastResult = "Succeeded"
+			status.Message = fmt.Sprintf("balanced volumes over %d volume servers in %v",
+				status.BalancedReplicas, task.finishTime.Sub(task.startTime).Round(time.Second))
+			r.Recorder.Event(m, corev1.EventTypeNormal, "VolumeBalanceSucceeded", status.Message)
+		}
+	} else if status.Running {
+		// the operator restarted during the balance, a scale-out which is still unbalanced starts another one
+		status.Running = false
+		status.LastResult = "Failed"
+		status.Message = "the balance was interrupted"
+	}
+
+	// a scale-in lowers the baseline, so that scaling out again triggers a balance
+	if replicas < status.BalancedReplicas {
+		status.BalancedReplicas = replicas
+	}
+
+	balance := m.Spec.Volume.Balance
+	if balance == nil || !balance.Enabled {
+		return ReconcileResult(nil)
+	}
+
+	if replicas <= status.BalancedReplicas || readyReplicas < replicas || m.Status.VolumeScaleIn != nil {
+		return ReconcileResult(nil)
+	}
+
+	cooldown := defaultVolumeBalanceCooldown
+	if balance.CooldownSeconds != nil {
+		cooldown = time.Duration(*balance.CooldownSeconds) * time.Second
+	}
+	if status.LastBalanceTime != nil && time.Since(status.LastBalanceTime.Time) < cooldown {
+		status.Message = fmt.Sprintf("waiting for the cooldown until %s", status.LastBalanceTime.Add(cooldown).Format(time.RFC3339))
+		return ReconcileResult(nil)
+	}
+
+	var cmds []string
+	for _, collection := range balance.Collections {
+		cmds = append(cmds, fmt.Sprintf("volume.balance -force -collection %s", collection))
+	}
+	if len(cmds) == 0 {
+		cmds = append(cmds, "volume.balance -force")
+	}
+
+	sa := r.seaweedAdmin(m)
+	if r.startAdminTask(m, volumeBalanceTaskName, func(ctx context.Context) error {
+		return sa.WithLock(ctx, func(ctx context.Context) error {
+			for _, cmd := range cmds {
+				if err := sa.ProcessCommand(ctx, cmd, ioutil.Discard); err != nil {
+					return fmt.Errorf("%s: %v", cmd, err)
+				}
+			}
+			return nil
+		})
+	}) {
+		status.Running = true
+		status.Message = fmt.Sprintf("balancing volumes from %d to %d volume servers", status.BalancedReplicas, replicas)
+		r.Recorder.Event(m, corev1.EventTypeNormal, "VolumeBalanceStarted", status.Message)
+	}
+
+	return ReconcileResult(nil)
+}
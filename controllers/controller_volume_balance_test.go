@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/controllers/swadmin"
+)
+
+func TestEnsureVolumeBalanced(t *testing.T) {
+	m := testSeaweed(1, 2)
+	m.Spec.Volume.Balance = &seaweedv1.VolumeBalanceSpec{Enabled: true, Collections: []string{"images", "logs"}}
+	replicas := int32(2)
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "sw-volume", Namespace: "default"},
+		Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+		Status:     appsv1.StatefulSetStatus{ReadyReplicas: 2},
+	}
+	r := newFakeReconciler(m, statefulSet)
+	admin := swadmin.NewFakeAdmin("")
+	useFakeAdmin(r, admin)
+	scale := func(replicas, ready int32) {
+		t.Helper()
+		statefulSet := &appsv1.StatefulSet{}
+		if err := r.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "sw-volume"}, statefulSet); err != nil {
+			t.Fatal(err)
+		}
+		statefulSet.Spec.Replicas = &replicas
+		statefulSet.Status.ReadyReplicas = ready
+		if err := r.Update(context.Background(), statefulSet); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ensure := func() *seaweedv1.VolumeBalanceStatus {
+		t.Helper()
+		if done, _, err := r.ensureVolumeBalanced(m); done || err != nil {
+			t.Fatalf("done = %v, err = %v", done, err)
+		}
+		return m.Status.VolumeBalance
+	}
+
+	// the first reconcile takes the running volume servers as balanced
+	if status := ensure(); status.BalancedReplicas != 2 || status.Running || r.currentAdminTask(m) != nil {
+		t.Fatalf("status of a new cluster = %+v", status)
+	}
+
+	// the scale-out is balanced once all new volume servers are ready
+	scale(3, 2)
+	if status := ensure(); status.Running || r.currentAdminTask(m) != nil {
+		t.Fatalf("balance started before the new volume server is ready: %+v", status)
+	}
+	scale(3, 3)
+	if status := ensure(); !status.Running || status.Message != "balancing volumes from 2 to 3 volume servers" {
+		t.Fatalf("status after the scale-out = %+v", status)
+	}
+	task := r.currentAdminTask(m)
+	if task == nil || task.name != volumeBalanceTaskName {
+		t.Fatalf("task = %v", task)
+	}
+	waitForAdminTask(t, task)
+	status := ensure()
+	if status.Running || status.LastResult != "Succeeded" || status.BalancedReplicas != 3 || status.LastBalanceTime == nil {
+		t.Errorf("status after the balance = %+v", status)
+	}
+	if r.currentAdminTask(m) != nil {
+		t.Errorf("finished balance not cleared")
+	}
+	expected := []string{"lock", "volume.balance -force -collection images", "volume.balance -force -collection logs", "unlock"}
+	if commands := admin.Commands(); !reflect.DeepEqual(commands, expected) {
+		t.Errorf("commands = %q, expected %q", commands, expected)
+	}
+
+	// another scale-out waits for the cooldown
+	scale(4, 4)
+	if status := ensure(); status.Running || !strings.HasPrefix(status.Message, "waiting for the cooldown until") {
+		t.Errorf("status within the cooldown = %+v", status)
+	}
+	lastBalance := metav1.NewTime(time.Now().Add(-defaultVolumeBalanceCooldown))
+	m.Status.VolumeBalance.LastBalanceTime = &lastBalance
+	if status := ensure(); !status.Running || status.Message != "balancing volumes from 3 to 4 volume servers" {
+		t.Errorf("status after the cooldown = %+v", status)
+	}
+	waitForAdminTask(t, r.currentAdminTask(m))
+	ensure()
+
+	// a scale-in lowers the baseline without a balance
+	scale(2, 2)
+	if status := ensure(); status.BalancedReplicas != 2 || status.Running || r.currentAdminTask(m) != nil {
+		t.Errorf("status after the scale-in = %+v", status)
+	}
+
+	// the operator restarted during a balance
+	m.Status.VolumeBalance.Running = true
+	if status := ensure(); status.Running || status.LastResult != "Failed" || status.Message != "the balance was interrupted" {
+		t.Errorf("status after a restart = %+v", status)
+	}
+}
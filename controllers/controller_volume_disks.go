@@ -0,0 +1,406 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/controllers/swadmin"
+)
+
+const (
+	volumeDiskEvacuateTaskName = "volume-disk-evacuate"
+
+	// retiringDiskTypePrefix tags the directories of removed disks. No volume is created on a disk type
+	// nobody asks for, so the directories only shrink while their volumes are moved away.
+	retiringDiskTypePrefix = "retiring-"
+)
+
+// ensureVolumeDisksMigrated adds or removes disks of the volume servers of a pool.
+// The volumeClaimTemplates of a StatefulSet are immutable, so the StatefulSet keeps the disks it was created with,
+// until the PVCs of added disks exist and the volumes of removed disks are moved to other volume servers.
+// Then the StatefulSet is recreated without its pods and rolls them to the new disks.
+func (r *SeaweedReconciler) ensureVolumeDisksMigrated(m *seaweedv1.Seaweed, pool volumePool) (bool, ctrl.Result, error) {
+	log := r.Log.WithValues("sw-volume-disks", m.Name, "pool", pool.name)
+
+	statefulSet := &appsv1.StatefulSet{}
+	err := r.Get(context.Background(), types.NamespacedName{Namespace: m.Namespace, Name: pool.statefulSetName(m)}, statefulSet)
+	if errors.IsNotFound(err) {
+		removeVolumeDiskMigration(m, pool.name)
+		return ReconcileResult(nil)
+	}
+	if err != nil {
+		return ReconcileResult(err)
+	}
+	if statefulSet.DeletionTimestamp != nil {
+		return true, ctrl.Result{RequeueAfter: statefulSetRecreateDelay}, nil
+	}
+
+	disks := pool.disks()
+	added, removed := volumeDiskChanges(statefulSet, disks)
+	if len(added) == 0 && len(removed) == 0 {
+		removeVolumeDiskMigration(m, pool.name)
+		return ReconcileResult(nil)
+	}
+
+	migration := findVolumeDiskMigration(m, pool.name)
+	if migration == nil || !equalStrings(migration.AddedDisks, added) || !equalStrings(migration.RemovedDisks, removed) {
+		removeVolumeDiskMigration(m, pool.name)
+		now := metav1.Now()
+		m.Status.VolumeDiskMigrations = append(m.Status.VolumeDiskMigrations, seaweedv1.VolumeDiskMigrationStatus{
+			Pool:         pool.name,
+			AddedDisks:   added,
+			RemovedDisks: removed,
+			StartTime:    &now,
+			Evacuated:    len(removed) == 0,
+		})
+		migration = &m.Status.VolumeDiskMigrations[len(m.Status.VolumeDiskMigrations)-1]
+		log.Info("start volume disk migration", "added", added, "removed", removed)
+		r.Recorder.Eventf(m, corev1.EventTypeNormal, "VolumeDiskMigrationStarted",
+			"Changing the disks of %s, adding %v and removing %v", pool.description(), added, removed)
+	}
+
+	if !migration.Evacuated {
+		// the volume servers first restart with the removed disks tagged as retiring
+		layout := volumeDiskLayout(statefulSet, disks)
+		script := buildVolumeServerStartupScript(m, pool, layout)
+		if !volumeServerRunsScript(statefulSet, script) || !statefulSetRolledOut(statefulSet) {
+			migration.Message = fmt.Sprintf("restarting the volume servers with %s tagged as retiring", strings.Join(removed, ","))
+			return ReconcileResult(nil)
+		}
+		if !r.evacuateRetiringDisks(m, pool, statefulSet, migration) {
+			return ReconcileResult(nil)
+		}
+	}
+
+	replicas := int32(0)
+	if statefulSet.Spec.Replicas != nil {
+		replicas = *statefulSet.Spec.Replicas
+	}
+	desired := r.createVolumeServerStatefulSet(m, pool)
+	for _, template := range desired.Spec.VolumeClaimTemplates {
+		if !containsString(added, template.Name) {
+			continue
+		}
+		for ordinal := int32(0); ordinal < replicas; ordinal++ {
+			if err := r.createVolumeDiskPersistentVolumeClaim(m, statefulSet, template, ordinal); err != nil {
+				migration.Message = fmt.Sprintf("creating the PVC of disk %s: %v", template.Name, err)
+				return ReconcileResult(err)
+			}
+		}
+	}
+
+	log.Info("recreate the StatefulSet with the new disks", "statefulSet", statefulSet.Name)
+	err = r.Delete(context.Background(), statefulSet, client.PropagationPolicy(metav1.DeletePropagationOrphan))
+	if err != nil && !errors.IsNotFound(err) {
+		return ReconcileResult(err)
+	}
+
+	// the PVCs are only deleted once the rolled pods released them
+	if m.Spec.EnablePVReclaim != nil && *m.Spec.EnablePVReclaim {
+		for _, name := range removed {
+			for ordinal := int32(0); ordinal < replicas; ordinal++ {
+				pvc := &corev1.PersistentVolumeClaim{}
+				pvc.Namespace = m.Namespace
+				pvc.Name = fmt.Sprintf("%s-%s-%d", name, statefulSet.Name, ordinal)
+				if err := r.Delete(context.Background(), pvc); err != nil && !errors.IsNotFound(err) {
+					return ReconcileResult(err)
+				}
+			}
+		}
+	}
+
+	r.Recorder.Eventf(m, corev1.EventTypeNormal, "VolumeDiskMigrationCompleted",
+		"Recreating StatefulSet %s with the new disks, its pods roll to added disks %v and drop removed disks %v", statefulSet.Name, added, removed)
+	removeVolumeDiskMigration(m, pool.name)
+	return true, ctrl.Result{RequeueAfter: statefulSetRecreateDelay}, nil
+}
+
+// evacuateRetiringDisks moves the volumes off the retiring disks of the pool, and reports whether they are empty
+func (r *SeaweedReconciler) evacuateRetiringDisks(m *seaweedv1.Seaweed, pool volumePool, statefulSet *appsv1.StatefulSet, migration *seaweedv1.VolumeDiskMigrationStatus) bool {
+	if task := r.currentAdminTask(m); task != nil {
+		finished, taskErr := task.finished()
+		switch {
+		case !finished || task.name != volumeDiskEvacuateTaskName:
+			migration.Message = fmt.Sprintf("waiting for admin task %s started at %s", task.name, task.startTime.Format(time.RFC3339))
+			return false
+		case taskErr != nil:
+			migration.Message = fmt.Sprintf("evacuating the removed disks failed: %v", taskErr)
+			if time.Since(task.finishTime) < volumeDrainRetryDelay {
+				return false
+			}
+			r.Recorder.Eventf(m, corev1.EventTypeWarning, "VolumeDiskEvacuationFailed", "Evacuating the removed disks of %s failed, retrying: %v", pool.description(), taskErr)
+			r.clearAdminTask(m)
+		default:
+			r.clearAdminTask(m)
+			migration.Evacuated = true
+			migration.Message = "removed disks evacuated"
+			return true
+		}
+	}
+
+	var servers []string
+	if statefulSet.Spec.Replicas != nil {
+		for ordinal := int32(0); ordinal < *statefulSet.Spec.Replicas; ordinal++ {
+			servers = append(servers, pool.serverAddress(m, ordinal))
+		}
+	}
+	sa := r.seaweedAdmin(m)
+	if r.startAdminTask(m, volumeDiskEvacuateTaskName, func(ctx context.Context) error {
+		return sa.WithLock(ctx, func(ctx context.Context) error {
+			return evacuateRetiringDisks(ctx, sa, servers)
+		})
+	}) {
+		migration.Message = fmt.Sprintf("moving the volumes of %s to other volume servers", strings.Join(migration.RemovedDisks, ","))
+	}
+	return false
+}
+
+// evacuateRetiringDisks moves every volume on a retiring disk of the servers to another volume server,
+// onto a disk of the type the volume had before
+func evacuateRetiringDisks(ctx context.Context, sa swadmin.Admin, servers []string) error {
+	topology, err := sa.VolumeList(ctx)
+	if err != nil {
+		return err
+	}
+
+	free := make(map[string]int64)
+	for _, node := range topology.Nodes() {
+		for _, disk := range node.Disks {
+			free[node.ID+"/"+disk.Type] = disk.FreeVolumeCount
+		}
+	}
+
+	for _, server := range servers {
+		node := topology.Node(server)
+		if node == nil {
+			continue
+		}
+		for _, disk := range node.Disks {
+			if !strings.HasPrefix(disk.Type, retiringDiskTypePrefix) {
+				continue
+			}
+			if len(disk.ECShards) > 0 {
+				return fmt.Errorf("%s has EC shards on a removed disk, which must be moved with ec.balance first", server)
+			}
+			diskType := strings.TrimPrefix(disk.Type, retiringDiskTypePrefix)
+			for _, volume := range disk.Volumes {
+				target := volumeMoveTarget(topology, free, volume.ID, diskType)
+				if target == "" {
+					return fmt.Errorf("no volume server has a free %s slot for volume %d", diskType, volume.ID)
+				}
+				cmd := fmt.Sprintf("volume.move -source %s -target %s -volumeId %d -disk %s", server, target, volume.ID, diskType)
+				if err := sa.ProcessCommand(ctx, cmd, ioutil.Discard); err != nil {
+					return fmt.Errorf("%s: %v", cmd, err)
+				}
+				free[target+"/"+diskType]--
+			}
+		}
+	}
+
+	topology, err = sa.VolumeList(ctx)
+	if err != nil {
+		return err
+	}
+	for _, server := range servers {
+		if node := topology.Node(server); node != nil {
+			for _, disk := range node.Disks {
+				if strings.HasPrefix(disk.Type, retiringDiskTypePrefix) && (len(disk.Volumes) > 0 || len(disk.ECShards) > 0) {
+					return fmt.Errorf("%s still has volumes on a removed disk", server)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// volumeMoveTarget picks the volume server with the most free slots of the disk type, which has no replica of the volume yet
+func volumeMoveTarget(topology *swadmin.Topology, free map[string]int64, vid uint32, diskType string) string {
+	locations := topology.VolumeLocations(vid)
+	target, most := "", int64(0)
+	for _, node := range topology.Nodes() {
+		if containsString(locations, node.ID) {
+			continue
+		}
+		if slots := free[node.ID+"/"+diskType]; slots > most {
+			target, most = node.ID, slots
+		}
+	}
+	return target
+}
+
+// createVolumeDiskPersistentVolumeClaim creates the PVC the StatefulSet would create for the template and ordinal
+func (r *SeaweedReconciler) createVolumeDiskPersistentVolumeClaim(m *seaweedv1.Seaweed, statefulSet *appsv1.StatefulSet, template corev1.PersistentVolumeClaim, ordinal int32) error {
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s-%d", template.Name, statefulSet.Name, ordinal),
+			Namespace: m.Namespace,
+			Labels:    statefulSet.Spec.Selector.MatchLabels,
+		},
+		Spec: template.Spec,
+	}
+	err := r.Create(context.Background(), pvc)
+	if errors.IsAlreadyExists(err) {
+		return nil
+	}
+	if err == nil {
+		r.Log.Info("created volume disk pvc", "pvc", pvc.Name)
+	}
+	return err
+}
+
+// volumeDiskChanges compares the disks with the volumeClaimTemplates of the StatefulSet
+func volumeDiskChanges(statefulSet *appsv1.StatefulSet, disks []volumeDisk) (added, removed []string) {
+	existing := make(map[string]bool)
+	for _, template := range statefulSet.Spec.VolumeClaimTemplates {
+		existing[template.Name] = true
+	}
+	desired := make(map[string]bool)
+	for _, disk := range disks {
+		desired[disk.name] = true
+		if !existing[disk.name] {
+			added = append(added, disk.name)
+		}
+	}
+	for _, template := range statefulSet.Spec.VolumeClaimTemplates {
+		if !desired[template.Name] {
+			removed = append(removed, template.Name)
+		}
+	}
+	return added, removed
+}
+
+// volumeDiskLayout lists the disks the pods of the StatefulSet can mount while its disks change:
+// the disks of its volumeClaimTemplates, with the ones missing from the spec tagged as retiring
+func volumeDiskLayout(statefulSet *appsv1.StatefulSet, disks []volumeDisk) []volumeDisk {
+	mountPaths := make(map[string]string)
+	if containers := statefulSet.Spec.Template.Spec.Containers; len(containers) > 0 {
+		for _, mount := range containers[0].VolumeMounts {
+			mountPaths[mount.Name] = strings.TrimSuffix(mount.MountPath, "/")
+		}
+	}
+	diskTypes := volumeServerDiskTypes(statefulSet)
+
+	var layout []volumeDisk
+	for _, template := range statefulSet.Spec.VolumeClaimTemplates {
+		found := false
+		for _, disk := range disks {
+			if disk.name == template.Name {
+				layout = append(layout, disk)
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+
+		mountPath := mountPaths[template.Name]
+		diskType := diskTypes[mountPath]
+		if diskType == "" {
+			diskType = "hdd"
+		}
+		if !strings.HasPrefix(diskType, retiringDiskTypePrefix) {
+			diskType = retiringDiskTypePrefix + diskType
+		}
+		layout = append(layout, volumeDisk{
+			name:             template.Name,
+			size:             template.Spec.Resources.Requests[corev1.ResourceStorage],
+			storageClassName: template.Spec.StorageClassName,
+			mountPath:        mountPath,
+			diskType:         diskType,
+		})
+	}
+	return layout
+}
+
+// volumeServerDiskTypes reads the disk type of every data directory from the -dir and -disk arguments of the volume server
+func volumeServerDiskTypes(statefulSet *appsv1.StatefulSet) map[string]string {
+	containers := statefulSet.Spec.Template.Spec.Containers
+	if len(containers) == 0 || len(containers[0].Command) == 0 {
+		return nil
+	}
+	var dirs, diskTypes []string
+	for _, field := range strings.Fields(containers[0].Command[len(containers[0].Command)-1]) {
+		switch {
+		case strings.HasPrefix(field, "-dir="):
+			dirs = strings.Split(strings.TrimPrefix(field, "-dir="), ",")
+		case strings.HasPrefix(field, "-disk="):
+			diskTypes = strings.Split(strings.TrimPrefix(field, "-disk="), ",")
+		}
+	}
+
+	types := make(map[string]string)
+	for i, dir := range dirs {
+		switch {
+		case len(diskTypes) == 1:
+			types[dir] = diskTypes[0]
+		case i < len(diskTypes):
+			types[dir] = diskTypes[i]
+		}
+	}
+	return types
+}
+
+// volumeServerRunsScript reports whether the pod template of the StatefulSet starts the volume server with the script
+func volumeServerRunsScript(statefulSet *appsv1.StatefulSet, script string) bool {
+	containers := statefulSet.Spec.Template.Spec.Containers
+	return len(containers) > 0 && len(containers[0].Command) > 0 && containers[0].Command[len(containers[0].Command)-1] == script
+}
+
+// statefulSetRolledOut reports whether every pod of the StatefulSet runs its latest template and is ready
+func statefulSetRolledOut(statefulSet *appsv1.StatefulSet) bool {
+	replicas := int32(1)
+	if statefulSet.Spec.Replicas != nil {
+		replicas = *statefulSet.Spec.Replicas
+	}
+	status := statefulSet.Status
+	// the current revision only follows the update revision with the RollingUpdate strategy
+	onDelete := statefulSet.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType
+	return status.ObservedGeneration >= statefulSet.Generation &&
+		(onDelete || status.CurrentRevision == status.UpdateRevision) &&
+		status.UpdatedReplicas == replicas &&
+		status.ReadyReplicas == replicas
+}
+
+func findVolumeDiskMigration(m *seaweedv1.Seaweed, pool string) *seaweedv1.VolumeDiskMigrationStatus {
+	for i := range m.Status.VolumeDiskMigrations {
+		if m.Status.VolumeDiskMigrations[i].Pool == pool {
+			return &m.Status.VolumeDiskMigrations[i]
+		}
+	}
+	return nil
+}
+
+func removeVolumeDiskMigration(m *seaweedv1.Seaweed, pool string) {
+	var migrations []seaweedv1.VolumeDiskMigrationStatus
+	for _, migration := range m.Status.VolumeDiskMigrations {
+		if migration.Pool != pool {
+			migrations = append(migrations, migration)
+		}
+	}
+	m.Status.VolumeDiskMigrations = migrations
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
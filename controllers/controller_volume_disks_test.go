@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+func TestVolumeDiskLayout(t *testing.T) {
+	m := testSeaweed(1, 2)
+	m.Spec.Volume.Disks = []seaweedv1.VolumeDiskSpec{
+		{Size: resource.MustParse("1Ti")},
+		{Name: "fast", Size: resource.MustParse("100Gi"), MountPath: "/ssd", DiskType: "ssd"},
+	}
+	r := &SeaweedReconciler{}
+	statefulSet := r.createVolumeServerStatefulSet(m, defaultVolumePool(m))
+
+	m.Spec.Volume.Disks = []seaweedv1.VolumeDiskSpec{
+		{Size: resource.MustParse("1Ti")},
+		{Name: "big", Size: resource.MustParse("4Ti"), MountPath: "/big"},
+	}
+	disks := defaultVolumePool(m).disks()
+	added, removed := volumeDiskChanges(statefulSet, disks)
+	if !reflect.DeepEqual(added, []string{"big"}) || !reflect.DeepEqual(removed, []string{"fast"}) {
+		t.Fatalf("added %v and removed %v, expected [big] and [fast]", added, removed)
+	}
+
+	pool := defaultVolumePool(m)
+	pool.layout = volumeDiskLayout(statefulSet, disks)
+	if len(pool.layout) != 2 || pool.layout[1].name != "fast" || pool.layout[1].mountPath != "/ssd" || pool.layout[1].diskType != "retiring-ssd" {
+		t.Fatalf("layout = %+v", pool.layout)
+	}
+	migrating := r.createVolumeServerStatefulSet(m, pool)
+	script := migrating.Spec.Template.Spec.Containers[0].Command[2]
+	if !strings.Contains(script, " -dir=/data0,/ssd") || !strings.Contains(script, " -disk=,retiring-ssd") {
+		t.Errorf("migrating script = %q", script)
+	}
+
+	// the tag is kept once the pods run with it
+	if layout := volumeDiskLayout(migrating, disks); layout[1].diskType != "retiring-ssd" {
+		t.Errorf("disk type of the rolled pods = %q", layout[1].diskType)
+	}
+}
+
+func TestVolumeMoveTarget(t *testing.T) {
+	topology := testTopology(map[string][]uint32{
+		"sw-volume-0.sw-volume-peer.default:8444": {1, 2},
+		"sw-volume-1.sw-volume-peer.default:8444": {2},
+	})
+	free := map[string]int64{
+		"sw-volume-0.sw-volume-peer.default:8444/hdd": 3,
+		"sw-volume-1.sw-volume-peer.default:8444/hdd": 1,
+	}
+	if target := volumeMoveTarget(topology, free, 1, "hdd"); target != "sw-volume-1.sw-volume-peer.default:8444" {
+		t.Errorf("target of volume 1 = %q", target)
+	}
+	if target := volumeMoveTarget(topology, free, 2, "hdd"); target != "" {
+		t.Errorf("volume 2 is on every server, got target %q", target)
+	}
+	if target := volumeMoveTarget(topology, free, 3, "ssd"); target != "" {
+		t.Errorf("no server has ssd slots, got target %q", target)
+	}
+}
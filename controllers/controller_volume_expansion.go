@@ -0,0 +1,231 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+// statefulSetRecreateDelay is the pause while an orphaning StatefulSet delete finishes
+const statefulSetRecreateDelay = 2 * time.Second
+
+const (
+	// defaultStorageClassAnnotation marks the StorageClass of the PVCs which do not name one
+	defaultStorageClassAnnotation     = "storageclass.kubernetes.io/is-default-class"
+	betaDefaultStorageClassAnnotation = "storageclass.beta.kubernetes.io/is-default-class"
+)
+
+// ensureVolumeStorageExpanded grows the PVCs of a volume pool when the requested disk sizes grow.
+// The volumeClaimTemplates of a StatefulSet are immutable, so once every PVC is resized, the StatefulSet
+// is deleted without its pods and created again with the new sizes.
+func (r *SeaweedReconciler) ensureVolumeStorageExpanded(m *seaweedv1.Seaweed, pool volumePool) (bool, ctrl.Result, error) {
+	log := r.Log.WithValues("sw-volume-expansion", m.Name, "pool", pool.name)
+
+	statefulSet := &appsv1.StatefulSet{}
+	err := r.Get(context.Background(), types.NamespacedName{Namespace: m.Namespace, Name: pool.statefulSetName(m)}, statefulSet)
+	if errors.IsNotFound(err) {
+		return ReconcileResult(nil)
+	}
+	if err != nil {
+		return ReconcileResult(err)
+	}
+	if statefulSet.DeletionTimestamp != nil {
+		// the StatefulSet is created again once the orphaning delete finished
+		return true, ctrl.Result{RequeueAfter: statefulSetRecreateDelay}, nil
+	}
+
+	if added, removed := volumeDiskChanges(statefulSet, pool.disks()); len(added) > 0 || len(removed) > 0 {
+		// ensureVolumeDisksMigrated recreates the StatefulSet with the new disks first
+		return ReconcileResult(nil)
+	}
+
+	templateSizes := make(map[string]resource.Quantity)
+	for _, template := range statefulSet.Spec.VolumeClaimTemplates {
+		templateSizes[template.Name] = template.Spec.Resources.Requests[corev1.ResourceStorage]
+	}
+	var grown []volumeDisk
+	for _, disk := range pool.disks() {
+		if size, found := templateSizes[disk.name]; found && disk.size.Cmp(size) > 0 {
+			grown = append(grown, disk)
+		}
+	}
+	if len(grown) == 0 {
+		removeVolumeExpansion(m, pool.name)
+		return ReconcileResult(nil)
+	}
+
+	expansion := findVolumeExpansion(m, pool.name)
+	if expansion == nil {
+		now := metav1.Now()
+		m.Status.VolumeExpansions = append(m.Status.VolumeExpansions, seaweedv1.VolumeExpansionStatus{Pool: pool.name, StartTime: &now})
+		expansion = &m.Status.VolumeExpansions[len(m.Status.VolumeExpansions)-1]
+		log.Info("start volume expansion")
+		r.Recorder.Eventf(m, corev1.EventTypeNormal, "VolumeExpansionStarted", "Expanding the PVCs of %s", pool.description())
+	}
+	fail := func(format string, args ...interface{}) (bool, ctrl.Result, error) {
+		message := fmt.Sprintf(format, args...)
+		if !expansion.Failed || expansion.Message != message {
+			r.Recorder.Event(m, corev1.EventTypeWarning, "VolumeExpansionFailed", message)
+		}
+		expansion.Failed = true
+		expansion.Message = message
+		return ReconcileResult(nil)
+	}
+
+	replicas := int32(0)
+	if statefulSet.Spec.Replicas != nil {
+		replicas = *statefulSet.Spec.Replicas
+	}
+	expandable := make(map[string]bool)
+	// defaultClass is looked up for the first PVC without a StorageClass
+	var defaultClass *string
+	var pending []string
+	for _, disk := range grown {
+		for ordinal := int32(0); ordinal < replicas; ordinal++ {
+			pvc := &corev1.PersistentVolumeClaim{}
+			name := fmt.Sprintf("%s-%s-%d", disk.name, statefulSet.Name, ordinal)
+			err := r.Get(context.Background(), types.NamespacedName{Namespace: m.Namespace, Name: name}, pvc)
+			if errors.IsNotFound(err) {
+				// the pod has not been created yet, its PVC gets the new size from the recreated StatefulSet
+				continue
+			}
+			if err != nil {
+				return ReconcileResult(err)
+			}
+
+			className := ""
+			if pvc.Spec.StorageClassName != nil {
+				className = *pvc.Spec.StorageClassName
+			} else {
+				if defaultClass == nil {
+					name, err := r.defaultStorageClass()
+					if err != nil {
+						return ReconcileResult(err)
+					}
+					defaultClass = &name
+				}
+				className = *defaultClass
+			}
+			allowed, found := expandable[className]
+			if !found {
+				if allowed, err = r.storageClassAllowsExpansion(className); err != nil {
+					return ReconcileResult(err)
+				}
+				expandable[className] = allowed
+			}
+			if !allowed && className == "" {
+				return fail("PVC %s has no StorageClass and there is no default StorageClass, it can not be expanded", name)
+			}
+			if !allowed {
+				return fail("StorageClass %q of PVC %s does not allow volume expansion", className, name)
+			}
+
+			if requested := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; requested.Cmp(disk.size) < 0 {
+				log.Info("expand pvc", "pvc", name, "from", requested.String(), "to", disk.size.String())
+				patch := client.MergeFrom(pvc.DeepCopy())
+				if pvc.Spec.Resources.Requests == nil {
+					pvc.Spec.Resources.Requests = corev1.ResourceList{}
+				}
+				pvc.Spec.Resources.Requests[corev1.ResourceStorage] = disk.size
+				if err := r.Patch(context.Background(), pvc, patch); err != nil {
+					return fail("expanding PVC %s to %s: %v", name, disk.size.String(), err)
+				}
+				pending = append(pending, name)
+				continue
+			}
+
+			if !persistentVolumeClaimResized(pvc, disk.size) {
+				pending = append(pending, name)
+			}
+		}
+	}
+
+	expansion.Failed = false
+	expansion.PendingPersistentVolumeClaims = pending
+	if len(pending) > 0 {
+		expansion.Message = fmt.Sprintf("waiting for %d PVCs to be resized", len(pending))
+		return ReconcileResult(nil)
+	}
+
+	log.Info("volume PVCs resized, recreate the StatefulSet with the new volumeClaimTemplates", "statefulSet", statefulSet.Name)
+	err = r.Delete(context.Background(), statefulSet, client.PropagationPolicy(metav1.DeletePropagationOrphan))
+	if err != nil && !errors.IsNotFound(err) {
+		return ReconcileResult(err)
+	}
+	r.Recorder.Eventf(m, corev1.EventTypeNormal, "VolumeExpansionCompleted",
+		"Expanded the PVCs of %s, recreating StatefulSet %s without restarting its pods", pool.description(), statefulSet.Name)
+	removeVolumeExpansion(m, pool.name)
+	return true, ctrl.Result{RequeueAfter: statefulSetRecreateDelay}, nil
+}
+
+// storageClassAllowsExpansion reports whether PVCs of the StorageClass can be resized
+func (r *SeaweedReconciler) storageClassAllowsExpansion(name string) (bool, error) {
+	if name == "" {
+		return false, nil
+	}
+	storageClass := &storagev1.StorageClass{}
+	err := r.Get(context.Background(), types.NamespacedName{Name: name}, storageClass)
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return storageClass.AllowVolumeExpansion != nil && *storageClass.AllowVolumeExpansion, nil
+}
+
+// defaultStorageClass is the name of the StorageClass annotated as the default, empty if there is none
+func (r *SeaweedReconciler) defaultStorageClass() (string, error) {
+	storageClasses := &storagev1.StorageClassList{}
+	if err := r.List(context.Background(), storageClasses); err != nil {
+		return "", err
+	}
+	for _, storageClass := range storageClasses.Items {
+		if storageClass.Annotations[defaultStorageClassAnnotation] == "true" || storageClass.Annotations[betaDefaultStorageClassAnnotation] == "true" {
+			return storageClass.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// persistentVolumeClaimResized reports whether the volume and its filesystem reached the size
+func persistentVolumeClaimResized(pvc *corev1.PersistentVolumeClaim, size resource.Quantity) bool {
+	for _, condition := range pvc.Status.Conditions {
+		if condition.Type == corev1.PersistentVolumeClaimResizing || condition.Type == corev1.PersistentVolumeClaimFileSystemResizePending {
+			return false
+		}
+	}
+	capacity := pvc.Status.Capacity[corev1.ResourceStorage]
+	return capacity.Cmp(size) >= 0
+}
+
+func findVolumeExpansion(m *seaweedv1.Seaweed, pool string) *seaweedv1.VolumeExpansionStatus {
+	for i := range m.Status.VolumeExpansions {
+		if m.Status.VolumeExpansions[i].Pool == pool {
+			return &m.Status.VolumeExpansions[i]
+		}
+	}
+	return nil
+}
+
+func removeVolumeExpansion(m *seaweedv1.Seaweed, pool string) {
+	var expansions []seaweedv1.VolumeExpansionStatus
+	for _, expansion := range m.Status.VolumeExpansions {
+		if expansion.Pool != pool {
+			expansions = append(expansions, expansion)
+		}
+	}
+	m.Status.VolumeExpansions = expansions
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestEnsureVolumeStorageExpanded(t *testing.T) {
+	m := testSeaweed(1, 3)
+	m.Spec.VolumeServerDiskCount = 1
+	statefulSet := (&SeaweedReconciler{}).createVolumeServerStatefulSet(m, volumePools(m)[0])
+
+	allowExpansion := true
+	objects := []runtime.Object{
+		statefulSet,
+		&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "slow"}},
+		&storagev1.StorageClass{
+			ObjectMeta:           metav1.ObjectMeta{Name: "standard", Annotations: map[string]string{defaultStorageClassAnnotation: "true"}},
+			AllowVolumeExpansion: &allowExpansion,
+		},
+	}
+	// the pod of the last ordinal has not been created yet
+	for ordinal := 0; ordinal < 2; ordinal++ {
+		objects = append(objects, &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("mount0-sw-volume-%d", ordinal), Namespace: "default"},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")}},
+			},
+			Status: corev1.PersistentVolumeClaimStatus{Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")}},
+		})
+	}
+	r := newFakeReconciler(objects...)
+	getPVC := func(name string) *corev1.PersistentVolumeClaim {
+		pvc := &corev1.PersistentVolumeClaim{}
+		if err := r.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, pvc); err != nil {
+			t.Fatal(err)
+		}
+		return pvc
+	}
+
+	m.Spec.Volume.Requests = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("2Gi")}
+	pool := volumePools(m)[0]
+
+	// the PVCs without a StorageClass are expanded through the default one
+	if done, _, err := r.ensureVolumeStorageExpanded(m, pool); done || err != nil {
+		t.Fatalf("done = %v, err = %v", done, err)
+	}
+	expansion := findVolumeExpansion(m, "")
+	if expansion == nil || expansion.Failed || len(expansion.PendingPersistentVolumeClaims) != 2 {
+		t.Fatalf("expansion = %+v, want 2 pending PVCs", expansion)
+	}
+	for _, name := range []string{"mount0-sw-volume-0", "mount0-sw-volume-1"} {
+		requested := getPVC(name).Spec.Resources.Requests[corev1.ResourceStorage]
+		if requested.Cmp(resource.MustParse("2Gi")) != 0 {
+			t.Errorf("%s requests %s, want 2Gi", name, requested.String())
+		}
+	}
+
+	// the StatefulSet is kept until the volumes are resized
+	if done, _, err := r.ensureVolumeStorageExpanded(m, pool); done || err != nil {
+		t.Fatalf("done = %v, err = %v while resizing", done, err)
+	}
+	for _, name := range []string{"mount0-sw-volume-0", "mount0-sw-volume-1"} {
+		pvc := getPVC(name)
+		pvc.Status.Capacity[corev1.ResourceStorage] = resource.MustParse("2Gi")
+		if err := r.Status().Update(context.Background(), pvc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// then it is deleted without its pods, and created again with the new volumeClaimTemplates
+	if done, _, err := r.ensureVolumeStorageExpanded(m, pool); !done || err != nil {
+		t.Fatalf("done = %v, err = %v after the resize", done, err)
+	}
+	if findVolumeExpansion(m, "") != nil {
+		t.Errorf("expansion is still reported")
+	}
+	err := r.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "sw-volume"}, statefulSet)
+	if !errors.IsNotFound(err) {
+		t.Errorf("StatefulSet is not recreated: %v", err)
+	}
+
+	// without an expandable default StorageClass the expansion fails
+	slow := "slow"
+	m.Status.VolumeExpansions = nil
+	pvc := getPVC("mount0-sw-volume-0")
+	pvc.Spec.StorageClassName = &slow
+	if err := r.Update(context.Background(), pvc); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Create(context.Background(), (&SeaweedReconciler{}).createVolumeServerStatefulSet(m, pool)); err != nil {
+		t.Fatal(err)
+	}
+	m.Spec.Volume.Requests = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("3Gi")}
+	if done, _, err := r.ensureVolumeStorageExpanded(m, volumePools(m)[0]); done || err != nil {
+		t.Fatalf("done = %v, err = %v", done, err)
+	}
+	if expansion := findVolumeExpansion(m, ""); expansion == nil || !expansion.Failed {
+		t.Errorf("expansion = %+v, want failed on the StorageClass slow", expansion)
+	}
+}
@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/controllers/label"
+)
+
+// volumePool is the group of volume servers of spec.volume, or of one of spec.volumePools.
+// Every pool has its own StatefulSet, peer service and per-ordinal services.
+type volumePool struct {
+	// name is empty for spec.volume
+	name       string
+	spec       *seaweedv1.VolumeSpec
+	accessor   seaweedv1.ComponentAccessor
+	diskCount  int32
+	diskType   string
+	dataCenter string
+	rack       string
+
+	// layout replaces the disks of the spec while disks are added or removed
+	layout []volumeDisk
+}
+
+// volumePools lists spec.volume followed by spec.volumePools
+func volumePools(m *seaweedv1.Seaweed) []volumePool {
+	var pools []volumePool
+	if m.Spec.Volume != nil {
+		pools = append(pools, defaultVolumePool(m))
+	}
+	for i := range m.Spec.VolumePools {
+		poolSpec := &m.Spec.VolumePools[i]
+		pool := volumePool{
+			name:      poolSpec.Name,
+			spec:      &poolSpec.VolumeSpec,
+			accessor:  m.BaseVolumePoolSpec(poolSpec),
+			diskCount: m.Spec.VolumeServerDiskCount,
+		}
+		if poolSpec.DiskCount != nil {
+			pool.diskCount = *poolSpec.DiskCount
+		}
+		if poolSpec.DiskType != nil {
+			pool.diskType = *poolSpec.DiskType
+		}
+		if poolSpec.DataCenter != nil {
+			pool.dataCenter = *poolSpec.DataCenter
+		}
+		if poolSpec.Rack != nil {
+			pool.rack = *poolSpec.Rack
+		}
+		pools = append(pools, pool)
+	}
+	return pools
+}
+
+func defaultVolumePool(m *seaweedv1.Seaweed) volumePool {
+	return volumePool{
+		spec:      m.Spec.Volume,
+		accessor:  m.BaseVolumeSpec(),
+		diskCount: m.Spec.VolumeServerDiskCount,
+	}
+}
+
+// findVolumePool returns the pool of the given name, the empty name is spec.volume
+func findVolumePool(m *seaweedv1.Seaweed, name string) (volumePool, bool) {
+	for _, pool := range volumePools(m) {
+		if pool.name == name {
+			return pool, true
+		}
+	}
+	return volumePool{}, false
+}
+
+func (p volumePool) statefulSetName(m *seaweedv1.Seaweed) string {
+	if p.name == "" {
+		return m.Name + "-volume"
+	}
+	return m.Name + "-volume-" + p.name
+}
+
+func (p volumePool) peerServiceName(m *seaweedv1.Seaweed) string {
+	return p.statefulSetName(m) + "-peer"
+}
+
+// serviceName is the name of the service of one volume server, the same as its pod name
+func (p volumePool) serviceName(m *seaweedv1.Seaweed, ordinal int) string {
+	return fmt.Sprintf("%s-%d", p.statefulSetName(m), ordinal)
+}
+
+// serverAddress is the address a volume server of the pool registers with the masters
+func (p volumePool) serverAddress(m *seaweedv1.Seaweed, ordinal int32) string {
+	return fmt.Sprintf("%s.%s.%s:%d", p.serviceName(m, int(ordinal)), p.peerServiceName(m), m.Namespace, seaweedv1.VolumeHTTPPort)
+}
+
+// labels of the pods of the pool, also the selector of its StatefulSet and services.
+// The pool label is empty for spec.volume, so its selectors do not match the pods of the named pools.
+func (p volumePool) labels(m *seaweedv1.Seaweed) map[string]string {
+	labels := labelsForVolumeServer(m.Name)
+	labels[label.VolumePoolLabelKey] = p.name
+	return labels
+}
+
+// ensureVolumePoolLabeled moves the StatefulSet of spec.volume from a selector without the pool label,
+// which also matched the pods of the named pools, to the labels of the pool. Its pods are labeled first,
+// so the services keep their endpoints, then the StatefulSet is recreated with the orphan propagation policy
+// and adopts the running pods.
+func (r *SeaweedReconciler) ensureVolumePoolLabeled(m *seaweedv1.Seaweed, pool volumePool) (bool, ctrl.Result, error) {
+	if pool.name != "" {
+		return ReconcileResult(nil)
+	}
+	log := r.Log.WithValues("sw-volume-pool-label", m.Name)
+
+	statefulSet := &appsv1.StatefulSet{}
+	err := r.Get(context.Background(), types.NamespacedName{Namespace: m.Namespace, Name: pool.statefulSetName(m)}, statefulSet)
+	if errors.IsNotFound(err) {
+		return ReconcileResult(nil)
+	}
+	if err != nil {
+		return ReconcileResult(err)
+	}
+	if statefulSet.DeletionTimestamp != nil {
+		return true, ctrl.Result{RequeueAfter: statefulSetRecreateDelay}, nil
+	}
+	if _, found := statefulSet.Spec.Selector.MatchLabels[label.VolumePoolLabelKey]; found {
+		return ReconcileResult(nil)
+	}
+
+	podList := &corev1.PodList{}
+	if err := r.List(context.Background(), podList, client.InNamespace(m.Namespace), client.MatchingLabels(labelsForVolumeServer(m.Name))); err != nil {
+		return ReconcileResult(err)
+	}
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if !metav1.IsControlledBy(pod, statefulSet) {
+			continue
+		}
+		if _, found := pod.Labels[label.VolumePoolLabelKey]; found {
+			continue
+		}
+		patch := client.MergeFrom(pod.DeepCopy())
+		pod.Labels[label.VolumePoolLabelKey] = pool.name
+		if err := r.Patch(context.Background(), pod, patch); err != nil && !errors.IsNotFound(err) {
+			return ReconcileResult(err)
+		}
+	}
+
+	log.Info("recreate the StatefulSet with the pool label in its selector", "statefulSet", statefulSet.Name)
+	err = r.Delete(context.Background(), statefulSet, client.PropagationPolicy(metav1.DeletePropagationOrphan))
+	if err != nil && !errors.IsNotFound(err) {
+		return ReconcileResult(err)
+	}
+	r.Recorder.Eventf(m, corev1.EventTypeNormal, "VolumeSelectorMigrating",
+		"Recreating StatefulSet %s with a selector which does not match the volume pools, the pods keep running", statefulSet.Name)
+	return true, ctrl.Result{RequeueAfter: statefulSetRecreateDelay}, nil
+}
+
+// description names the pool in logs and events
+func (p volumePool) description() string {
+	if p.name == "" {
+		return "volume servers"
+	}
+	return fmt.Sprintf("volume pool %s", p.name)
+}
+
+// volumeDisk is a data directory of a volume server with its PVC
+type volumeDisk struct {
+	name             string
+	size             resource.Quantity
+	storageClassName *string
+	mountPath        string
+	diskType         string
+	maxVolumes       int32
+}
+
+// disks resolves spec.disks, or diskCount identical disks sized by the storage request
+func (p volumePool) disks() []volumeDisk {
+	if p.layout != nil {
+		return p.layout
+	}
+
+	var disks []volumeDisk
+	if len(p.spec.Disks) == 0 {
+		for i := 0; i < int(p.diskCount); i++ {
+			disks = append(disks, volumeDisk{
+				name:             fmt.Sprintf("mount%d", i),
+				size:             p.spec.Requests[corev1.ResourceStorage],
+				storageClassName: p.spec.StorageClassName,
+				mountPath:        fmt.Sprintf("/data%d", i),
+				diskType:         p.diskType,
+			})
+		}
+		return disks
+	}
+
+	for i, diskSpec := range p.spec.Disks {
+		disk := volumeDisk{
+			name:             diskSpec.Name,
+			size:             diskSpec.Size,
+			storageClassName: diskSpec.StorageClassName,
+			mountPath:        strings.TrimSuffix(diskSpec.MountPath, "/"),
+			diskType:         diskSpec.DiskType,
+		}
+		if disk.name == "" {
+			disk.name = fmt.Sprintf("mount%d", i)
+		}
+		if disk.storageClassName == nil {
+			disk.storageClassName = p.spec.StorageClassName
+		}
+		if disk.mountPath == "" {
+			disk.mountPath = fmt.Sprintf("/data%d", i)
+		}
+		if disk.diskType == "" {
+			disk.diskType = p.diskType
+		}
+		if diskSpec.MaxVolumes != nil {
+			disk.maxVolumes = *diskSpec.MaxVolumes
+		}
+		disks = append(disks, disk)
+	}
+	return disks
+}
@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/controllers/label"
+)
+
+func volumePoolTestSeaweed() *seaweedv1.Seaweed {
+	m := testSeaweed(1, 2)
+	diskCount := int32(2)
+	m.Spec.VolumeServerDiskCount = 1
+	m.Spec.VolumePools = []seaweedv1.VolumePoolSpec{
+		{Name: "hot", VolumeSpec: seaweedv1.VolumeSpec{Replicas: 1}, DiskCount: &diskCount},
+	}
+	return m
+}
+
+func TestVolumePools(t *testing.T) {
+	m := volumePoolTestSeaweed()
+	pools := volumePools(m)
+	if len(pools) != 2 || pools[0].name != "" || pools[1].name != "hot" || pools[1].diskCount != 2 || pools[0].diskCount != 1 {
+		t.Fatalf("pools = %+v", pools)
+	}
+
+	for _, tc := range []struct {
+		pool                         volumePool
+		statefulSet, server, address string
+	}{
+		{pools[0], "sw-volume", "sw-volume-1", "sw-volume-1.sw-volume-peer.default:8444"},
+		{pools[1], "sw-volume-hot", "sw-volume-hot-1", "sw-volume-hot-1.sw-volume-hot-peer.default:8444"},
+	} {
+		if got := tc.pool.statefulSetName(m); got != tc.statefulSet {
+			t.Errorf("statefulSetName = %s, want %s", got, tc.statefulSet)
+		}
+		if got := tc.pool.serviceName(m, 1); got != tc.server {
+			t.Errorf("serviceName = %s, want %s", got, tc.server)
+		}
+		if got := tc.pool.serverAddress(m, 1); got != tc.address {
+			t.Errorf("serverAddress = %s, want %s", got, tc.address)
+		}
+	}
+
+	// the selectors of one pool do not match the pods of the other
+	for i, pool := range pools {
+		selector := labels.SelectorFromSet(pool.labels(m))
+		for j, other := range pools {
+			if matches := selector.Matches(labels.Set(other.labels(m))); matches != (i == j) {
+				t.Errorf("selector of %q matches the pods of %q: %v", pool.name, other.name, matches)
+			}
+		}
+	}
+
+	if _, found := findVolumePool(m, "cold"); found {
+		t.Errorf("found a pool which is not in the spec")
+	}
+}
+
+func TestValidateVolumePoolsUpdate(t *testing.T) {
+	old := volumePoolTestSeaweed()
+	old.Spec.Volume.Requests = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")}
+	old.Spec.VolumePools[0].Requests = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")}
+
+	updated := old.DeepCopy()
+	updated.Spec.VolumePools[0].Replicas = 3
+	if err := updated.ValidateUpdate(old); err != nil {
+		t.Errorf("scaling a pool is refused: %v", err)
+	}
+
+	updated.Spec.VolumePools = nil
+	if err := updated.ValidateUpdate(old); err == nil || !strings.Contains(err.Error(), "volume pool hot can not be removed") {
+		t.Errorf("removing a pool: %v", err)
+	}
+
+	updated = old.DeepCopy()
+	updated.Spec.VolumePools = append(updated.Spec.VolumePools, updated.Spec.VolumePools[0])
+	if err := updated.ValidateUpdate(old); err == nil || !strings.Contains(err.Error(), "volume pool hot is defined twice") {
+		t.Errorf("duplicate pool: %v", err)
+	}
+}
+
+func TestEnsureVolumePoolLabeled(t *testing.T) {
+	m := volumePoolTestSeaweed()
+	oldSelector := labelsForVolumeServer(m.Name)
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "sw-volume", Namespace: "default", UID: "sts-uid"},
+		Spec:       appsv1.StatefulSetSpec{Selector: &metav1.LabelSelector{MatchLabels: oldSelector}},
+	}
+	controller := true
+	defaultPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name: "sw-volume-0", Namespace: "default", Labels: labelsForVolumeServer(m.Name),
+		OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "StatefulSet", Name: "sw-volume", UID: "sts-uid", Controller: &controller}},
+	}}
+	poolPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name: "sw-volume-hot-0", Namespace: "default", Labels: volumePools(m)[1].labels(m),
+		OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "StatefulSet", Name: "sw-volume-hot", UID: "hot-uid", Controller: &controller}},
+	}}
+	r := newFakeReconciler(statefulSet, defaultPod, poolPod)
+
+	if done, _, err := r.ensureVolumePoolLabeled(m, volumePools(m)[0]); !done || err != nil {
+		t.Fatalf("done = %v, err = %v, want the StatefulSet recreated", done, err)
+	}
+	pod := &corev1.Pod{}
+	if err := r.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "sw-volume-0"}, pod); err != nil {
+		t.Fatal(err)
+	}
+	if value, found := pod.Labels[label.VolumePoolLabelKey]; !found || value != "" {
+		t.Errorf("pod of spec.volume is not labeled: %v", pod.Labels)
+	}
+	if err := r.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "sw-volume-hot-0"}, pod); err != nil || pod.Labels[label.VolumePoolLabelKey] != "hot" {
+		t.Errorf("pod of the pool changed: %v %v", pod.Labels, err)
+	}
+	err := r.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "sw-volume"}, &appsv1.StatefulSet{})
+	if !errors.IsNotFound(err) {
+		t.Errorf("StatefulSet with the old selector is kept: %v", err)
+	}
+
+	// a StatefulSet with the pool label is left alone
+	statefulSet = r.createVolumeServerStatefulSet(m, volumePools(m)[0])
+	if err := r.Create(context.Background(), statefulSet); err != nil {
+		t.Fatal(err)
+	}
+	if done, _, err := r.ensureVolumePoolLabeled(m, volumePools(m)[0]); done || err != nil {
+		t.Errorf("done = %v, err = %v for a migrated StatefulSet", done, err)
+	}
+}
@@ -0,0 +1,187 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/controllers/swadmin"
+)
+
+// ensureVolumeServersRolledOut restarts the outdated volume servers of a pool with the OnDelete update strategy.
+// One pod is deleted at a time, and the next one only once the restarted server registered with the master again
+// and every volume it held before the restart has a replica again.
+func (r *SeaweedReconciler) ensureVolumeServersRolledOut(m *seaweedv1.Seaweed, pool volumePool) (bool, ctrl.Result, error) {
+	log := r.Log.WithValues("sw-volume-rollout", m.Name, "pool", pool.name)
+
+	statefulSet := &appsv1.StatefulSet{}
+	err := r.Get(context.Background(), types.NamespacedName{Namespace: m.Namespace, Name: pool.statefulSetName(m)}, statefulSet)
+	if errors.IsNotFound(err) {
+		removeVolumeRollout(m, pool.name)
+		return ReconcileResult(nil)
+	}
+	if err != nil {
+		return ReconcileResult(err)
+	}
+	if statefulSet.Spec.UpdateStrategy.Type != appsv1.OnDeleteStatefulSetStrategyType {
+		removeVolumeRollout(m, pool.name)
+		return ReconcileResult(nil)
+	}
+	if statefulSet.Status.ObservedGeneration < statefulSet.Generation || statefulSet.Status.UpdateRevision == "" {
+		return ReconcileResult(nil)
+	}
+	revision := statefulSet.Status.UpdateRevision
+
+	podList := &corev1.PodList{}
+	if err := r.List(context.Background(), podList, client.InNamespace(m.Namespace), client.MatchingLabels(pool.labels(m))); err != nil {
+		return ReconcileResult(err)
+	}
+	// only the pods of this StatefulSet, a selector overlapping another pool must not restart its servers
+	var pods []corev1.Pod
+	for _, pod := range podList.Items {
+		if metav1.IsControlledBy(&pod, statefulSet) {
+			pods = append(pods, pod)
+		}
+	}
+	var outdated []corev1.Pod
+	for _, pod := range pods {
+		if pod.Labels[appsv1.StatefulSetRevisionLabel] != revision {
+			outdated = append(outdated, pod)
+		}
+	}
+
+	rollout := findVolumeRollout(m, pool.name)
+	if len(outdated) == 0 && (rollout == nil || rollout.RestartingServer == "") {
+		if rollout != nil {
+			log.Info("volume servers rolled out", "revision", revision)
+			r.Recorder.Eventf(m, corev1.EventTypeNormal, "VolumeRolloutCompleted", "Restarted the volume servers of %s into revision %s", pool.description(), revision)
+			removeVolumeRollout(m, pool.name)
+		}
+		return ReconcileResult(nil)
+	}
+	if rollout == nil {
+		now := metav1.Now()
+		m.Status.VolumeRollouts = append(m.Status.VolumeRollouts, seaweedv1.VolumeRolloutStatus{Pool: pool.name, StartTime: &now})
+		rollout = &m.Status.VolumeRollouts[len(m.Status.VolumeRollouts)-1]
+	}
+	if rollout.Revision != revision {
+		// a restart in flight is still verified when the revision changes again
+		rollout.Revision = revision
+		log.Info("start volume server rollout", "revision", revision, "outdated", len(outdated))
+		r.Recorder.Eventf(m, corev1.EventTypeNormal, "VolumeRolloutStarted", "Restarting %d volume servers of %s into revision %s", len(outdated), pool.description(), revision)
+	}
+	rollout.UpdatedReplicas = int32(len(pods) - len(outdated))
+
+	replicas := pool.spec.Replicas
+	if statefulSet.Spec.Replicas != nil {
+		replicas = *statefulSet.Spec.Replicas
+	}
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil || !podReady(&pod) {
+			rollout.Message = fmt.Sprintf("waiting for pod %s to be ready", pod.Name)
+			return ReconcileResult(nil)
+		}
+	}
+	if int32(len(pods)) < replicas {
+		rollout.Message = fmt.Sprintf("waiting for %d of %d pods to be created", replicas-int32(len(pods)), replicas)
+		return ReconcileResult(nil)
+	}
+	if task := r.currentAdminTask(m); task != nil {
+		if finished, _ := task.finished(); !finished {
+			rollout.Message = fmt.Sprintf("waiting for admin task %s started at %s", task.name, task.startTime.Format(time.RFC3339))
+			return ReconcileResult(nil)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), swadminTimeout)
+	defer cancel()
+	topology, err := r.seaweedAdmin(m).VolumeList(ctx)
+	if err != nil {
+		rollout.Message = fmt.Sprintf("can not read the volume topology: %v", err)
+		return ReconcileResult(nil)
+	}
+	if server := unregisteredVolumeServer(m, topology); server != "" {
+		rollout.Message = fmt.Sprintf("waiting for volume server %s to register with the master", server)
+		return ReconcileResult(nil)
+	}
+
+	if rollout.RestartingServer != "" {
+		if lost := volumesWithoutReplica(topology, rollout.Volumes); len(lost) > 0 {
+			rollout.Message = fmt.Sprintf("waiting for volumes %v of %s to have a replica again", lost, rollout.RestartingServer)
+			return ReconcileResult(nil)
+		}
+		log.Info("volume server restarted", "server", rollout.RestartingServer)
+		r.Recorder.Eventf(m, corev1.EventTypeNormal, "VolumeServerRestarted",
+			"Volume server %s registered again with its %d volumes", rollout.RestartingServer, len(rollout.Volumes))
+		rollout.RestartingServer = ""
+		rollout.Volumes = nil
+	}
+	if len(outdated) == 0 {
+		return ReconcileResult(nil)
+	}
+
+	// the highest ordinal first, like a RollingUpdate
+	sort.Slice(outdated, func(i, j int) bool { return podOrdinal(outdated[i].Name) > podOrdinal(outdated[j].Name) })
+	pod := &outdated[0]
+	server := pool.serverAddress(m, int32(podOrdinal(pod.Name)))
+	rollout.RestartingServer = server
+	rollout.Volumes = volumeIdsOnServer(topology, server)
+	rollout.Message = fmt.Sprintf("restarting volume server %s", server)
+	log.Info("restart volume server", "pod", pod.Name, "volumes", len(rollout.Volumes))
+	if err := r.Delete(context.Background(), pod); err != nil && !errors.IsNotFound(err) {
+		return ReconcileResult(err)
+	}
+	return ReconcileResult(nil)
+}
+
+// unregisteredVolumeServer returns a volume server of the cluster the master does not know, empty if there is none
+func unregisteredVolumeServer(m *seaweedv1.Seaweed, topology *swadmin.Topology) string {
+	for _, pool := range volumePools(m) {
+		for i := int32(0); i < pool.spec.Replicas; i++ {
+			if server := pool.serverAddress(m, i); topology.Node(server) == nil {
+				return server
+			}
+		}
+	}
+	return ""
+}
+
+// volumesWithoutReplica lists the volumes no volume server holds
+func volumesWithoutReplica(topology *swadmin.Topology, vids []uint32) []uint32 {
+	var lost []uint32
+	for _, vid := range vids {
+		if len(topology.VolumeLocations(vid)) == 0 {
+			lost = append(lost, vid)
+		}
+	}
+	return lost
+}
+
+func findVolumeRollout(m *seaweedv1.Seaweed, pool string) *seaweedv1.VolumeRolloutStatus {
+	for i := range m.Status.VolumeRollouts {
+		if m.Status.VolumeRollouts[i].Pool == pool {
+			return &m.Status.VolumeRollouts[i]
+		}
+	}
+	return nil
+}
+
+func removeVolumeRollout(m *seaweedv1.Seaweed, pool string) {
+	var rollouts []seaweedv1.VolumeRolloutStatus
+	for _, rollout := range m.Status.VolumeRollouts {
+		if rollout.Pool != pool {
+			rollouts = append(rollouts, rollout)
+		}
+	}
+	m.Status.VolumeRollouts = rollouts
+}
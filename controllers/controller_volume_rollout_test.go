@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+func TestVolumeRolloutHealth(t *testing.T) {
+	topology := testTopology(map[string][]uint32{
+		"sw-volume-0.sw-volume-peer.default:8444": {1, 2},
+		"sw-volume-1.sw-volume-peer.default:8444": {2, 3},
+	})
+	m := testSeaweed(1, 2)
+
+	if server := unregisteredVolumeServer(m, topology); server != "" {
+		t.Errorf("unregistered server = %s, want none", server)
+	}
+	m.Spec.Volume.Replicas = 3
+	if server := unregisteredVolumeServer(m, topology); server != "sw-volume-2.sw-volume-peer.default:8444" {
+		t.Errorf("unregistered server = %s, want sw-volume-2", server)
+	}
+
+	if lost := volumesWithoutReplica(topology, []uint32{1, 2, 3, 4}); !reflect.DeepEqual(lost, []uint32{4}) {
+		t.Errorf("volumes without replica = %v, want [4]", lost)
+	}
+}
+
+func TestStatefulSetUpdateStrategy(t *testing.T) {
+	m := &seaweedv1.Seaweed{
+		Spec: seaweedv1.SeaweedSpec{
+			StatefulSetUpdateStrategy: appsv1.OnDeleteStatefulSetStrategyType,
+			Master:                    &seaweedv1.MasterSpec{Replicas: 1},
+			Volume:                    &seaweedv1.VolumeSpec{Replicas: 1},
+			Filer: &seaweedv1.FilerSpec{
+				ComponentSpec: seaweedv1.ComponentSpec{StatefulSetUpdateStrategy: appsv1.RollingUpdateStatefulSetStrategyType},
+				Replicas:      1,
+			},
+		},
+	}
+
+	if strategy := statefulSetUpdateStrategy(m.BaseVolumeSpec()); strategy.Type != appsv1.OnDeleteStatefulSetStrategyType || strategy.RollingUpdate != nil {
+		t.Errorf("volume strategy = %+v, want OnDelete", strategy)
+	}
+	if strategy := statefulSetUpdateStrategy(m.BaseFilerSpec()); strategy.Type != appsv1.RollingUpdateStatefulSetStrategyType || *strategy.RollingUpdate.Partition != 0 {
+		t.Errorf("filer strategy = %+v, want RollingUpdate", strategy)
+	}
+}
+
+func TestVolumeRolloutOfDefaultPool(t *testing.T) {
+	m := volumePoolTestSeaweed()
+	m.Spec.StatefulSetUpdateStrategy = appsv1.OnDeleteStatefulSetStrategyType
+	var objects []runtime.Object
+	for _, pool := range volumePools(m) {
+		statefulSet := (&SeaweedReconciler{}).createVolumeServerStatefulSet(m, pool)
+		statefulSet.UID = types.UID(statefulSet.Name)
+		statefulSet.Status.UpdateRevision = statefulSet.Name + "-v2"
+		objects = append(objects, statefulSet)
+	}
+	pod := func(name, owner, revision string, labels map[string]string) *corev1.Pod {
+		controller := true
+		labels[appsv1.StatefulSetRevisionLabel] = revision
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name, Namespace: "default", Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "StatefulSet", Name: owner, UID: types.UID(owner), Controller: &controller}},
+			},
+			Status: corev1.PodStatus{Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}},
+		}
+	}
+	pools := volumePools(m)
+	objects = append(objects,
+		pod("sw-volume-0", "sw-volume", "sw-volume-v2", pools[0].labels(m)),
+		pod("sw-volume-1", "sw-volume", "sw-volume-v2", pools[0].labels(m)),
+		// outdated pods of the named pool, one of them matching the selector of spec.volume
+		pod("sw-volume-hot-0", "sw-volume-hot", "sw-volume-hot-v1", pools[1].labels(m)),
+		pod("sw-volume-hot-1", "sw-volume-hot", "sw-volume-hot-v1", pools[0].labels(m)),
+	)
+	r := newFakeReconciler(objects...)
+
+	if done, _, err := r.ensureVolumeServersRolledOut(m, pools[0]); done || err != nil {
+		t.Fatalf("done = %v, err = %v", done, err)
+	}
+	if rollout := findVolumeRollout(m, ""); rollout != nil {
+		t.Errorf("rollout of spec.volume started for the pods of the pool: %+v", rollout)
+	}
+	for _, name := range []string{"sw-volume-0", "sw-volume-1", "sw-volume-hot-0", "sw-volume-hot-1"} {
+		if err := r.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, &corev1.Pod{}); err != nil {
+			t.Errorf("pod %s: %v", name, err)
+		}
+	}
+}
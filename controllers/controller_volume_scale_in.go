@@ -0,0 +1,261 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/controllers/swadmin"
+)
+
+const (
+	volumeDrainTaskName   = "volume-drain"
+	volumeRestoreTaskName = "volume-restore-writable"
+
+	// volumeDrainRetryDelay is the pause before a failed drain is retried
+	volumeDrainRetryDelay = 60 * time.Second
+)
+
+// volumeServerReplicas decides the replica count of the StatefulSet of a volume pool.
+// On scale-in, the current replica count is kept until the departing servers are drained:
+// their volumes are marked readonly, evacuated to the remaining servers and marked writable again.
+// Only one pool is scaled in at a time.
+func (r *SeaweedReconciler) volumeServerReplicas(m *seaweedv1.Seaweed, pool volumePool) (int32, error) {
+	log := r.Log.WithValues("sw-volume-scale-in", m.Name, "pool", pool.name)
+	desired := pool.spec.Replicas
+
+	statefulSet := &appsv1.StatefulSet{}
+	err := r.Get(context.Background(), types.NamespacedName{Namespace: m.Namespace, Name: pool.statefulSetName(m)}, statefulSet)
+	if errors.IsNotFound(err) {
+		return desired, nil
+	}
+	if err != nil {
+		return desired, err
+	}
+	current := desired
+	if statefulSet.Spec.Replicas != nil {
+		current = *statefulSet.Spec.Replicas
+	}
+
+	if current <= desired {
+		return desired, r.cancelVolumeScaleIn(m, pool)
+	}
+
+	scaleIn := m.Status.VolumeScaleIn
+	if scaleIn != nil && scaleIn.Pool != pool.name {
+		log.Info("wait for the scale-in of another volume pool", "scalingIn", scaleIn.Pool)
+		return current, nil
+	}
+	if scaleIn == nil || scaleIn.FromReplicas != current || scaleIn.ToReplicas != desired {
+		now := metav1.Now()
+		var readonlyVolumes []uint32
+		if scaleIn != nil {
+			readonlyVolumes = scaleIn.ReadonlyVolumes
+		}
+		scaleIn = &seaweedv1.VolumeScaleInStatus{
+			Pool:            pool.name,
+			FromReplicas:    current,
+			ToReplicas:      desired,
+			StartTime:       &now,
+			ReadonlyVolumes: readonlyVolumes,
+		}
+		for ordinal := desired; ordinal < current; ordinal++ {
+			scaleIn.DrainingServers = append(scaleIn.DrainingServers, pool.serverAddress(m, ordinal))
+		}
+		m.Status.VolumeScaleIn = scaleIn
+		// the servers hold volumes again since their last drain
+		m.Status.DrainedVolumeServers = removeStrings(m.Status.DrainedVolumeServers, scaleIn.DrainingServers)
+		log.Info("start volume server scale-in", "from", current, "to", desired)
+		r.Recorder.Eventf(m, corev1.EventTypeNormal, "VolumeScaleInStarted",
+			"Draining volume servers %s before scaling in %s from %d to %d", strings.Join(scaleIn.DrainingServers, ","), pool.description(), current, desired)
+	}
+
+	if task := r.currentAdminTask(m); task != nil {
+		finished, taskErr := task.finished()
+		switch {
+		case !finished:
+			scaleIn.Message = fmt.Sprintf("waiting for admin task %s started at %s", task.name, task.startTime.Format(time.RFC3339))
+			return current, nil
+		case task.name != volumeDrainTaskName:
+			// the owner of the task records its result before clearing it
+			scaleIn.Message = fmt.Sprintf("waiting for admin task %s to be cleared", task.name)
+			return current, nil
+		case taskErr != nil:
+			scaleIn.Message = fmt.Sprintf("drain failed: %v", taskErr)
+			if time.Since(task.finishTime) < volumeDrainRetryDelay {
+				return current, nil
+			}
+			r.Recorder.Eventf(m, corev1.EventTypeWarning, "VolumeDrainFailed", "Draining volume servers failed, retrying: %v", taskErr)
+			r.clearAdminTask(m)
+		default:
+			// the task marked the moved volumes writable again
+			scaleIn.ReadonlyVolumes = nil
+			r.clearAdminTask(m)
+		}
+	}
+
+	sa := r.seaweedAdmin(m)
+	ctx, cancel := context.WithTimeout(context.Background(), swadminTimeout)
+	defer cancel()
+	topology, err := sa.VolumeList(ctx)
+	if err != nil {
+		scaleIn.Message = fmt.Sprintf("can not read the volume topology: %v", err)
+		return current, nil
+	}
+
+	remaining := 0
+	for _, server := range scaleIn.DrainingServers {
+		remaining += countVolumesOnServer(topology, server)
+	}
+	scaleIn.RemainingVolumes = int32(remaining)
+
+	if remaining == 0 && len(scaleIn.ReadonlyVolumes) == 0 {
+		log.Info("volume servers drained", "servers", scaleIn.DrainingServers)
+		r.Recorder.Eventf(m, corev1.EventTypeNormal, "VolumeScaleInCompleted",
+			"Drained volume servers %s, scaling in %s from %d to %d", strings.Join(scaleIn.DrainingServers, ","), pool.description(), current, desired)
+		m.Status.DrainedVolumeServers = sortedUnique(append(m.Status.DrainedVolumeServers, scaleIn.DrainingServers...))
+		m.Status.VolumeScaleIn = nil
+		return desired, nil
+	}
+
+	for _, server := range scaleIn.DrainingServers {
+		scaleIn.ReadonlyVolumes = mergeVolumeIds(scaleIn.ReadonlyVolumes, volumeIdsOnServer(topology, server))
+	}
+	servers := scaleIn.DrainingServers
+	readonlyVolumes := scaleIn.ReadonlyVolumes
+	if r.startAdminTask(m, volumeDrainTaskName, func(ctx context.Context) error {
+		return drainVolumeServers(ctx, sa, servers, readonlyVolumes)
+	}) {
+		scaleIn.Message = fmt.Sprintf("draining %d volumes from %d servers", remaining, len(servers))
+	}
+	return current, nil
+}
+
+// cancelVolumeScaleIn marks the volumes writable again if a scale-in of the pool was abandoned half way
+func (r *SeaweedReconciler) cancelVolumeScaleIn(m *seaweedv1.Seaweed, pool volumePool) error {
+	scaleIn := m.Status.VolumeScaleIn
+	if scaleIn == nil || scaleIn.Pool != pool.name {
+		return nil
+	}
+
+	if task := r.currentAdminTask(m); task != nil {
+		finished, taskErr := task.finished()
+		if !finished {
+			scaleIn.Message = fmt.Sprintf("scale-in canceled, waiting for admin task %s", task.name)
+			return nil
+		}
+		if task.name != volumeDrainTaskName && task.name != volumeRestoreTaskName {
+			scaleIn.Message = fmt.Sprintf("scale-in canceled, waiting for admin task %s to be cleared", task.name)
+			return nil
+		}
+		r.clearAdminTask(m)
+		if taskErr == nil {
+			scaleIn.ReadonlyVolumes = nil
+		}
+	}
+
+	if len(scaleIn.ReadonlyVolumes) == 0 {
+		r.Recorder.Event(m, corev1.EventTypeNormal, "VolumeScaleInCanceled", "Volume server scale-in canceled")
+		m.Status.VolumeScaleIn = nil
+		return nil
+	}
+
+	sa := r.seaweedAdmin(m)
+	readonlyVolumes := scaleIn.ReadonlyVolumes
+	if r.startAdminTask(m, volumeRestoreTaskName, func(ctx context.Context) error {
+		return sa.WithLock(ctx, func(ctx context.Context) error {
+			return markVolumesWritable(ctx, sa, readonlyVolumes, nil)
+		})
+	}) {
+		scaleIn.Message = fmt.Sprintf("scale-in canceled, marking %d volumes writable again", len(readonlyVolumes))
+	}
+	return nil
+}
+
+// drainVolumeServers marks the volumes of the servers readonly, evacuates the servers,
+// and marks the moved volumes writable on their new servers
+func drainVolumeServers(ctx context.Context, sa swadmin.Admin, servers []string, readonlyVolumes []uint32) error {
+	return sa.WithLock(ctx, func(ctx context.Context) error {
+		topology, err := sa.VolumeList(ctx)
+		if err != nil {
+			return err
+		}
+		for _, server := range servers {
+			for _, vid := range volumeIdsOnServer(topology, server) {
+				if err := sa.ProcessCommand(ctx, fmt.Sprintf("volume.mark -node %s -volumeId %d -readonly", server, vid), ioutil.Discard); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, server := range servers {
+			if err := sa.ProcessCommand(ctx, fmt.Sprintf("volumeServer.evacuate -node %s -force", server), ioutil.Discard); err != nil {
+				return fmt.Errorf("evacuate %s: %v", server, err)
+			}
+		}
+
+		return markVolumesWritable(ctx, sa, readonlyVolumes, servers)
+	})
+}
+
+// markVolumesWritable marks every replica of the volumes writable, except the replicas on the excluded servers
+func markVolumesWritable(ctx context.Context, sa swadmin.Admin, vids []uint32, excluded []string) error {
+	topology, err := sa.VolumeList(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, vid := range vids {
+		for _, server := range topology.VolumeLocations(vid) {
+			if containsString(excluded, server) {
+				continue
+			}
+			if err := sa.ProcessCommand(ctx, fmt.Sprintf("volume.mark -node %s -volumeId %d -writable", server, vid), ioutil.Discard); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// volumeIdsOnServer lists the ids of the normal volumes on the server
+func volumeIdsOnServer(topology *swadmin.Topology, server string) []uint32 {
+	if node := topology.Node(server); node != nil {
+		return node.VolumeIDs()
+	}
+	return nil
+}
+
+func mergeVolumeIds(vids []uint32, more []uint32) []uint32 {
+	seen := make(map[uint32]bool)
+	var merged []uint32
+	for _, vid := range append(append([]uint32{}, vids...), more...) {
+		if !seen[vid] {
+			seen[vid] = true
+			merged = append(merged, vid)
+		}
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i] < merged[j] })
+	return merged
+}
+
+// removeStrings returns the values which are not in removed
+func removeStrings(values []string, removed []string) []string {
+	var kept []string
+	for _, value := range values {
+		if !containsString(removed, value) {
+			kept = append(kept, value)
+		}
+	}
+	return kept
+}
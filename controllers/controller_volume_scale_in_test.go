@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/controllers/swadmin"
+)
+
+func TestDrainVolumeServers(t *testing.T) {
+	departing := "sw-volume-1.sw-volume-peer.default:8444"
+	remaining := "sw-volume-0.sw-volume-peer.default:8444"
+
+	sa := swadmin.NewFakeAdmin("sw-master-0.sw-master-peer.default:9333")
+	sa.Topology = testTopology(map[string][]uint32{
+		departing: {1, 2},
+		remaining: {3},
+	})
+	sa.OnCommand = func(cmd string) {
+		if cmd == "volumeServer.evacuate -node "+departing+" -force" {
+			sa.Topology = testTopology(map[string][]uint32{
+				departing: nil,
+				remaining: {1, 2, 3},
+			})
+		}
+	}
+
+	if err := drainVolumeServers(context.Background(), sa, []string{departing}, []uint32{1, 2}); err != nil {
+		t.Fatalf("drain: %v", err)
+	}
+
+	expected := []string{
+		"lock",
+		"volume.mark -node " + departing + " -volumeId 1 -readonly",
+		"volume.mark -node " + departing + " -volumeId 2 -readonly",
+		"volumeServer.evacuate -node " + departing + " -force",
+		"volume.mark -node " + remaining + " -volumeId 1 -writable",
+		"volume.mark -node " + remaining + " -volumeId 2 -writable",
+		"unlock",
+	}
+	if commands := sa.Commands(); !reflect.DeepEqual(commands, expected) {
+		t.Errorf("commands = %q, expected %q", commands, expected)
+	}
+}
+
+func TestCancelVolumeScaleInLeavesOtherTasks(t *testing.T) {
+	m := testSeaweed(1, 2)
+	m.Status.VolumeScaleIn = &seaweedv1.VolumeScaleInStatus{FromReplicas: 2, ToReplicas: 1, ReadonlyVolumes: []uint32{1}}
+	r := newFakeReconciler(m)
+	finish := func(name string) *adminTask {
+		if !r.startAdminTask(m, name, func(ctx context.Context) error { return nil }) {
+			t.Fatalf("%s did not start", name)
+		}
+		task := r.currentAdminTask(m)
+		waitForAdminTask(t, task)
+		return task
+	}
+
+	// a finished balance is left to the balance to record and clear
+	balance := finish(volumeBalanceTaskName)
+	if err := r.cancelVolumeScaleIn(m, volumePool{}); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if r.currentAdminTask(m) != balance {
+		t.Errorf("finished balance cleared by the scale-in")
+	}
+	if m.Status.VolumeScaleIn == nil || len(m.Status.VolumeScaleIn.ReadonlyVolumes) != 1 {
+		t.Errorf("scale-in = %+v while waiting for the balance", m.Status.VolumeScaleIn)
+	}
+	r.clearAdminTask(m)
+
+	// a finished restore is the scale-in's own
+	finish(volumeRestoreTaskName)
+	if err := r.cancelVolumeScaleIn(m, volumePool{}); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if task := r.currentAdminTask(m); task != nil {
+		t.Errorf("restore task %s not cleared", task.name)
+	}
+	if m.Status.VolumeScaleIn != nil {
+		t.Errorf("scale-in = %+v after the restore", m.Status.VolumeScaleIn)
+	}
+}
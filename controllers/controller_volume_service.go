@@ -1,8 +1,6 @@
 package controllers
 
 import (
-	"fmt"
-
 	"github.com/seaweedfs/seaweedfs-operator/controllers/label"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -11,12 +9,12 @@ import (
 	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
 )
 
-func (r *SeaweedReconciler) createVolumeServerPeerService(m *seaweedv1.Seaweed) *corev1.Service {
-	labels := labelsForVolumeServer(m.Name)
+func (r *SeaweedReconciler) createVolumeServerPeerService(m *seaweedv1.Seaweed, pool volumePool) *corev1.Service {
+	labels := pool.labels(m)
 
 	dep := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      m.Name + "-volume-peer",
+			Name:      pool.peerServiceName(m),
 			Namespace: m.Namespace,
 			Labels:    labels,
 			Annotations: map[string]string{
@@ -45,9 +43,9 @@ func (r *SeaweedReconciler) createVolumeServerPeerService(m *seaweedv1.Seaweed)
 	}
 	return dep
 }
-func (r *SeaweedReconciler) createVolumeServerService(m *seaweedv1.Seaweed, i int) *corev1.Service {
-	labels := labelsForVolumeServer(m.Name)
-	serviceName := fmt.Sprintf("%s-volume-%d", m.Name, i)
+func (r *SeaweedReconciler) createVolumeServerService(m *seaweedv1.Seaweed, pool volumePool, i int) *corev1.Service {
+	labels := pool.labels(m)
+	serviceName := pool.serviceName(m, i)
 	labels[label.PodName] = serviceName
 
 	dep := &corev1.Service{
@@ -79,8 +77,8 @@ func (r *SeaweedReconciler) createVolumeServerService(m *seaweedv1.Seaweed, i in
 		},
 	}
 
-	if m.Spec.Volume.Service != nil {
-		svcSpec := m.Spec.Volume.Service
+	if pool.spec.Service != nil {
+		svcSpec := pool.spec.Service
 		dep.Annotations = copyAnnotations(svcSpec.Annotations)
 
 		if svcSpec.Type != "" {
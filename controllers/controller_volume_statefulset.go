@@ -12,36 +12,64 @@ import (
 	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
 )
 
-func buildVolumeServerStartupScript(m *seaweedv1.Seaweed, dirs []string) string {
+func buildVolumeServerStartupScript(m *seaweedv1.Seaweed, pool volumePool, disks []volumeDisk) string {
+	var dirs, maxVolumes, diskTypes []string
+	for _, disk := range disks {
+		dirs = append(dirs, disk.mountPath)
+		maxVolumes = append(maxVolumes, fmt.Sprintf("%d", disk.maxVolumes))
+		diskTypes = append(diskTypes, disk.diskType)
+	}
+
 	commands := []string{"weed", "-logtostderr=true", "volume"}
 	commands = append(commands, fmt.Sprintf("-port=%d", seaweedv1.VolumeHTTPPort))
-	commands = append(commands, "-max=0")
-	commands = append(commands, fmt.Sprintf("-ip=$(POD_NAME).%s-volume-peer.%s", m.Name, m.Namespace))
+	commands = append(commands, fmt.Sprintf("-max=%s", perDirArgument(maxVolumes)))
+	commands = append(commands, fmt.Sprintf("-ip=$(POD_NAME).%s.%s", pool.peerServiceName(m), m.Namespace))
 	commands = append(commands, fmt.Sprintf("-metricsPort=9999"))
 	if m.Spec.HostSuffix != nil && *m.Spec.HostSuffix != "" {
 		commands = append(commands, fmt.Sprintf("-publicUrl=$(POD_NAME).%s", *m.Spec.HostSuffix))
 	}
 	commands = append(commands, fmt.Sprintf("-mserver=%s", getMasterPeersString(m)))
 	commands = append(commands, fmt.Sprintf("-dir=%s", strings.Join(dirs, ",")))
+	if diskType := perDirArgument(diskTypes); diskType != "" {
+		// an empty entry is a hdd
+		commands = append(commands, fmt.Sprintf("-disk=%s", diskType))
+	}
 
+	if pool.spec.Topology != nil {
+		commands = append(commands, volumeTopologyArgs()...)
+		return volumeTopologyWaitScript() + strings.Join(commands, " ")
+	}
+	if pool.dataCenter != "" {
+		commands = append(commands, fmt.Sprintf("-dataCenter=%s", pool.dataCenter))
+	}
+	if pool.rack != "" {
+		commands = append(commands, fmt.Sprintf("-rack=%s", pool.rack))
+	}
 	return strings.Join(commands, " ")
 }
 
-func (r *SeaweedReconciler) createVolumeServerStatefulSet(m *seaweedv1.Seaweed) *appsv1.StatefulSet {
-	labels := labelsForVolumeServer(m.Name)
-	replicas := int32(m.Spec.Volume.Replicas)
-	rollingUpdatePartition := int32(0)
-	enableServiceLinks := false
-
-	volumeCount := int(m.Spec.VolumeServerDiskCount)
-	volumeRequests := corev1.ResourceList{
-		corev1.ResourceStorage: m.Spec.Volume.Requests[corev1.ResourceStorage],
+// perDirArgument joins the values of all dirs with commas, a single value applies to all dirs
+func perDirArgument(values []string) string {
+	for _, v := range values {
+		if v != values[0] {
+			return strings.Join(values, ",")
+		}
 	}
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
 
-	requestCPU := m.Spec.Volume.Requests[corev1.ResourceCPU]
-	requestMemory := m.Spec.Volume.Requests[corev1.ResourceMemory]
-	limitCPU := m.Spec.Volume.Limits[corev1.ResourceCPU]
-	limitMemory := m.Spec.Volume.Limits[corev1.ResourceMemory]
+func (r *SeaweedReconciler) createVolumeServerStatefulSet(m *seaweedv1.Seaweed, pool volumePool) *appsv1.StatefulSet {
+	labels := pool.labels(m)
+	replicas := int32(pool.spec.Replicas)
+	enableServiceLinks := false
+
+	requestCPU := pool.spec.Requests[corev1.ResourceCPU]
+	requestMemory := pool.spec.Requests[corev1.ResourceMemory]
+	limitCPU := pool.spec.Limits[corev1.ResourceCPU]
+	limitMemory := pool.spec.Limits[corev1.ResourceMemory]
 
 	resources := corev1.ResourceRequirements{}
 
@@ -84,50 +112,59 @@ func (r *SeaweedReconciler) createVolumeServerStatefulSet(m *seaweedv1.Seaweed)
 	var volumeMounts []corev1.VolumeMount
 	var volumes []corev1.Volume
 	var persistentVolumeClaims []corev1.PersistentVolumeClaim
-	var dirs []string
-	for i := 0; i < volumeCount; i++ {
+	disks := pool.disks()
+	for _, disk := range disks {
 		volumeMounts = append(volumeMounts, corev1.VolumeMount{
-			Name:      fmt.Sprintf("mount%d", i),
+			Name:      disk.name,
 			ReadOnly:  false,
-			MountPath: fmt.Sprintf("/data%d/", i),
+			MountPath: disk.mountPath + "/",
 		})
 		volumes = append(volumes, corev1.Volume{
-			Name: fmt.Sprintf("mount%d", i),
+			Name: disk.name,
 			VolumeSource: corev1.VolumeSource{
 				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-					ClaimName: fmt.Sprintf("mount%d", i),
+					ClaimName: disk.name,
 					ReadOnly:  false,
 				},
 			},
 		})
 		persistentVolumeClaims = append(persistentVolumeClaims, corev1.PersistentVolumeClaim{
 			ObjectMeta: metav1.ObjectMeta{
-				Name: fmt.Sprintf("mount%d", i),
+				Name: disk.name,
 			},
 			Spec: corev1.PersistentVolumeClaimSpec{
-				StorageClassName: m.Spec.Volume.StorageClassName,
+				StorageClassName: disk.storageClassName,
 				AccessModes: []corev1.PersistentVolumeAccessMode{
 					corev1.ReadWriteOnce,
 				},
 				Resources: corev1.ResourceRequirements{
-					Requests: volumeRequests,
+					Requests: corev1.ResourceList{
+						corev1.ResourceStorage: disk.size,
+					},
 				},
 			},
 		})
-		dirs = append(dirs, fmt.Sprintf("/data%d", i))
+	}
+	if pool.spec.Topology != nil {
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      volumeTopologyVolumeName,
+			ReadOnly:  true,
+			MountPath: volumeTopologyMountPath,
+		})
+		volumes = append(volumes, volumeTopologyVolume())
 	}
 
-	volumePodSpec := m.BaseVolumeSpec().BuildPodSpec()
+	volumePodSpec := pool.accessor.BuildPodSpec()
 	volumePodSpec.EnableServiceLinks = &enableServiceLinks
 	volumePodSpec.Containers = []corev1.Container{{
 		Name:            "volume",
-		Image:           m.Spec.Image,
-		ImagePullPolicy: m.BaseVolumeSpec().ImagePullPolicy(),
-		Env:             append(m.BaseVolumeSpec().Env(), kubernetesEnvVars...),
+		Image:           pool.accessor.Image(),
+		ImagePullPolicy: pool.accessor.ImagePullPolicy(),
+		Env:             append(pool.accessor.Env(), kubernetesEnvVars...),
 		Command: []string{
 			"/bin/sh",
 			"-ec",
-			buildVolumeServerStartupScript(m, dirs),
+			buildVolumeServerStartupScript(m, pool, disks),
 		},
 		Ports: []corev1.ContainerPort{
 			{
@@ -174,19 +211,14 @@ func (r *SeaweedReconciler) createVolumeServerStatefulSet(m *seaweedv1.Seaweed)
 
 	dep := &appsv1.StatefulSet{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      m.Name + "-volume",
+			Name:      pool.statefulSetName(m),
 			Namespace: m.Namespace,
 		},
 		Spec: appsv1.StatefulSetSpec{
-			ServiceName:         m.Name + "-volume-peer",
+			ServiceName:         pool.peerServiceName(m),
 			PodManagementPolicy: appsv1.ParallelPodManagement,
 			Replicas:            &replicas,
-			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
-				Type: appsv1.RollingUpdateStatefulSetStrategyType,
-				RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{
-					Partition: &rollingUpdatePartition,
-				},
-			},
+			UpdateStrategy:      statefulSetUpdateStrategy(pool.accessor),
 			Selector: &metav1.LabelSelector{
 				MatchLabels: labels,
 			},
@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+func TestVolumeServerStartupScriptDisks(t *testing.T) {
+	ssd := "local-ssd"
+	max := int32(20)
+	m := testSeaweed(1, 1)
+	m.Spec.Volume.Disks = []seaweedv1.VolumeDiskSpec{
+		{Size: resource.MustParse("1Ti")},
+		{Name: "fast", Size: resource.MustParse("100Gi"), StorageClassName: &ssd, MountPath: "/ssd/", DiskType: "ssd", MaxVolumes: &max},
+	}
+
+	pool := defaultVolumePool(m)
+	disks := pool.disks()
+	if len(disks) != 2 || disks[0].name != "mount0" || disks[1].name != "fast" || *disks[1].storageClassName != ssd {
+		t.Fatalf("disks = %+v", disks)
+	}
+
+	script := buildVolumeServerStartupScript(m, pool, disks)
+	for _, arg := range []string{"-dir=/data0,/ssd", "-max=0,20", "-disk=,ssd"} {
+		if !strings.Contains(script, " "+arg) {
+			t.Errorf("%q is missing %s", script, arg)
+		}
+	}
+
+	m.Spec.Volume.Disks = nil
+	m.Spec.VolumeServerDiskCount = 2
+	script = buildVolumeServerStartupScript(m, defaultVolumePool(m), defaultVolumePool(m).disks())
+	if !strings.Contains(script, " -max=0 ") || !strings.Contains(script, " -dir=/data0,/data1") || strings.Contains(script, "-disk=") {
+		t.Errorf("script for identical disks = %q", script)
+	}
+}
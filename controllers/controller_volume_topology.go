@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/controllers/label"
+)
+
+const (
+	// DataCenterAnnotation is set on volume server pods to the data center of their node
+	DataCenterAnnotation = "seaweed.seaweedfs.com/data-center"
+	// RackAnnotation is set on volume server pods to the rack of their node
+	RackAnnotation = "seaweed.seaweedfs.com/rack"
+
+	volumeTopologyVolumeName = "topology"
+	volumeTopologyMountPath  = "/etc/seaweedfs-topology"
+)
+
+// volumeTopologyVolume exposes the topology annotations of the pod as files.
+// The kubelet refreshes the files once the operator has annotated the scheduled pod.
+func volumeTopologyVolume() corev1.Volume {
+	return corev1.Volume{
+		Name: volumeTopologyVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			DownwardAPI: &corev1.DownwardAPIVolumeSource{
+				Items: []corev1.DownwardAPIVolumeFile{
+					{
+						Path:     "data-center",
+						FieldRef: &corev1.ObjectFieldSelector{APIVersion: "v1", FieldPath: fmt.Sprintf("metadata.annotations['%s']", DataCenterAnnotation)},
+					},
+					{
+						Path:     "rack",
+						FieldRef: &corev1.ObjectFieldSelector{APIVersion: "v1", FieldPath: fmt.Sprintf("metadata.annotations['%s']", RackAnnotation)},
+					},
+				},
+			},
+		},
+	}
+}
+
+// volumeTopologyWaitSeconds bounds the wait of a volume server for the topology annotations of its pod
+const volumeTopologyWaitSeconds = 300
+
+// volumeTopologyWaitScript waits until the operator annotated the pod, the volume server must not
+// register in the default data center and rack first. After volumeTopologyWaitSeconds it gives up and starts
+// in the default data center and rack, the server moves to its node topology with its next restart.
+func volumeTopologyWaitScript() string {
+	return fmt.Sprintf("waited=0; until [ -s %[1]s/data-center ] && [ -s %[1]s/rack ]; do "+
+		"if [ $waited -ge %[2]d ]; then echo no node topology after %[2]ds, starting in %[3]s %[4]s; break; fi; "+
+		"echo waiting for the node topology; sleep 2; waited=$((waited+2)); done; "+
+		"DATA_CENTER=$(cat %[1]s/data-center); RACK=$(cat %[1]s/rack); ",
+		volumeTopologyMountPath, volumeTopologyWaitSeconds, seaweedv1.DefaultDataCenter, seaweedv1.DefaultRack)
+}
+
+// volumeTopologyArgs are the weed volume flags of the data center and rack read by volumeTopologyWaitScript
+func volumeTopologyArgs() []string {
+	return []string{
+		fmt.Sprintf("-dataCenter=${DATA_CENTER:-%s}", seaweedv1.DefaultDataCenter),
+		fmt.Sprintf("-rack=${RACK:-%s}", seaweedv1.DefaultRack),
+	}
+}
+
+// ensureVolumeServerTopology annotates the scheduled volume server pods with the data center and rack
+// read from the labels of their nodes, for the pools with a topology
+func (r *SeaweedReconciler) ensureVolumeServerTopology(m *seaweedv1.Seaweed) (bool, ctrl.Result, error) {
+	topologies := make(map[string]*seaweedv1.VolumeTopologySpec)
+	for _, pool := range volumePools(m) {
+		if pool.spec.Topology != nil {
+			topologies[pool.name] = pool.spec.Topology
+		}
+	}
+	if len(topologies) == 0 {
+		return ReconcileResult(nil)
+	}
+	log := r.Log.WithValues("sw-volume-topology", m.Name)
+
+	podList := &corev1.PodList{}
+	listOpts := []client.ListOption{
+		client.InNamespace(m.Namespace),
+		client.MatchingLabels(labelsForVolumeServer(m.Name)),
+	}
+	if err := r.List(context.Background(), podList, listOpts...); err != nil {
+		return ReconcileResult(err)
+	}
+
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		topology := topologies[pod.Labels[label.VolumePoolLabelKey]]
+		if topology == nil || pod.Spec.NodeName == "" || pod.DeletionTimestamp != nil {
+			continue
+		}
+		if pod.Annotations[DataCenterAnnotation] != "" && pod.Annotations[RackAnnotation] != "" {
+			continue
+		}
+
+		node := &corev1.Node{}
+		if err := r.Get(context.Background(), types.NamespacedName{Name: pod.Spec.NodeName}, node); err != nil {
+			return ReconcileResult(err)
+		}
+		dataCenter := node.Labels[topology.GetDataCenterLabel()]
+		if dataCenter == "" {
+			dataCenter = seaweedv1.DefaultDataCenter
+		}
+		rack := node.Labels[topology.GetRackLabel()]
+		if rack == "" {
+			rack = seaweedv1.DefaultRack
+		}
+
+		log.Info("set volume server topology", "pod", pod.Name, "node", node.Name, "dataCenter", dataCenter, "rack", rack)
+		patch := client.MergeFrom(pod.DeepCopy())
+		if pod.Annotations == nil {
+			pod.Annotations = make(map[string]string)
+		}
+		pod.Annotations[DataCenterAnnotation] = dataCenter
+		pod.Annotations[RackAnnotation] = rack
+		if err := r.Patch(context.Background(), pod, patch); err != nil {
+			return ReconcileResult(err)
+		}
+	}
+
+	return ReconcileResult(nil)
+}
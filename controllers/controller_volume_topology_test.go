@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVolumeTopologyWaitScript(t *testing.T) {
+	dir, err := ioutil.TempDir("", "topology")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	run := func(script string) string {
+		script = strings.ReplaceAll(script, volumeTopologyMountPath, dir)
+		out, err := exec.Command("/bin/sh", "-c", script+"echo "+strings.Join(volumeTopologyArgs(), " ")).CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %v", out, err)
+		}
+		return string(out)
+	}
+
+	// the wait gives up at the deadline
+	expired := strings.Replace(volumeTopologyWaitScript(), fmt.Sprintf("-ge %d", volumeTopologyWaitSeconds), "-ge 0", 1)
+	out := run(expired)
+	if !strings.Contains(out, "no node topology after") || !strings.HasSuffix(out, "-dataCenter=DefaultDataCenter -rack=DefaultRack\n") {
+		t.Errorf("output without annotations = %q", out)
+	}
+
+	for file, value := range map[string]string{"data-center": "dc1", "rack": "rack2"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, file), []byte(value), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out := run(volumeTopologyWaitScript()); out != "-dataCenter=dc1 -rack=rack2\n" {
+		t.Errorf("output with annotations = %q", out)
+	}
+}
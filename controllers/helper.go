@@ -3,14 +3,19 @@ package controllers
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
 const (
 	masterPeerAddressPattern = "%s-master-%d.%s-master-peer.%s:9333"
+
+	// swadminTimeout bounds how long a reconcile waits on the masters
+	swadminTimeout = 10 * time.Second
 )
 
 var (
@@ -71,3 +76,17 @@ func copyAnnotations(src map[string]string) map[string]string {
 	}
 	return dst
 }
+
+// statefulSetUpdateStrategy is the update strategy of the component, a RollingUpdate replaces every pod
+func statefulSetUpdateStrategy(accessor seaweedv1.ComponentAccessor) appsv1.StatefulSetUpdateStrategy {
+	if accessor.StatefulSetUpdateStrategy() == appsv1.OnDeleteStatefulSetStrategyType {
+		return appsv1.StatefulSetUpdateStrategy{Type: appsv1.OnDeleteStatefulSetStrategyType}
+	}
+	partition := int32(0)
+	return appsv1.StatefulSetUpdateStrategy{
+		Type: appsv1.RollingUpdateStatefulSetStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{
+			Partition: &partition,
+		},
+	}
+}
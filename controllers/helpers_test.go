@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/controllers/swadmin"
+)
+
+// The unit tests share these fixtures, the Ginkgo suite needs a test environment with etcd and an API server.
+
+// newFakeReconciler is a reconciler on a fake client holding the objects
+func newFakeReconciler(objects ...runtime.Object) *SeaweedReconciler {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = seaweedv1.AddToScheme(scheme)
+	return &SeaweedReconciler{
+		Client:     fake.NewFakeClientWithScheme(scheme, objects...),
+		Log:        ctrl.Log.WithName("test"),
+		Scheme:     scheme,
+		Recorder:   record.NewFakeRecorder(100),
+		AdminTasks: &AdminTaskTracker{},
+	}
+}
+
+// useFakeAdmin makes the reconciler run its admin commands with admin, which takes the master addresses of the cluster
+func useFakeAdmin(r *SeaweedReconciler, admin *swadmin.FakeAdmin) {
+	r.Admins = &swadmin.Cache{NewAdmin: func(target swadmin.Target, options swadmin.Options) swadmin.Admin {
+		admin.MastersAddress = target.Masters
+		return admin
+	}}
+}
+
+// testSeaweed is the Seaweed sw in the default namespace with the masters, and the volume servers with 1Gi of storage
+// if there are any. The tests add the components and settings they cover.
+func testSeaweed(masters, volumeServers int32) *seaweedv1.Seaweed {
+	m := &seaweedv1.Seaweed{
+		ObjectMeta: metav1.ObjectMeta{Name: "sw", Namespace: "default", UID: "sw-uid"},
+		Spec: seaweedv1.SeaweedSpec{
+			Master: &seaweedv1.MasterSpec{Replicas: masters},
+		},
+	}
+	if volumeServers > 0 {
+		m.Spec.Volume = &seaweedv1.VolumeSpec{
+			Replicas:             volumeServers,
+			ResourceRequirements: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")}},
+		}
+	}
+	return m
+}
+
+// testTopology has the volume servers sw-volume-0 and sw-volume-1 holding the volumes
+func testTopology(volumes map[string][]uint32) *swadmin.Topology {
+	rack := swadmin.Rack{ID: "DefaultRack"}
+	for _, server := range []string{"sw-volume-0.sw-volume-peer.default:8444", "sw-volume-1.sw-volume-peer.default:8444"} {
+		disk := swadmin.Disk{Type: "hdd"}
+		for _, vid := range volumes[server] {
+			disk.Volumes = append(disk.Volumes, swadmin.Volume{ID: vid})
+		}
+		rack.Nodes = append(rack.Nodes, swadmin.DataNode{ID: server, Disks: []swadmin.Disk{disk}})
+	}
+	return &swadmin.Topology{
+		DataCenters: []swadmin.DataCenter{{ID: "DefaultDataCenter", Racks: []swadmin.Rack{rack}}},
+	}
+}
+
+// waitForAdminTask waits until the task finished
+func waitForAdminTask(t *testing.T, task *adminTask) {
+	t.Helper()
+	select {
+	case <-task.done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("admin task %s does not finish", task.name)
+	}
+}
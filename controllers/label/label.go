@@ -19,4 +19,11 @@ const (
 	// PodName is to select pod by name
 	// https://kubernetes.io/docs/concepts/workloads/controllers/statefulset/#pod-selector
 	PodName string = "statefulset.kubernetes.io/pod-name"
+
+	// VolumePoolLabelKey is the name of the volume pool of a volume server, it is empty for spec.volume
+	VolumePoolLabelKey string = "seaweed.seaweedfs.com/volume-pool"
+
+	// ConfigHashAnnotationKey is the hash of the config files and Secrets a pod uses, set on the pod template
+	// so that changing them rolls the pods
+	ConfigHashAnnotationKey string = "seaweed.seaweedfs.com/config-hash"
 )
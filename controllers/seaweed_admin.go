@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"fmt"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/controllers/swadmin"
+)
+
+// seaweedAdmin returns the cached admin client of the cluster
+func (r *SeaweedReconciler) seaweedAdmin(m *seaweedv1.Seaweed) swadmin.Admin {
+	return r.Admins.Get(seaweedKey(m).String(), adminTarget(m))
+}
+
+// adminTarget locates the masters of the cluster for the admin client
+func adminTarget(m *seaweedv1.Seaweed) swadmin.Target {
+	target := swadmin.Target{
+		Masters:   getMasterPeersString(m),
+		Namespace: m.Namespace,
+		Container: "master",
+	}
+	for i := int32(0); i < m.Spec.Master.Replicas; i++ {
+		target.Pods = append(target.Pods, fmt.Sprintf("%s-master-%d", m.Name, i))
+	}
+	return target
+}
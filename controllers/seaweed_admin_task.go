@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/types"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+// adminTask is a long running admin job of a cluster, such as draining volume servers.
+// It runs outside of the reconcile loop, and the reconciler polls it on every reconcile.
+type adminTask struct {
+	name      string
+	startTime time.Time
+	cancel    context.CancelFunc
+	done      chan struct{}
+
+	// set once done is closed
+	finishTime time.Time
+	err        error
+}
+
+// finished reports whether the task is over, and its error if so
+func (t *adminTask) finished() (bool, error) {
+	select {
+	case <-t.done:
+		return true, t.err
+	default:
+		return false, nil
+	}
+}
+
+// AdminTaskTracker runs at most one adminTask per cluster. The Seaweed and the SeaweedOperation reconcilers
+// share one, so user operations never run next to drains, balances or maintenance scripts.
+type AdminTaskTracker struct {
+	lock  sync.Mutex
+	tasks map[types.NamespacedName]*adminTask
+}
+
+// current returns the task of the cluster, running or finished, or nil if there is none
+func (t *AdminTaskTracker) current(key types.NamespacedName) *adminTask {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.tasks[key]
+}
+
+// start runs fn in the background as the task of the cluster, timeout bounds the task if not zero.
+// It returns false if another task of the cluster has not been cleared yet.
+func (t *AdminTaskTracker) start(key types.NamespacedName, name string, timeout time.Duration, log logr.Logger, fn func(ctx context.Context) error) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.tasks == nil {
+		t.tasks = make(map[types.NamespacedName]*adminTask)
+	}
+	if _, found := t.tasks[key]; found {
+		return false
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	}
+	task := &adminTask{
+		name:      name,
+		startTime: time.Now(),
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	t.tasks[key] = task
+	log.Info("start admin task", "seaweed", key, "task", name)
+
+	go func() {
+		defer cancel()
+		err := fn(ctx)
+
+		t.lock.Lock()
+		defer t.lock.Unlock()
+		task.finishTime = time.Now()
+		task.err = err
+		close(task.done)
+	}()
+	return true
+}
+
+// clear forgets the finished task of the cluster so that the next one can start,
+// unless another task has replaced it meanwhile
+func (t *AdminTaskTracker) clear(key types.NamespacedName, task *adminTask) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.tasks[key] == task {
+		delete(t.tasks, key)
+	}
+}
+
+// cancel stops the task of the cluster and forgets it
+func (t *AdminTaskTracker) cancel(key types.NamespacedName) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if task, found := t.tasks[key]; found {
+		task.cancel()
+		delete(t.tasks, key)
+	}
+}
+
+// cancelMatching stops and forgets the tasks of the clusters in the namespace whose name matches
+func (t *AdminTaskTracker) cancelMatching(namespace string, match func(name string) bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for key, task := range t.tasks {
+		if key.Namespace == namespace && match(task.name) {
+			task.cancel()
+			delete(t.tasks, key)
+		}
+	}
+}
+
+func seaweedKey(m *seaweedv1.Seaweed) types.NamespacedName {
+	return types.NamespacedName{Namespace: m.Namespace, Name: m.Name}
+}
+
+func (r *SeaweedReconciler) currentAdminTask(m *seaweedv1.Seaweed) *adminTask {
+	return r.AdminTasks.current(seaweedKey(m))
+}
+
+func (r *SeaweedReconciler) startAdminTask(m *seaweedv1.Seaweed, name string, fn func(ctx context.Context) error) bool {
+	return r.AdminTasks.start(seaweedKey(m), name, 0, r.Log, fn)
+}
+
+// clearAdminTask forgets the finished task of the cluster. The task of a SeaweedOperation is left
+// to the SeaweedOperation reconciler, which records its result.
+func (r *SeaweedReconciler) clearAdminTask(m *seaweedv1.Seaweed) {
+	key := seaweedKey(m)
+	if task := r.AdminTasks.current(key); task != nil && !isOperationTask(task.name) {
+		r.AdminTasks.clear(key, task)
+	}
+}
@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+func TestAdminTasksShared(t *testing.T) {
+	m := testSeaweed(1, 1)
+	op := &seaweedv1.SeaweedOperation{ObjectMeta: metav1.ObjectMeta{Name: "fix", Namespace: "default"}}
+	r := newFakeReconciler()
+	ops := &SeaweedOperationReconciler{AdminTasks: r.AdminTasks}
+
+	release := make(chan struct{})
+	if !r.startAdminTask(m, volumeBalanceTaskName, func(ctx context.Context) error {
+		<-release
+		return nil
+	}) {
+		t.Fatalf("balance did not start")
+	}
+	if ops.AdminTasks.start(seaweedKey(m), operationTaskName(op, 1), 0, r.Log, func(ctx context.Context) error { return nil }) {
+		t.Errorf("operation started next to the balance")
+	}
+	close(release)
+	waitForAdminTask(t, r.currentAdminTask(m))
+	r.clearAdminTask(m)
+
+	if !ops.AdminTasks.start(seaweedKey(m), operationTaskName(op, 1), 0, r.Log, func(ctx context.Context) error { return nil }) {
+		t.Fatalf("operation did not start")
+	}
+	task := r.currentAdminTask(m)
+	waitForAdminTask(t, task)
+	// the result of the operation is left to its reconciler
+	r.clearAdminTask(m)
+	if r.currentAdminTask(m) != task {
+		t.Errorf("finished operation cleared by the Seaweed reconciler")
+	}
+	if r.startAdminTask(m, volumeBalanceTaskName, func(ctx context.Context) error { return nil }) {
+		t.Errorf("balance started before the operation was cleared")
+	}
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+func TestValidateConfigs(t *testing.T) {
+	password := &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "redis"}, Key: "password"}
+	for _, tc := range []struct {
+		name        string
+		masterToml  string
+		filerToml   string
+		store       *seaweedv1.FilerStoreSpec
+		maintenance *seaweedv1.MaintenanceSpec
+		want        string
+	}{
+		{name: "valid", filerToml: "[leveldb2]\nenabled = true\ndir = \"/data/filerldb2\"\n"},
+		{name: "master syntax", masterToml: "[master.maintenance\n", want: "spec.master.config line 1, column"},
+		{name: "filer syntax", filerToml: "[leveldb2]\nenabled = \"yes\n", want: "spec.filer.config line 2, column 12: unescaped control character"},
+		{name: "unknown store", filerToml: "[leveldb9]\nenabled = true\n", want: "spec.filer.config line 2, column 1: unknown filer store leveldb9"},
+		{
+			name:      "two stores",
+			filerToml: "[leveldb2]\nenabled = true\n\n[redis2]\nenabled = true\n",
+			want:      "spec.filer.config line 5, column 1: enables more than one filer store: leveldb2, redis2",
+		},
+		{
+			name:      "store next to the typed one",
+			filerToml: "[leveldb2]\nenabled = true\n",
+			store:     &seaweedv1.FilerStoreSpec{Redis: &seaweedv1.RedisStoreSpec{Address: "redis:6379"}},
+			want:      "enables leveldb2 next to redis2 of spec.filer.store",
+		},
+		{
+			name:      "option from a Secret",
+			filerToml: "[redis2]\npassword = \"plain\"\n",
+			store:     &seaweedv1.FilerStoreSpec{Redis: &seaweedv1.RedisStoreSpec{Address: "redis:6379", PasswordSecretRef: password}},
+			want:      "spec.filer.config line 2, column 1: redis2.password is overridden",
+		},
+		{
+			name:      "typed redis address",
+			filerToml: "[redis2]\naddress = \"other:6379\"\n",
+			store:     &seaweedv1.FilerStoreSpec{Redis: &seaweedv1.RedisStoreSpec{Address: "redis:6379"}},
+			want:      "spec.filer.config line 2, column 1: redis2.address is rendered from spec.filer.store",
+		},
+		{
+			name:      "typed postgres database",
+			filerToml: "[postgres]\nenabled = true\nhostname = \"db\"\nport = 5433\ndatabase = \"other\"\n",
+			store:     &seaweedv1.FilerStoreSpec{Postgres: &seaweedv1.PostgresStoreSpec{Hostname: "postgres"}},
+			want:      "spec.filer.config line 5, column 1: postgres.database is rendered from spec.filer.store",
+		},
+		{
+			name:      "leveldb2 dir of the storage",
+			filerToml: "[leveldb2]\ndir = \"/tmp/filerldb2\"\n",
+			store:     &seaweedv1.FilerStoreSpec{LevelDB2: &seaweedv1.LevelDB2StoreSpec{}},
+			want:      "spec.filer.config line 2, column 1: leveldb2.dir is rendered from spec.filer.store",
+		},
+		{
+			name:      "nested elastic option",
+			filerToml: "[elastic7.index]\nmax_result_window = 100\n",
+			store:     &seaweedv1.FilerStoreSpec{Elastic: &seaweedv1.ElasticStoreSpec{Servers: []string{"http://elastic:9200"}}},
+			want:      "spec.filer.config line 2, column 1: elastic7.index.max_result_window is rendered from spec.filer.store",
+		},
+		{
+			name:      "store option without a typed field",
+			filerToml: "[redis2]\nenabled = true\n\n[filer.options]\nrecursive_delete = false\n",
+			store:     &seaweedv1.FilerStoreSpec{Redis: &seaweedv1.RedisStoreSpec{Address: "redis:6379"}},
+		},
+		{
+			name:        "maintenance scripts",
+			masterToml:  "[master.maintenance]\nscripts = \"volume.balance -force\"\n",
+			maintenance: &seaweedv1.MaintenanceSpec{Scripts: []seaweedv1.MaintenanceScript{{Name: "balance", Schedule: "@daily", Commands: []string{"volume.balance -force"}}}},
+			want:        "spec.master.config line 2, column 1: master.maintenance.scripts conflicts with spec.maintenance",
+		},
+	} {
+		m := testSeaweed(1, 1)
+		m.Spec.Master.Config = &tc.masterToml
+		m.Spec.Filer = &seaweedv1.FilerSpec{Replicas: 1, Config: &tc.filerToml, Store: tc.store}
+		m.Spec.Maintenance = tc.maintenance
+		err := m.ValidateCreate()
+		switch {
+		case tc.want == "" && err != nil:
+			t.Errorf("%s: %v", tc.name, err)
+		case tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)):
+			t.Errorf("%s: error %v, want %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestValidateUpdateChangedSpec(t *testing.T) {
+	// the spec was valid when created, a later operator version refuses it
+	filerToml := "[leveldb2]\nenabled = true\n\n[redis2]\nenabled = true\n"
+	old := testSeaweed(1, 1)
+	old.Spec.Filer = &seaweedv1.FilerSpec{Replicas: 1, Config: &filerToml}
+
+	updated := old.DeepCopy()
+	updated.Finalizers = []string{SeaweedFinalizer}
+	if err := updated.ValidateUpdate(old); err != nil {
+		t.Errorf("finalizer update refused: %v", err)
+	}
+
+	updated = old.DeepCopy()
+	now := metav1.Now()
+	updated.DeletionTimestamp = &now
+	updated.Spec.Volume.Replicas = 2
+	if err := updated.ValidateUpdate(old); err != nil {
+		t.Errorf("update during deletion refused: %v", err)
+	}
+
+	updated = old.DeepCopy()
+	updated.Spec.Volume.Replicas = 2
+	if err := updated.ValidateUpdate(old); err == nil || !strings.Contains(err.Error(), "enables more than one filer store") {
+		t.Errorf("spec update: %v", err)
+	}
+}
@@ -88,6 +88,17 @@ var _ = Describe("Seaweed Controller", func() {
 				}, timeout, interval).Should(BeTrue())
 				Expect(filerSts.Spec.Replicas).ShouldNot(BeNil())
 				Expect(*filerSts.Spec.Replicas).Should(Equal(seaweed.Spec.Filer.Replicas))
+
+				Eventually(func() seaweedv1.SeaweedPhase {
+					observed := &seaweedv1.Seaweed{}
+					if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, observed); err != nil {
+						return ""
+					}
+					if observed.Status.Master.Replicas != seaweed.Spec.Master.Replicas {
+						return ""
+					}
+					return observed.Status.Phase
+				}, timeout, interval).Should(Equal(seaweedv1.SeaweedPhaseCreating))
 			})
 		})
 	})
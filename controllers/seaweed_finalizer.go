@@ -0,0 +1,380 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+const (
+	// SeaweedFinalizer holds the deletion of a Seaweed until its DeletionPolicy has been applied
+	SeaweedFinalizer = "seaweed.seaweedfs.com/finalizer"
+)
+
+var (
+	volumeSnapshotGVK = schema.GroupVersionKind{
+		Group:   "snapshot.storage.k8s.io",
+		Version: "v1",
+		Kind:    "VolumeSnapshot",
+	}
+)
+
+func (r *SeaweedReconciler) ensureSeaweedFinalizer(seaweedCR *seaweedv1.Seaweed) (bool, ctrl.Result, error) {
+	if hasFinalizer(seaweedCR, SeaweedFinalizer) {
+		return ReconcileResult(nil)
+	}
+
+	controllerutil.AddFinalizer(seaweedCR, SeaweedFinalizer)
+	return ReconcileResult(r.Update(context.Background(), seaweedCR))
+}
+
+// finalizeSeaweed applies the deletion policy and removes the finalizer once the policy is fully applied
+func (r *SeaweedReconciler) finalizeSeaweed(seaweedCR *seaweedv1.Seaweed) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("sw-finalizer", seaweedCR.Name)
+
+	if !hasFinalizer(seaweedCR, SeaweedFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	policy := seaweedCR.Spec.DeletionPolicy
+	if policy == "" {
+		policy = seaweedv1.DeletionPolicyRetain
+	}
+
+	status := &seaweedCR.Status
+	status.Phase = seaweedv1.SeaweedPhaseDeleting
+	if status.Deletion == nil || status.Deletion.Policy != policy {
+		now := metav1.Now()
+		status.Deletion = &seaweedv1.DeletionStatus{
+			Policy:    policy,
+			StartTime: &now,
+		}
+	}
+
+	var finished bool
+	var err error
+	switch policy {
+	case seaweedv1.DeletionPolicyDelete:
+		finished, err = r.applyDeletePolicy(seaweedCR, log)
+	case seaweedv1.DeletionPolicySnapshot:
+		finished, err = r.applySnapshotPolicy(seaweedCR, log)
+	default:
+		finished, err = r.applyRetainPolicy(seaweedCR, log)
+	}
+	if err != nil {
+		status.Deletion.Message = err.Error()
+	}
+
+	if statusErr := r.Status().Update(ctx, seaweedCR); statusErr != nil {
+		log.Error(statusErr, "Failed to update deletion status")
+	}
+
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !finished {
+		return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
+	}
+
+	log.Info("deletion policy applied", "policy", policy)
+	r.AdminTasks.cancel(seaweedKey(seaweedCR))
+	r.health.forget(seaweedKey(seaweedCR))
+	r.Admins.Remove(seaweedKey(seaweedCR).String())
+	controllerutil.RemoveFinalizer(seaweedCR, SeaweedFinalizer)
+	return ctrl.Result{}, r.Update(ctx, seaweedCR)
+}
+
+// applyRetainPolicy keeps the PVCs, pins their PVs and orphans the generated Secrets and ConfigMaps
+func (r *SeaweedReconciler) applyRetainPolicy(seaweedCR *seaweedv1.Seaweed, log logr.Logger) (bool, error) {
+	pvcs, err := r.listSeaweedPersistentVolumeClaims(seaweedCR)
+	if err != nil {
+		return false, err
+	}
+
+	for i := range pvcs {
+		if err := r.setPVReclaimPolicy(&pvcs[i], corev1.PersistentVolumeReclaimRetain); err != nil {
+			return false, err
+		}
+	}
+
+	if err := r.orphanGeneratedObjects(seaweedCR); err != nil {
+		return false, err
+	}
+
+	log.Info("retained persistent volume claims", "count", len(pvcs))
+	seaweedCR.Status.Deletion.PendingPersistentVolumeClaims = nil
+	seaweedCR.Status.Deletion.Message = fmt.Sprintf("retained %d persistent volume claims", len(pvcs))
+	return true, nil
+}
+
+// applyDeletePolicy removes the workloads, then the PVCs together with their PVs, then the generated Secrets and ConfigMaps
+func (r *SeaweedReconciler) applyDeletePolicy(seaweedCR *seaweedv1.Seaweed, log logr.Logger) (bool, error) {
+	if err := r.deleteSeaweedStatefulSets(seaweedCR); err != nil {
+		return false, err
+	}
+
+	pending, err := r.deletePersistentVolumeClaims(seaweedCR, log)
+	if err != nil {
+		return false, err
+	}
+	seaweedCR.Status.Deletion.PendingPersistentVolumeClaims = pending
+	if len(pending) > 0 {
+		seaweedCR.Status.Deletion.Message = fmt.Sprintf("waiting for %d persistent volume claims to be deleted", len(pending))
+		return false, nil
+	}
+
+	if err := r.deleteGeneratedObjects(seaweedCR); err != nil {
+		return false, err
+	}
+
+	seaweedCR.Status.Deletion.Message = "deleted persistent volume claims and generated objects"
+	return true, nil
+}
+
+// applySnapshotPolicy stops the workloads, snapshots every PVC, and deletes the PVCs once all snapshots are ready.
+// The generated Secrets and ConfigMaps are orphaned so the cluster can be restored from the snapshots.
+func (r *SeaweedReconciler) applySnapshotPolicy(seaweedCR *seaweedv1.Seaweed, log logr.Logger) (bool, error) {
+	deletion := seaweedCR.Status.Deletion
+
+	if err := r.deleteSeaweedStatefulSets(seaweedCR); err != nil {
+		return false, err
+	}
+
+	podList := &corev1.PodList{}
+	listOpts := []client.ListOption{
+		client.InNamespace(seaweedCR.Namespace),
+		client.MatchingLabels(labelsForSeaweed(seaweedCR.Name)),
+	}
+	if err := r.List(context.Background(), podList, listOpts...); err != nil {
+		return false, err
+	}
+	if len(podList.Items) > 0 {
+		deletion.Message = fmt.Sprintf("waiting for %d pods to terminate before taking snapshots", len(podList.Items))
+		return false, nil
+	}
+
+	pvcs, err := r.listSeaweedPersistentVolumeClaims(seaweedCR)
+	if err != nil {
+		return false, err
+	}
+
+	var notReady []string
+	for i := range pvcs {
+		pvc := &pvcs[i]
+		if pvc.DeletionTimestamp != nil {
+			continue
+		}
+		name, ready, err := r.ensureVolumeSnapshot(seaweedCR, pvc)
+		if err != nil {
+			return false, err
+		}
+		if !containsString(deletion.Snapshots, name) {
+			deletion.Snapshots = append(deletion.Snapshots, name)
+		}
+		if !ready {
+			notReady = append(notReady, pvc.Name)
+		}
+	}
+	if len(notReady) > 0 {
+		deletion.PendingPersistentVolumeClaims = notReady
+		deletion.Message = fmt.Sprintf("waiting for %d volume snapshots to be ready", len(notReady))
+		return false, nil
+	}
+
+	pending, err := r.deletePersistentVolumeClaims(seaweedCR, log)
+	if err != nil {
+		return false, err
+	}
+	deletion.PendingPersistentVolumeClaims = pending
+	if len(pending) > 0 {
+		deletion.Message = fmt.Sprintf("waiting for %d persistent volume claims to be deleted", len(pending))
+		return false, nil
+	}
+
+	if err := r.orphanGeneratedObjects(seaweedCR); err != nil {
+		return false, err
+	}
+
+	deletion.Message = fmt.Sprintf("took %d volume snapshots and deleted persistent volume claims", len(deletion.Snapshots))
+	return true, nil
+}
+
+// ensureVolumeSnapshot creates the VolumeSnapshot of a PVC if missing, and reports whether it is ready to use
+func (r *SeaweedReconciler) ensureVolumeSnapshot(seaweedCR *seaweedv1.Seaweed, pvc *corev1.PersistentVolumeClaim) (string, bool, error) {
+	ctx := context.Background()
+	// the uid suffix keeps snapshots of a previous cluster with the same name apart
+	name := fmt.Sprintf("%s-%s", pvc.Name, string(seaweedCR.UID)[:8])
+
+	snapshot := &unstructured.Unstructured{}
+	snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+	err := r.Get(ctx, types.NamespacedName{Namespace: pvc.Namespace, Name: name}, snapshot)
+	if errors.IsNotFound(err) {
+		snapshot.SetName(name)
+		snapshot.SetNamespace(pvc.Namespace)
+		snapshot.SetLabels(labelsForSeaweed(seaweedCR.Name))
+		source := map[string]interface{}{
+			"persistentVolumeClaimName": pvc.Name,
+		}
+		if err := unstructured.SetNestedMap(snapshot.Object, source, "spec", "source"); err != nil {
+			return name, false, err
+		}
+		if seaweedCR.Spec.VolumeSnapshotClassName != nil {
+			if err := unstructured.SetNestedField(snapshot.Object, *seaweedCR.Spec.VolumeSnapshotClassName, "spec", "volumeSnapshotClassName"); err != nil {
+				return name, false, err
+			}
+		}
+		r.Log.Info("create volume snapshot", "snapshot", name, "pvc", pvc.Name)
+		return name, false, r.Create(ctx, snapshot)
+	}
+	if err != nil {
+		return name, false, err
+	}
+
+	if message, found, _ := unstructured.NestedString(snapshot.Object, "status", "error", "message"); found {
+		return name, false, fmt.Errorf("volume snapshot %s: %s", name, message)
+	}
+	ready, _, _ := unstructured.NestedBool(snapshot.Object, "status", "readyToUse")
+	return name, ready, nil
+}
+
+// deletePersistentVolumeClaims deletes the PVCs with their PVs and returns the PVCs which still exist
+func (r *SeaweedReconciler) deletePersistentVolumeClaims(seaweedCR *seaweedv1.Seaweed, log logr.Logger) ([]string, error) {
+	pvcs, err := r.listSeaweedPersistentVolumeClaims(seaweedCR)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []string
+	for i := range pvcs {
+		pvc := &pvcs[i]
+		pending = append(pending, pvc.Name)
+		if pvc.DeletionTimestamp != nil {
+			continue
+		}
+		if err := r.setPVReclaimPolicy(pvc, corev1.PersistentVolumeReclaimDelete); err != nil {
+			return nil, err
+		}
+		log.Info("delete persistent volume claim", "pvc", pvc.Name)
+		if err := r.Delete(context.Background(), pvc); err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+	return pending, nil
+}
+
+// deleteSeaweedStatefulSets deletes the StatefulSets so that their pods release the PVCs
+func (r *SeaweedReconciler) deleteSeaweedStatefulSets(seaweedCR *seaweedv1.Seaweed) error {
+	names := []string{seaweedCR.Name + "-master", seaweedCR.Name + "-filer"}
+	for _, pool := range volumePools(seaweedCR) {
+		names = append(names, pool.statefulSetName(seaweedCR))
+	}
+	for _, name := range names {
+		statefulSet := &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: seaweedCR.Namespace,
+			},
+		}
+		err := r.Delete(context.Background(), statefulSet, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// orphanGeneratedObjects removes the owner reference from the generated Secrets and ConfigMaps
+// so that the garbage collector keeps them after the Seaweed is gone
+func (r *SeaweedReconciler) orphanGeneratedObjects(seaweedCR *seaweedv1.Seaweed) error {
+	objects, err := r.listGeneratedObjects(seaweedCR)
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range objects {
+		meta := obj.(metav1.Object)
+		var refs []metav1.OwnerReference
+		for _, ref := range meta.GetOwnerReferences() {
+			if ref.UID != seaweedCR.UID {
+				refs = append(refs, ref)
+			}
+		}
+		if len(refs) == len(meta.GetOwnerReferences()) {
+			continue
+		}
+		meta.SetOwnerReferences(refs)
+		if err := r.Update(context.Background(), obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *SeaweedReconciler) deleteGeneratedObjects(seaweedCR *seaweedv1.Seaweed) error {
+	objects, err := r.listGeneratedObjects(seaweedCR)
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range objects {
+		if err := r.Delete(context.Background(), obj); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// listGeneratedObjects lists the Secrets and ConfigMaps the operator created for the cluster
+func (r *SeaweedReconciler) listGeneratedObjects(seaweedCR *seaweedv1.Seaweed) ([]runtime.Object, error) {
+	listOpts := []client.ListOption{
+		client.InNamespace(seaweedCR.Namespace),
+		client.MatchingLabels(labelsForSeaweed(seaweedCR.Name)),
+	}
+
+	configMapList := &corev1.ConfigMapList{}
+	if err := r.List(context.Background(), configMapList, listOpts...); err != nil {
+		return nil, err
+	}
+	secretList := &corev1.SecretList{}
+	if err := r.List(context.Background(), secretList, listOpts...); err != nil {
+		return nil, err
+	}
+
+	var objects []runtime.Object
+	for i := range configMapList.Items {
+		objects = append(objects, &configMapList.Items[i])
+	}
+	for i := range secretList.Items {
+		objects = append(objects, &secretList.Items[i])
+	}
+	return objects, nil
+}
+
+func hasFinalizer(obj metav1.Object, finalizer string) bool {
+	return containsString(obj.GetFinalizers(), finalizer)
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
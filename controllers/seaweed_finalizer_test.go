@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/controllers/swadmin"
+)
+
+// newFinalizerReconciler is a reconciler of the deleted Seaweed sw with the policy, holding its volume server StatefulSet,
+// the PVC mount0-sw-volume-0 bound to the PV pv-0, a generated ConfigMap, and the PVC of another cluster
+func newFinalizerReconciler(policy seaweedv1.DeletionPolicy, objects ...runtime.Object) (*SeaweedReconciler, *seaweedv1.Seaweed) {
+	m := testSeaweed(1, 1)
+	m.UID = "0123456789abcdef"
+	m.Spec.DeletionPolicy = policy
+	m.Finalizers = []string{SeaweedFinalizer}
+	now := metav1.Now()
+	m.DeletionTimestamp = &now
+
+	owner := metav1.OwnerReference{APIVersion: "seaweed.seaweedfs.com/v1", Kind: "Seaweed", Name: "sw", UID: m.UID}
+	objects = append(objects,
+		m,
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "sw-volume", Namespace: "default", Labels: labelsForVolumeServer("sw")}},
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "mount0-sw-volume-0", Namespace: "default", Labels: labelsForVolumeServer("sw")},
+			Spec:       corev1.PersistentVolumeClaimSpec{VolumeName: "pv-0"},
+		},
+		&corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-0"},
+			Spec:       corev1.PersistentVolumeSpec{PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimDelete},
+		},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "sw-filer", Namespace: "default", Labels: labelsForSeaweed("sw"), OwnerReferences: []metav1.OwnerReference{owner}}},
+		&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "mount0-other-volume-0", Namespace: "default", Labels: labelsForVolumeServer("other")}},
+	)
+	r := newFakeReconciler(objects...)
+	useFakeAdmin(r, swadmin.NewFakeAdmin(""))
+	return r, m
+}
+
+// finalize applies the deletion policy once and reports whether the finalizer was removed
+func finalize(t *testing.T, r *SeaweedReconciler, m *seaweedv1.Seaweed) bool {
+	t.Helper()
+	if _, err := r.finalizeSeaweed(m); err != nil {
+		t.Fatalf("finalize: %v", err)
+	}
+	stored := &seaweedv1.Seaweed{}
+	if err := r.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "sw"}, stored); err != nil {
+		t.Fatalf("get seaweed: %v", err)
+	}
+	return !hasFinalizer(stored, SeaweedFinalizer)
+}
+
+func exists(t *testing.T, r *SeaweedReconciler, name string, obj runtime.Object) bool {
+	t.Helper()
+	err := r.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, obj)
+	if err != nil && !errors.IsNotFound(err) {
+		t.Fatalf("get %s: %v", name, err)
+	}
+	return err == nil
+}
+
+func TestFinalizeSeaweedRetain(t *testing.T) {
+	r, m := newFinalizerReconciler(seaweedv1.DeletionPolicyRetain)
+
+	if !finalize(t, r, m) {
+		t.Fatalf("finalizer kept: %+v", m.Status.Deletion)
+	}
+	if !exists(t, r, "mount0-sw-volume-0", &corev1.PersistentVolumeClaim{}) || !exists(t, r, "mount0-other-volume-0", &corev1.PersistentVolumeClaim{}) {
+		t.Errorf("PVCs deleted")
+	}
+	pv := &corev1.PersistentVolume{}
+	if err := r.Get(context.Background(), types.NamespacedName{Name: "pv-0"}, pv); err != nil || pv.Spec.PersistentVolumeReclaimPolicy != corev1.PersistentVolumeReclaimRetain {
+		t.Errorf("PV reclaim policy = %s, %v, want Retain", pv.Spec.PersistentVolumeReclaimPolicy, err)
+	}
+	configMap := &corev1.ConfigMap{}
+	if !exists(t, r, "sw-filer", configMap) || len(configMap.OwnerReferences) != 0 {
+		t.Errorf("generated ConfigMap not orphaned: %+v", configMap.ObjectMeta)
+	}
+}
+
+func TestFinalizeSeaweedDelete(t *testing.T) {
+	r, m := newFinalizerReconciler(seaweedv1.DeletionPolicyDelete)
+
+	// the PVCs are deleted first, the generated objects once the PVCs are gone
+	if finalize(t, r, m) {
+		t.Fatalf("finalizer removed while the PVCs are deleted")
+	}
+	if exists(t, r, "sw-volume", &appsv1.StatefulSet{}) {
+		t.Errorf("StatefulSet kept")
+	}
+	if exists(t, r, "mount0-sw-volume-0", &corev1.PersistentVolumeClaim{}) {
+		t.Errorf("PVC of the cluster kept")
+	}
+	pv := &corev1.PersistentVolume{}
+	if err := r.Get(context.Background(), types.NamespacedName{Name: "pv-0"}, pv); err != nil || pv.Spec.PersistentVolumeReclaimPolicy != corev1.PersistentVolumeReclaimDelete {
+		t.Errorf("PV reclaim policy = %s, %v, want Delete", pv.Spec.PersistentVolumeReclaimPolicy, err)
+	}
+	if !exists(t, r, "sw-filer", &corev1.ConfigMap{}) {
+		t.Errorf("generated ConfigMap deleted before the PVCs are gone")
+	}
+
+	if !finalize(t, r, m) {
+		t.Fatalf("finalizer kept: %+v", m.Status.Deletion)
+	}
+	if exists(t, r, "sw-filer", &corev1.ConfigMap{}) {
+		t.Errorf("generated ConfigMap kept")
+	}
+	if !exists(t, r, "mount0-other-volume-0", &corev1.PersistentVolumeClaim{}) {
+		t.Errorf("PVC of another cluster deleted")
+	}
+}
+
+func TestFinalizeSeaweedSnapshot(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "sw-volume-0", Namespace: "default", Labels: labelsForVolumeServer("sw")}}
+	r, m := newFinalizerReconciler(seaweedv1.DeletionPolicySnapshot, pod)
+	snapshot := &unstructured.Unstructured{}
+	snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+	snapshotName := "mount0-sw-volume-0-01234567"
+
+	// the snapshots are taken once the pods are gone
+	if finalize(t, r, m) || exists(t, r, snapshotName, snapshot) {
+		t.Fatalf("snapshot taken while the pods run")
+	}
+	if err := r.Delete(context.Background(), pod); err != nil {
+		t.Fatal(err)
+	}
+
+	if finalize(t, r, m) {
+		t.Fatalf("finalizer removed before the snapshot is ready")
+	}
+	if !exists(t, r, snapshotName, snapshot) {
+		t.Fatalf("snapshot not created: %+v", m.Status.Deletion)
+	}
+	if source, _, _ := unstructured.NestedString(snapshot.Object, "spec", "source", "persistentVolumeClaimName"); source != "mount0-sw-volume-0" {
+		t.Errorf("snapshot source = %q", source)
+	}
+	if finalize(t, r, m) || !exists(t, r, "mount0-sw-volume-0", &corev1.PersistentVolumeClaim{}) {
+		t.Fatalf("PVC deleted before the snapshot is ready")
+	}
+
+	if err := unstructured.SetNestedField(snapshot.Object, true, "status", "readyToUse"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Update(context.Background(), snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if finalize(t, r, m) {
+		t.Fatalf("finalizer removed while the PVCs are deleted")
+	}
+	if exists(t, r, "mount0-sw-volume-0", &corev1.PersistentVolumeClaim{}) {
+		t.Errorf("PVC kept after the snapshot is ready")
+	}
+
+	if !finalize(t, r, m) {
+		t.Fatalf("finalizer kept: %+v", m.Status.Deletion)
+	}
+	if !exists(t, r, snapshotName, snapshot) || len(m.Status.Deletion.Snapshots) != 1 {
+		t.Errorf("snapshots = %v", m.Status.Deletion.Snapshots)
+	}
+	configMap := &corev1.ConfigMap{}
+	if !exists(t, r, "sw-filer", configMap) || len(configMap.OwnerReferences) != 0 {
+		t.Errorf("generated ConfigMap not orphaned: %+v", configMap.ObjectMeta)
+	}
+	if !exists(t, r, "mount0-other-volume-0", &corev1.PersistentVolumeClaim{}) {
+		t.Errorf("PVC of another cluster deleted")
+	}
+}
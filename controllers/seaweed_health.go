@@ -0,0 +1,254 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/controllers/swadmin"
+)
+
+// clusterHealthInterval is how often the masters are queried for the cluster health
+const clusterHealthInterval = 30 * time.Second
+
+// clusterHealthTimeout bounds one query of the masters
+const clusterHealthTimeout = 5 * time.Second
+
+// clusterHealthProbe is a query of the masters running in the background
+type clusterHealthProbe struct {
+	startTime time.Time
+	done      chan struct{}
+
+	// set once done is closed
+	health *seaweedv1.ClusterHealth
+}
+
+// clusterHealthTracker queries the masters of every cluster in the background, at most once per clusterHealthInterval
+type clusterHealthTracker struct {
+	lock   sync.Mutex
+	probes map[types.NamespacedName]*clusterHealthProbe
+}
+
+// poll returns the health of the last finished probe of the cluster, nil if there is none yet.
+// It starts the next probe with collect once neither the last probe nor lastProbeTime is within clusterHealthInterval.
+func (t *clusterHealthTracker) poll(key types.NamespacedName, lastProbeTime *metav1.Time, collect func(ctx context.Context) *seaweedv1.ClusterHealth) *seaweedv1.ClusterHealth {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.probes == nil {
+		t.probes = make(map[types.NamespacedName]*clusterHealthProbe)
+	}
+	var health *seaweedv1.ClusterHealth
+	probe := t.probes[key]
+	if probe != nil {
+		select {
+		case <-probe.done:
+			health = probe.health
+		default:
+			return nil
+		}
+	}
+	if probe != nil && time.Since(probe.startTime) < clusterHealthInterval ||
+		lastProbeTime != nil && time.Since(lastProbeTime.Time) < clusterHealthInterval {
+		return health
+	}
+
+	next := &clusterHealthProbe{startTime: time.Now(), done: make(chan struct{})}
+	t.probes[key] = next
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), clusterHealthTimeout)
+		defer cancel()
+		health := collect(ctx)
+
+		t.lock.Lock()
+		defer t.lock.Unlock()
+		next.health = health
+		close(next.done)
+	}()
+	return health
+}
+
+// forget drops the probes of a deleted cluster, a running probe finishes unobserved
+func (t *clusterHealthTracker) forget(key types.NamespacedName) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.probes, key)
+}
+
+// updateClusterHealth queries the masters through the admin for the raft leader and the heartbeating volume servers,
+// in the background and at most once per clusterHealthInterval, and sets the Degraded condition from the last result
+func (r *SeaweedReconciler) updateClusterHealth(m *seaweedv1.Seaweed) {
+	var lastProbeTime *metav1.Time
+	if m.Status.Health != nil {
+		lastProbeTime = m.Status.Health.LastProbeTime
+	}
+	cluster := m.DeepCopy()
+	probed := r.health.poll(seaweedKey(m), lastProbeTime, func(ctx context.Context) *seaweedv1.ClusterHealth {
+		return collectClusterHealth(ctx, cluster, r.seaweedAdmin(cluster))
+	})
+	if probed == nil {
+		return
+	}
+	health := probed.DeepCopy()
+	m.Status.Health = health
+
+	wasDegraded := m.Status.IsConditionTrue(seaweedv1.Degraded)
+	condition := degradedCondition(m, health)
+	m.Status.SetCondition(condition)
+	if condition.Status == corev1.ConditionTrue && !wasDegraded {
+		r.Recorder.Event(m, corev1.EventTypeWarning, condition.Reason, condition.Message)
+	}
+
+	if m.Spec.Master.DefaultReplication == nil {
+		m.Status.RemoveCondition(seaweedv1.ReplicationSatisfiable)
+	} else if len(health.VolumeServers) > 0 {
+		m.Status.SetCondition(replicationCondition(m, *m.Spec.Master.DefaultReplication, health))
+	}
+}
+
+// collectClusterHealth asks every master for its raft state, then asks the leader for the topology
+func collectClusterHealth(ctx context.Context, m *seaweedv1.Seaweed, admin swadmin.Admin) *seaweedv1.ClusterHealth {
+	now := metav1.Now()
+	health := &seaweedv1.ClusterHealth{LastProbeTime: &now}
+
+	masters := strings.Split(admin.Masters(), ",")
+	statuses, errs := admin.ClusterStatus(ctx)
+	for i, status := range statuses {
+		if status == nil {
+			continue
+		}
+		health.RespondingMasters++
+		if status.IsLeader {
+			health.Leader = masters[i]
+			health.Peers = status.Peers
+			health.MaxVolumeID = status.MaxVolumeID
+		} else if health.Leader == "" {
+			health.Leader = status.Leader
+		}
+	}
+	if health.RespondingMasters == 0 {
+		health.Message = fmt.Sprintf("no master responded: %v", errs[0])
+		return health
+	}
+	if health.Leader == "" {
+		health.Message = "the masters have not elected a leader"
+		return health
+	}
+
+	topology, err := admin.VolumeList(ctx)
+	if err != nil {
+		health.Message = fmt.Sprintf("can not read the topology from the leader: %v", err)
+		return health
+	}
+
+	registered := make(map[string]bool)
+	for _, dc := range topology.DataCenters {
+		capacity := seaweedv1.DataCenterCapacity{Name: dc.ID}
+		for _, rack := range dc.Racks {
+			for _, dn := range rack.Nodes {
+				server := seaweedv1.VolumeServerHealth{Address: dn.ID, DataCenter: dc.ID, Rack: rack.ID}
+				for _, disk := range dn.Disks {
+					server.Volumes += disk.VolumeCount
+					server.MaxVolumes += disk.MaxVolumeCount
+					for _, shards := range disk.ECShards {
+						server.EcShards += int64(len(shards.ShardIDs))
+					}
+					health.FreeVolumeSlots += disk.FreeVolumeCount
+				}
+				registered[dn.ID] = true
+				health.VolumeServers = append(health.VolumeServers, server)
+				health.VolumeSlots += server.MaxVolumes
+				capacity.VolumeServers++
+				capacity.Volumes += server.Volumes
+				capacity.VolumeSlots += server.MaxVolumes
+			}
+		}
+		health.DataCenters = append(health.DataCenters, capacity)
+	}
+
+	for _, pool := range volumePools(m) {
+		for i := int32(0); i < pool.spec.Replicas; i++ {
+			if server := pool.serverAddress(m, i); !registered[server] {
+				health.MissingVolumeServers = append(health.MissingVolumeServers, server)
+			}
+		}
+	}
+	return health
+}
+
+// degradedCondition is True when there is no leader or volume servers are missing,
+// and Unknown when the masters could not be queried
+func degradedCondition(m *seaweedv1.Seaweed, health *seaweedv1.ClusterHealth) seaweedv1.SeaweedCondition {
+	condition := seaweedv1.SeaweedCondition{
+		Type:               seaweedv1.Degraded,
+		Status:             corev1.ConditionFalse,
+		ObservedGeneration: m.Generation,
+		Reason:             "Healthy",
+		Message:            fmt.Sprintf("leader %s, %d volume servers heartbeating", health.Leader, len(health.VolumeServers)),
+	}
+	switch {
+	case health.RespondingMasters == 0:
+		condition.Status = corev1.ConditionUnknown
+		condition.Reason = "MastersUnreachable"
+		condition.Message = health.Message
+	case health.Leader == "":
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = "NoLeader"
+		condition.Message = health.Message
+	case len(health.MissingVolumeServers) > 0:
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = "VolumeServersNotHeartbeating"
+		condition.Message = fmt.Sprintf("no heartbeat from %s", strings.Join(health.MissingVolumeServers, ","))
+	case health.Message != "":
+		condition.Status = corev1.ConditionUnknown
+		condition.Reason = "TopologyUnavailable"
+		condition.Message = health.Message
+	}
+	return condition
+}
+
+// replicationCondition checks the default replication against the data centers and racks
+// of the heartbeating volume servers
+func replicationCondition(m *seaweedv1.Seaweed, replication string, health *seaweedv1.ClusterHealth) seaweedv1.SeaweedCondition {
+	condition := seaweedv1.SeaweedCondition{
+		Type:               seaweedv1.ReplicationSatisfiable,
+		Status:             corev1.ConditionTrue,
+		ObservedGeneration: m.Generation,
+		Reason:             "EnoughVolumeServers",
+	}
+
+	servers := make(map[string]map[string]int)
+	for _, server := range health.VolumeServers {
+		if servers[server.DataCenter] == nil {
+			servers[server.DataCenter] = make(map[string]int)
+		}
+		servers[server.DataCenter][server.Rack]++
+	}
+	racks := 0
+	for _, dc := range servers {
+		racks += len(dc)
+	}
+	topology := fmt.Sprintf("%d data centers, %d racks, %d volume servers", len(servers), racks, len(health.VolumeServers))
+
+	rp, err := seaweedv1.ParseReplicaPlacement(replication)
+	switch {
+	case err != nil:
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = "InvalidReplication"
+		condition.Message = err.Error()
+	case !rp.CanPlace(servers):
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = "NotEnoughVolumeServers"
+		condition.Message = fmt.Sprintf("replication %s can not be placed on %s", replication, topology)
+	default:
+		condition.Message = fmt.Sprintf("replication %s fits %s", replication, topology)
+	}
+	return condition
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/controllers/swadmin"
+)
+
+func TestClusterHealthTracker(t *testing.T) {
+	var tracker clusterHealthTracker
+	key := types.NamespacedName{Namespace: "default", Name: "sw"}
+	var probes int32
+	release := make(chan struct{})
+	collect := func(ctx context.Context) *seaweedv1.ClusterHealth {
+		atomic.AddInt32(&probes, 1)
+		<-release
+		return &seaweedv1.ClusterHealth{Leader: "sw-master-0.sw-master-peer.default:9333"}
+	}
+
+	// a recent probe in the status is not repeated
+	recent := metav1.Now()
+	if health := tracker.poll(key, &recent, collect); health != nil || atomic.LoadInt32(&probes) != 0 {
+		t.Fatalf("health = %+v, probes = %d, want no probe", health, probes)
+	}
+
+	// the probe runs in the background, the reconcile does not wait for it
+	if health := tracker.poll(key, nil, collect); health != nil {
+		t.Errorf("health = %+v before the probe finished", health)
+	}
+	if health := tracker.poll(key, nil, collect); health != nil {
+		t.Errorf("health = %+v before the probe finished", health)
+	}
+	close(release)
+	deadline := time.Now().Add(5 * time.Second)
+	var health *seaweedv1.ClusterHealth
+	for health == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		health = tracker.poll(key, nil, collect)
+	}
+	if health == nil || health.Leader != "sw-master-0.sw-master-peer.default:9333" {
+		t.Fatalf("health = %+v", health)
+	}
+	if n := atomic.LoadInt32(&probes); n != 1 {
+		t.Errorf("%d probes within the interval, want 1", n)
+	}
+
+	tracker.probes[key].startTime = time.Now().Add(-2 * clusterHealthInterval)
+	if health := tracker.poll(key, nil, collect); health == nil {
+		t.Errorf("last health is dropped when the next probe starts")
+	}
+	for atomic.LoadInt32(&probes) != 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := atomic.LoadInt32(&probes); n != 2 {
+		t.Errorf("%d probes after the interval, want 2", n)
+	}
+}
+
+func TestCollectClusterHealth(t *testing.T) {
+	m := testSeaweed(3, 3)
+	masters := getMasterAddresses("default", "sw", 3)
+	admin := swadmin.NewFakeAdmin(getMasterPeersString(m))
+	admin.ClusterStatuses = map[string]*swadmin.ClusterStatus{
+		masters[0]: {Leader: masters[1]},
+		masters[1]: {IsLeader: true, Peers: masters[:1], MaxVolumeID: 3},
+	}
+	admin.Topology = testTopology(map[string][]uint32{
+		"sw-volume-0.sw-volume-peer.default:8444": {1, 2},
+		"sw-volume-1.sw-volume-peer.default:8444": {3},
+	})
+	admin.Topology.DataCenters[0].Racks[0].Nodes[0].Disks[0].VolumeCount = 2
+	admin.Topology.DataCenters[0].Racks[0].Nodes[0].Disks[0].MaxVolumeCount = 8
+	admin.Topology.DataCenters[0].Racks[0].Nodes[0].Disks[0].FreeVolumeCount = 6
+
+	health := collectClusterHealth(context.Background(), m, admin)
+	if health.Leader != masters[1] || health.RespondingMasters != 2 || health.MaxVolumeID != 3 {
+		t.Errorf("raft state = %+v", health)
+	}
+	if health.VolumeSlots != 8 || health.FreeVolumeSlots != 6 || len(health.VolumeServers) != 2 || health.VolumeServers[0].Volumes != 2 {
+		t.Errorf("topology = %+v", health)
+	}
+	if want := []string{"sw-volume-2.sw-volume-peer.default:8444"}; !reflect.DeepEqual(health.MissingVolumeServers, want) {
+		t.Errorf("missing volume servers = %v, want %v", health.MissingVolumeServers, want)
+	}
+
+	admin.ClusterStatuses = nil
+	if health := collectClusterHealth(context.Background(), m, admin); health.RespondingMasters != 0 || degradedCondition(m, health).Reason != "MastersUnreachable" {
+		t.Errorf("health of unreachable masters = %+v", health)
+	}
+}
@@ -1,37 +1,167 @@
 package controllers
 
 import (
+	"context"
+	"fmt"
 	"io/ioutil"
-	"os"
+	"strings"
+	"time"
 
-	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
-	"github.com/seaweedfs/seaweedfs-operator/controllers/swadmin"
+	"github.com/robfig/cron/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
 )
 
-func (r *SeaweedReconciler) maintenance(m *seaweedv1.Seaweed) (done bool, result ctrl.Result, err error) {
+const (
+	maintenanceTaskPrefix = "maintenance "
+)
 
-	masters := getMasterPeersString(m)
+// ensureMaintenance starts the scheduled scripts that are due, one at a time per cluster.
+// The scripts run as admin tasks, so the reconcile loop never waits for them.
+func (r *SeaweedReconciler) ensureMaintenance(m *seaweedv1.Seaweed) (done bool, result ctrl.Result, err error) {
+	log := r.Log.WithValues("sw-maintenance", m.Name)
 
-	r.Log.V(0).Info("wait to connect to masters", "masters", masters)
+	if task := r.currentAdminTask(m); task != nil && strings.HasPrefix(task.name, maintenanceTaskPrefix) {
+		scriptStatus := findMaintenanceScriptStatus(m, strings.TrimPrefix(task.name, maintenanceTaskPrefix))
+		finished, taskErr := task.finished()
+		if !finished {
+			if scriptStatus != nil {
+				scriptStatus.Running = true
+			}
+		} else {
+			r.clearAdminTask(m)
+			log.Info("maintenance script finished", "script", task.name, "error", taskErr)
+			if scriptStatus != nil {
+				startTime := metav1.NewTime(task.startTime)
+				scriptStatus.Running = false
+				scriptStatus.LastRunTime = &startTime
+				scriptStatus.LastDuration = &metav1.Duration{Duration: task.finishTime.Sub(task.startTime).Round(time.Second)}
+				if taskErr != nil {
+					scriptStatus.LastResult = "Failed"
+					scriptStatus.Message = taskErr.Error()
+				} else {
+					scriptStatus.LastResult = "Succeeded"
+					scriptStatus.Message = ""
+				}
+			}
+		}
+	}
 
-	// this step blocks since the operator can not access the masters when running from outside of the k8s cluster
-	sa := swadmin.NewSeaweedAdmin(masters, ioutil.Discard)
+	spec := m.Spec.Maintenance
+	if spec == nil {
+		m.Status.Maintenance = nil
+		return ReconcileResult(nil)
+	}
 
-	// For now this is an example of the admin commands
-	// master by default has some maintenance commands already.
-	r.Log.V(0).Info("volume.list")
-	sa.Output = os.Stdout
-	if err := sa.ProcessCommand("volume.list"); err != nil {
-		r.Log.V(0).Info("volume.list", "error", err)
+	loc, err := spec.Location()
+	if err != nil {
+		return ReconcileResult(err)
 	}
 
-	sa.ProcessCommand("lock")
-	if err := sa.ProcessCommand("volume.balance -force"); err != nil {
-		r.Log.V(0).Info("volume.balance", "error", err)
+	now := time.Now()
+	var statuses []seaweedv1.MaintenanceScriptStatus
+	for _, script := range spec.Scripts {
+		scriptStatus := seaweedv1.MaintenanceScriptStatus{
+			Name:             script.Name,
+			LastScheduleTime: &metav1.Time{Time: now},
+		}
+		if existing := findMaintenanceScriptStatus(m, script.Name); existing != nil {
+			scriptStatus = *existing
+		}
+		r.scheduleMaintenanceScript(m, spec, script, &scriptStatus, now.In(loc))
+		statuses = append(statuses, scriptStatus)
 	}
-	sa.ProcessCommand("unlock")
+	m.Status.Maintenance = statuses
 
 	return ReconcileResult(nil)
+}
+
+// scheduleMaintenanceScript starts the script if a schedule tick passed since the last handled one
+func (r *SeaweedReconciler) scheduleMaintenanceScript(m *seaweedv1.Seaweed, spec *seaweedv1.MaintenanceSpec, script seaweedv1.MaintenanceScript, scriptStatus *seaweedv1.MaintenanceScriptStatus, now time.Time) {
+	schedule, err := script.ParseSchedule()
+	if err != nil {
+		scriptStatus.Message = fmt.Sprintf("invalid schedule: %v", err)
+		return
+	}
+
+	if scriptStatus.Running {
+		if task := r.currentAdminTask(m); task != nil && task.name == maintenanceTaskPrefix+script.Name {
+			return
+		}
+		// the operator restarted during the run
+		scriptStatus.Running = false
+		scriptStatus.LastResult = "Failed"
+		scriptStatus.Message = "the run was interrupted"
+	}
+
+	if scriptStatus.LastScheduleTime == nil {
+		scriptStatus.LastScheduleTime = &metav1.Time{Time: now}
+	}
+	tick := latestScheduleTick(schedule, scriptStatus.LastScheduleTime.In(now.Location()), now)
+	if tick.IsZero() {
+		return
+	}
+
+	inWindow, err := spec.InWindow(now)
+	if err != nil {
+		scriptStatus.Message = err.Error()
+		return
+	}
+	if !inWindow {
+		scriptStatus.LastScheduleTime = &metav1.Time{Time: tick}
+		scriptStatus.Message = fmt.Sprintf("skipped the run scheduled at %s outside of the maintenance windows", tick.Format(time.RFC3339))
+		return
+	}
 
+	sa := r.seaweedAdmin(m)
+	commands := script.Commands
+	if !r.startAdminTask(m, maintenanceTaskPrefix+script.Name, func(ctx context.Context) error {
+		return sa.WithLock(ctx, func(ctx context.Context) error {
+			for _, cmd := range commands {
+				if err := sa.ProcessCommand(ctx, cmd, ioutil.Discard); err != nil {
+					return fmt.Errorf("%s: %v", cmd, err)
+				}
+			}
+			return nil
+		})
+	}) {
+		// the task may have finished and been cleared since
+		scriptStatus.Message = "waiting for another admin task to finish"
+		if task := r.currentAdminTask(m); task != nil {
+			scriptStatus.Message = fmt.Sprintf("waiting for %s to finish", task.name)
+		}
+		return
+	}
+
+	scriptStatus.LastScheduleTime = &metav1.Time{Time: tick}
+	scriptStatus.Running = true
+	scriptStatus.Message = fmt.Sprintf("started the run scheduled at %s", tick.Format(time.RFC3339))
+}
+
+// latestScheduleTick returns the last tick after last and not after now, or zero if there is none
+func latestScheduleTick(schedule cron.Schedule, last, now time.Time) time.Time {
+	tick := schedule.Next(last)
+	if tick.IsZero() || tick.After(now) {
+		return time.Time{}
+	}
+	// skip the ticks missed while the operator was down, they only run once
+	for i := 0; i < 10000; i++ {
+		next := schedule.Next(tick)
+		if next.IsZero() || next.After(now) {
+			break
+		}
+		tick = next
+	}
+	return tick
+}
+
+func findMaintenanceScriptStatus(m *seaweedv1.Seaweed, name string) *seaweedv1.MaintenanceScriptStatus {
+	for i := range m.Status.Maintenance {
+		if m.Status.Maintenance[i].Name == name {
+			return &m.Status.Maintenance[i]
+		}
+	}
+	return nil
 }
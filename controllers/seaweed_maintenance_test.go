@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/controllers/swadmin"
+)
+
+func TestLatestScheduleTick(t *testing.T) {
+	at := func(value string) time.Time {
+		tick, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tick
+	}
+	for _, tc := range []struct {
+		name     string
+		schedule string
+		last     string
+		now      string
+		want     string
+	}{
+		{name: "before the next tick", schedule: "0 3 * * *", last: "2021-11-01T03:00:00Z", now: "2021-11-02T02:59:00Z"},
+		{name: "at the next tick", schedule: "0 3 * * *", last: "2021-11-01T03:00:00Z", now: "2021-11-02T03:00:00Z", want: "2021-11-02T03:00:00Z"},
+		{name: "after the next tick", schedule: "0 3 * * *", last: "2021-11-01T03:00:00Z", now: "2021-11-02T09:30:00Z", want: "2021-11-02T03:00:00Z"},
+		{name: "missed ticks run once", schedule: "0 3 * * *", last: "2021-11-01T03:00:00Z", now: "2021-11-05T10:00:00Z", want: "2021-11-05T03:00:00Z"},
+		{name: "missed hourly ticks", schedule: "@hourly", last: "2021-11-01T10:30:00Z", now: "2021-11-01T13:59:00Z", want: "2021-11-01T13:00:00Z"},
+		{name: "first run after the status was created", schedule: "*/15 * * * *", last: "2021-11-01T10:05:00Z", now: "2021-11-01T10:20:00Z", want: "2021-11-01T10:15:00Z"},
+	} {
+		schedule, err := cron.ParseStandard(tc.schedule)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		tick := latestScheduleTick(schedule, at(tc.last), at(tc.now))
+		switch {
+		case tc.want == "" && !tick.IsZero():
+			t.Errorf("%s: tick = %s, want none", tc.name, tick)
+		case tc.want != "" && !tick.Equal(at(tc.want)):
+			t.Errorf("%s: tick = %s, want %s", tc.name, tick, tc.want)
+		}
+	}
+}
+
+func TestMaintenanceWindowContains(t *testing.T) {
+	for _, tc := range []struct {
+		start, end string
+		clock      string
+		want       bool
+	}{
+		{start: "02:00", end: "04:00", clock: "01:59", want: false},
+		{start: "02:00", end: "04:00", clock: "02:00", want: true},
+		{start: "02:00", end: "04:00", clock: "03:59", want: true},
+		{start: "02:00", end: "04:00", clock: "04:00", want: false},
+		// the window spans midnight
+		{start: "22:00", end: "02:00", clock: "21:59", want: false},
+		{start: "22:00", end: "02:00", clock: "22:00", want: true},
+		{start: "22:00", end: "02:00", clock: "23:59", want: true},
+		{start: "22:00", end: "02:00", clock: "00:00", want: true},
+		{start: "22:00", end: "02:00", clock: "01:59", want: true},
+		{start: "22:00", end: "02:00", clock: "02:00", want: false},
+		{start: "22:00", end: "02:00", clock: "12:00", want: false},
+	} {
+		clock, _ := time.Parse("15:04", tc.clock)
+		w := seaweedv1.MaintenanceWindow{Start: tc.start, End: tc.end}
+		if in, err := w.Contains(clock); err != nil || in != tc.want {
+			t.Errorf("%s-%s contains %s = %v, %v, want %v", tc.start, tc.end, tc.clock, in, err, tc.want)
+		}
+	}
+
+	if _, err := (&seaweedv1.MaintenanceWindow{Start: "22:00", End: "2am"}).Contains(time.Now()); err == nil {
+		t.Errorf("invalid end accepted")
+	}
+
+	// the window is in the time zone of the spec
+	spec := &seaweedv1.MaintenanceSpec{TimeZone: "Europe/Berlin", Windows: []seaweedv1.MaintenanceWindow{{Start: "23:00", End: "01:00"}}}
+	if in, err := spec.InWindow(time.Date(2021, 11, 1, 22, 30, 0, 0, time.UTC)); err != nil || !in {
+		t.Errorf("23:30 in Berlin is not in the window: %v", err)
+	}
+	if in, err := spec.InWindow(time.Date(2021, 11, 1, 0, 30, 0, 0, time.UTC)); err != nil || in {
+		t.Errorf("01:30 in Berlin is in the window: %v", err)
+	}
+}
+
+func TestEnsureMaintenance(t *testing.T) {
+	m := testSeaweed(1, 1)
+	m.Spec.Maintenance = &seaweedv1.MaintenanceSpec{
+		Scripts: []seaweedv1.MaintenanceScript{{Name: "vacuum", Schedule: "@hourly", Commands: []string{"volume.vacuum"}}},
+	}
+	lastTick := metav1.NewTime(time.Now().Add(-2 * time.Hour))
+	m.Status.Maintenance = []seaweedv1.MaintenanceScriptStatus{{Name: "vacuum", LastScheduleTime: &lastTick}}
+	r := newFakeReconciler(m)
+	admin := swadmin.NewFakeAdmin("")
+	useFakeAdmin(r, admin)
+	ensure := func() *seaweedv1.MaintenanceScriptStatus {
+		t.Helper()
+		if done, _, err := r.ensureMaintenance(m); done || err != nil {
+			t.Fatalf("done = %v, err = %v", done, err)
+		}
+		return &m.Status.Maintenance[0]
+	}
+
+	// the due run waits for the running balance, and starts once the balance was cleared
+	release := make(chan struct{})
+	r.startAdminTask(m, volumeBalanceTaskName, func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	if status := ensure(); status.Running || status.Message != "waiting for volume-balance to finish" {
+		t.Errorf("status next to the balance = %+v", status)
+	}
+	close(release)
+	waitForAdminTask(t, r.currentAdminTask(m))
+	if status := ensure(); status.Running {
+		t.Errorf("started before the finished balance was cleared: %+v", status)
+	}
+	r.AdminTasks.clear(seaweedKey(m), r.currentAdminTask(m))
+
+	status := ensure()
+	if !status.Running || !strings.HasPrefix(status.Message, "started the run scheduled at") {
+		t.Fatalf("status after the balance = %+v", status)
+	}
+	task := r.currentAdminTask(m)
+	waitForAdminTask(t, task)
+	if status := ensure(); status.Running || status.LastResult != "Succeeded" || status.LastRunTime == nil {
+		t.Errorf("status after the run = %+v", status)
+	}
+	if r.currentAdminTask(m) != nil {
+		t.Errorf("finished run not cleared")
+	}
+	if commands := admin.Commands(); !reflect.DeepEqual(commands, []string{"lock", "volume.vacuum", "unlock"}) {
+		t.Errorf("commands = %q", commands)
+	}
+
+	// the operator restarted during a run, the tick was handled
+	m.Status.Maintenance[0].Running = true
+	if status := ensure(); status.Running || status.LastResult != "Failed" || status.Message != "the run was interrupted" {
+		t.Errorf("status after a restart = %+v", status)
+	}
+}
@@ -0,0 +1,272 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+// reconcileErrorGracePeriod is how long reconcile errors which a retry may fix are retried before the phase is Failed
+const reconcileErrorGracePeriod = 5 * time.Minute
+
+// updateSeaweedStatus observes the workloads of the cluster and writes the result into the status subresource.
+// reconcileErr is the error of the current reconciliation, if any.
+func (r *SeaweedReconciler) updateSeaweedStatus(seaweedCR *seaweedv1.Seaweed, reconcileErr error) error {
+	ctx := context.Background()
+	original := seaweedCR.Status.DeepCopy()
+	status := &seaweedCR.Status
+
+	upgrading := false
+
+	masterStatus, masterUpgrading, err := r.statefulSetStatus(seaweedCR, seaweedCR.Name+"-master", seaweedCR.Spec.Master.Replicas, original.Master.Version)
+	if err != nil {
+		return err
+	}
+	status.Master = masterStatus
+	upgrading = upgrading || masterUpgrading
+	setComponentCondition(seaweedCR, seaweedv1.MastersReady, masterStatus)
+
+	// VolumesReady covers the volume servers of all pools
+	allVolumesStatus := seaweedv1.ComponentStatus{}
+	status.VolumePools = nil
+	for _, pool := range volumePools(seaweedCR) {
+		previous := componentStatusOf(original, upgradeComponentName(pool))
+		if previous == nil {
+			previous = &seaweedv1.ComponentStatus{}
+		}
+		volumeStatus, volumeUpgrading, err := r.statefulSetStatus(seaweedCR, pool.statefulSetName(seaweedCR), pool.spec.Replicas, previous.Version)
+		if err != nil {
+			return err
+		}
+		if pool.name == "" {
+			status.Volume = volumeStatus
+		} else {
+			status.VolumePools = append(status.VolumePools, seaweedv1.VolumePoolStatus{Name: pool.name, ComponentStatus: volumeStatus})
+		}
+		upgrading = upgrading || volumeUpgrading
+		allVolumesStatus.Replicas += volumeStatus.Replicas
+		allVolumesStatus.ReadyReplicas += volumeStatus.ReadyReplicas
+		allVolumesStatus.UpdatedReplicas += volumeStatus.UpdatedReplicas
+	}
+	if seaweedCR.Spec.Volume != nil || len(seaweedCR.Spec.VolumePools) > 0 {
+		setComponentCondition(seaweedCR, seaweedv1.VolumesReady, allVolumesStatus)
+	}
+
+	if seaweedCR.Spec.Filer != nil {
+		filerStatus, filerUpgrading, err := r.statefulSetStatus(seaweedCR, seaweedCR.Name+"-filer", seaweedCR.Spec.Filer.Replicas, original.Filer.Version)
+		if err != nil {
+			return err
+		}
+		status.Filer = filerStatus
+		upgrading = upgrading || filerUpgrading
+		setComponentCondition(seaweedCR, seaweedv1.FilersReady, filerStatus)
+	}
+
+	if seaweedCR.Spec.Gateway != nil && seaweedCR.Spec.Gateway.Enabled {
+		gatewayStatus, gatewayUpgrading, err := r.deploymentStatus(seaweedCR, seaweedCR.Name+"-s3-gateway", seaweedCR.Spec.Gateway.Replicas, original.Gateway.Version)
+		if err != nil {
+			return err
+		}
+		status.Gateway = gatewayStatus
+		upgrading = upgrading || gatewayUpgrading
+		setComponentCondition(seaweedCR, seaweedv1.GatewayReady, gatewayStatus)
+	} else {
+		status.Gateway = seaweedv1.ComponentStatus{}
+		status.RemoveCondition(seaweedv1.GatewayReady)
+	}
+
+	if seaweedCR.Spec.HostSuffix != nil && len(*seaweedCR.Spec.HostSuffix) != 0 {
+		ingress := &extensionsv1beta1.Ingress{}
+		err := r.Get(ctx, types.NamespacedName{Namespace: seaweedCR.Namespace, Name: seaweedCR.Name + "-ingress"}, ingress)
+		switch {
+		case err == nil:
+			status.SetCondition(seaweedv1.SeaweedCondition{
+				Type:               seaweedv1.IngressReady,
+				Status:             corev1.ConditionTrue,
+				ObservedGeneration: seaweedCR.Generation,
+				Reason:             "IngressCreated",
+				Message:            fmt.Sprintf("ingress %s is created", ingress.Name),
+			})
+		case errors.IsNotFound(err):
+			status.SetCondition(seaweedv1.SeaweedCondition{
+				Type:               seaweedv1.IngressReady,
+				Status:             corev1.ConditionFalse,
+				ObservedGeneration: seaweedCR.Generation,
+				Reason:             "IngressNotFound",
+				Message:            "ingress is not created yet",
+			})
+		default:
+			return err
+		}
+	} else {
+		status.RemoveCondition(seaweedv1.IngressReady)
+	}
+
+	r.updateClusterHealth(seaweedCR)
+
+	if err := r.updateUpgradeStatus(seaweedCR, original); err != nil {
+		return err
+	}
+	if status.Upgrade != nil {
+		upgrading = true
+	}
+
+	if reconcileErr == nil {
+		status.ObservedGeneration = seaweedCR.Generation
+	}
+	setReconciledCondition(seaweedCR, reconcileErr)
+	status.Phase = seaweedPhase(seaweedCR, upgrading, reconcileErr)
+
+	if apiequality.Semantic.DeepEqual(original, status) {
+		return nil
+	}
+	return r.Status().Update(ctx, seaweedCR)
+}
+
+// seaweedPhase summarizes the conditions into a single phase.
+// A reconcile error only fails the cluster if a retry does not fix it or it persists, the reconciliation is
+// requeued on errors such as update conflicts and the phase follows the conditions meanwhile.
+func seaweedPhase(seaweedCR *seaweedv1.Seaweed, upgrading bool, reconcileErr error) seaweedv1.SeaweedPhase {
+	status := &seaweedCR.Status
+	if reconcileErr != nil && (isTerminalError(reconcileErr) || reconcileErrorPersists(status, time.Now())) {
+		return seaweedv1.SeaweedPhaseFailed
+	}
+
+	allReady := true
+	for _, c := range status.Conditions {
+		// the reconcile errors are judged above
+		if c.Type == seaweedv1.Reconciled {
+			continue
+		}
+		// Degraded is the only condition where True is bad, and Unknown when the masters are not reachable
+		if c.Type == seaweedv1.Degraded {
+			if c.Status == corev1.ConditionTrue {
+				allReady = false
+			}
+			continue
+		}
+		if c.Status != corev1.ConditionTrue {
+			allReady = false
+		}
+	}
+	if allReady && !upgrading {
+		return seaweedv1.SeaweedPhaseRunning
+	}
+
+	if status.Phase == "" || status.Phase == seaweedv1.SeaweedPhaseCreating {
+		return seaweedv1.SeaweedPhaseCreating
+	}
+
+	if upgrading {
+		return seaweedv1.SeaweedPhaseUpgrading
+	}
+
+	if status.Master.ReadyReplicas < status.Master.Replicas/2+1 {
+		return seaweedv1.SeaweedPhaseFailed
+	}
+
+	return seaweedv1.SeaweedPhaseDegraded
+}
+
+// setReconciledCondition records whether the reconciliation failed, and since when if it keeps failing
+func setReconciledCondition(seaweedCR *seaweedv1.Seaweed, reconcileErr error) {
+	condition := seaweedv1.SeaweedCondition{
+		Type:               seaweedv1.Reconciled,
+		Status:             corev1.ConditionTrue,
+		ObservedGeneration: seaweedCR.Generation,
+		Reason:             "ReconcileSucceeded",
+	}
+	if reconcileErr != nil {
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = "ReconcileError"
+		condition.Message = reconcileErr.Error()
+	}
+	seaweedCR.Status.SetCondition(condition)
+}
+
+// reconcileErrorPersists reports whether the reconciliation has failed for longer than reconcileErrorGracePeriod
+func reconcileErrorPersists(status *seaweedv1.SeaweedStatus, now time.Time) bool {
+	c := status.GetCondition(seaweedv1.Reconciled)
+	return c != nil && c.Status == corev1.ConditionFalse && now.Sub(c.LastTransitionTime.Time) >= reconcileErrorGracePeriod
+}
+
+// isTerminalError reports whether the API server refused a request which is the same on every retry
+func isTerminalError(err error) bool {
+	return errors.IsInvalid(err) || errors.IsBadRequest(err) || errors.IsForbidden(err) || errors.IsMethodNotSupported(err)
+}
+
+func setComponentCondition(seaweedCR *seaweedv1.Seaweed, conditionType seaweedv1.SeaweedConditionType, componentStatus seaweedv1.ComponentStatus) {
+	condition := seaweedv1.SeaweedCondition{
+		Type:               conditionType,
+		Status:             corev1.ConditionTrue,
+		ObservedGeneration: seaweedCR.Generation,
+		Reason:             "AllReplicasReady",
+		Message:            fmt.Sprintf("%d/%d replicas ready", componentStatus.ReadyReplicas, componentStatus.Replicas),
+	}
+	if componentStatus.ReadyReplicas < componentStatus.Replicas {
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = "ReplicasNotReady"
+	}
+	seaweedCR.Status.SetCondition(condition)
+}
+
+// statefulSetStatus reads the replica counts of a StatefulSet and whether it is rolling out a new revision.
+// The version is the one of the image once every replica runs it, previousVersion until then.
+func (r *SeaweedReconciler) statefulSetStatus(seaweedCR *seaweedv1.Seaweed, name string, replicas int32, previousVersion string) (seaweedv1.ComponentStatus, bool, error) {
+	componentStatus := seaweedv1.ComponentStatus{Replicas: replicas, Version: previousVersion}
+
+	statefulSet := &appsv1.StatefulSet{}
+	err := r.Get(context.Background(), types.NamespacedName{Namespace: seaweedCR.Namespace, Name: name}, statefulSet)
+	if errors.IsNotFound(err) {
+		return componentStatus, false, nil
+	}
+	if err != nil {
+		return componentStatus, false, err
+	}
+
+	componentStatus.ReadyReplicas = statefulSet.Status.ReadyReplicas
+	componentStatus.UpdatedReplicas = statefulSet.Status.UpdatedReplicas
+	upgrading := statefulSet.Status.ObservedGeneration < statefulSet.Generation ||
+		(statefulSet.Status.UpdateRevision != "" && statefulSet.Status.CurrentRevision != statefulSet.Status.UpdateRevision)
+	if statefulSet.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType {
+		// the current revision does not follow the update revision with the OnDelete strategy
+		upgrading = statefulSet.Status.ObservedGeneration < statefulSet.Generation || statefulSet.Status.UpdatedReplicas < replicas
+	}
+	if statefulSetRolledOut(statefulSet) || previousVersion == "" {
+		componentStatus.Version = seaweedv1.ImageVersion(podTemplateImage(&statefulSet.Spec.Template))
+	}
+	return componentStatus, upgrading, nil
+}
+
+// deploymentStatus reads the replica counts of a Deployment and whether it is rolling out a new revision.
+// The version is the one of the image once every replica runs it, previousVersion until then.
+func (r *SeaweedReconciler) deploymentStatus(seaweedCR *seaweedv1.Seaweed, name string, replicas int32, previousVersion string) (seaweedv1.ComponentStatus, bool, error) {
+	componentStatus := seaweedv1.ComponentStatus{Replicas: replicas, Version: previousVersion}
+
+	deployment := &appsv1.Deployment{}
+	err := r.Get(context.Background(), types.NamespacedName{Namespace: seaweedCR.Namespace, Name: name}, deployment)
+	if errors.IsNotFound(err) {
+		return componentStatus, false, nil
+	}
+	if err != nil {
+		return componentStatus, false, err
+	}
+
+	componentStatus.ReadyReplicas = deployment.Status.ReadyReplicas
+	componentStatus.UpdatedReplicas = deployment.Status.UpdatedReplicas
+	upgrading := deployment.Status.ObservedGeneration < deployment.Generation ||
+		deployment.Status.UpdatedReplicas < deployment.Status.Replicas
+	if !upgrading || previousVersion == "" {
+		componentStatus.Version = seaweedv1.ImageVersion(podTemplateImage(&deployment.Spec.Template))
+	}
+	return componentStatus, upgrading, nil
+}
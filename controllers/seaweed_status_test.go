@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+func TestSeaweedPhase(t *testing.T) {
+	seaweeds := schema.GroupResource{Group: "seaweed.seaweedfs.com", Resource: "seaweeds"}
+	conflict := errors.NewConflict(seaweeds, "sw", fmt.Errorf("the object has been modified"))
+	invalid := errors.NewInvalid(schema.GroupKind{Group: "apps", Kind: "StatefulSet"}, "sw-master", nil)
+
+	for _, tc := range []struct {
+		name string
+		// phase is the phase of the previous reconciliation
+		phase seaweedv1.SeaweedPhase
+		// failingSince is how long the previous reconciliations failed, none if zero
+		failingSince time.Duration
+		ready        bool
+		err          error
+		want         seaweedv1.SeaweedPhase
+	}{
+		{name: "ready", phase: seaweedv1.SeaweedPhaseDegraded, ready: true, want: seaweedv1.SeaweedPhaseRunning},
+		{name: "conflict while ready", phase: seaweedv1.SeaweedPhaseRunning, ready: true, err: conflict, want: seaweedv1.SeaweedPhaseRunning},
+		{name: "conflict while creating", err: conflict, want: seaweedv1.SeaweedPhaseCreating},
+		{name: "conflict while degraded", phase: seaweedv1.SeaweedPhaseDegraded, err: conflict, want: seaweedv1.SeaweedPhaseDegraded},
+		{name: "recent errors", phase: seaweedv1.SeaweedPhaseRunning, failingSince: time.Minute, ready: true, err: conflict, want: seaweedv1.SeaweedPhaseRunning},
+		{name: "persistent errors", phase: seaweedv1.SeaweedPhaseRunning, failingSince: reconcileErrorGracePeriod, ready: true, err: conflict, want: seaweedv1.SeaweedPhaseFailed},
+		{name: "error after persistent errors were fixed", phase: seaweedv1.SeaweedPhaseFailed, ready: true, err: conflict, want: seaweedv1.SeaweedPhaseRunning},
+		{name: "terminal error", phase: seaweedv1.SeaweedPhaseRunning, ready: true, err: invalid, want: seaweedv1.SeaweedPhaseFailed},
+		{name: "unknown error", phase: seaweedv1.SeaweedPhaseRunning, ready: true, err: fmt.Errorf("no leader"), want: seaweedv1.SeaweedPhaseRunning},
+	} {
+		m := testSeaweed(3, 0)
+		m.Status.Phase = tc.phase
+		m.Status.Master = seaweedv1.ComponentStatus{Replicas: 3, ReadyReplicas: 2}
+		if tc.ready {
+			m.Status.Master.ReadyReplicas = 3
+		}
+		setComponentCondition(m, seaweedv1.MastersReady, m.Status.Master)
+		if tc.failingSince > 0 {
+			m.Status.SetCondition(seaweedv1.SeaweedCondition{
+				Type:               seaweedv1.Reconciled,
+				Status:             corev1.ConditionFalse,
+				LastTransitionTime: metav1.NewTime(time.Now().Add(-tc.failingSince)),
+			})
+		}
+
+		setReconciledCondition(m, tc.err)
+		if phase := seaweedPhase(m, false, tc.err); phase != tc.want {
+			t.Errorf("%s: phase = %s, want %s", tc.name, phase, tc.want)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+// upgradeComponent is a workload of the cluster, upgradeComponents lists them in the order of upgrades
+type upgradeComponent struct {
+	// name is master, volume, volume-<pool>, filer or gateway
+	name       string
+	workload   string
+	deployment bool
+	image      string
+	pool       *volumePool
+}
+
+// upgradeComponents lists the masters, the volume servers pool by pool, the filers and the gateway
+func upgradeComponents(m *seaweedv1.Seaweed) []upgradeComponent {
+	components := []upgradeComponent{{name: "master", workload: m.Name + "-master", image: m.BaseMasterSpec().Image()}}
+	for _, pool := range volumePools(m) {
+		pool := pool
+		components = append(components, upgradeComponent{name: upgradeComponentName(pool), workload: pool.statefulSetName(m), image: pool.accessor.Image(), pool: &pool})
+	}
+	if m.Spec.Filer != nil {
+		components = append(components, upgradeComponent{name: "filer", workload: m.Name + "-filer", image: m.BaseFilerSpec().Image()})
+	}
+	if m.Spec.Gateway != nil && m.Spec.Gateway.Enabled {
+		components = append(components, upgradeComponent{name: "gateway", workload: m.Name + "-s3-gateway", deployment: true, image: m.BaseGatewaySpec().Image()})
+	}
+	return components
+}
+
+// upgradeComponentName is volume for spec.volume and volume-<pool> for the pools
+func upgradeComponentName(pool volumePool) string {
+	if pool.name == "" {
+		return "volume"
+	}
+	return "volume-" + pool.name
+}
+
+// workloadRollout reads the image of the pod template of the component, empty if the workload does not exist,
+// and whether every replica runs the template and is ready
+func (r *SeaweedReconciler) workloadRollout(m *seaweedv1.Seaweed, c upgradeComponent) (string, bool, error) {
+	key := types.NamespacedName{Namespace: m.Namespace, Name: c.workload}
+	if c.deployment {
+		deployment := &appsv1.Deployment{}
+		err := r.Get(context.Background(), key, deployment)
+		if errors.IsNotFound(err) {
+			return "", false, nil
+		}
+		if err != nil {
+			return "", false, err
+		}
+		replicas := int32(1)
+		if deployment.Spec.Replicas != nil {
+			replicas = *deployment.Spec.Replicas
+		}
+		status := deployment.Status
+		rolledOut := status.ObservedGeneration >= deployment.Generation &&
+			status.UpdatedReplicas == replicas && status.ReadyReplicas == replicas && status.Replicas == replicas
+		return podTemplateImage(&deployment.Spec.Template), rolledOut, nil
+	}
+
+	statefulSet := &appsv1.StatefulSet{}
+	err := r.Get(context.Background(), key, statefulSet)
+	if errors.IsNotFound(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return podTemplateImage(&statefulSet.Spec.Template), statefulSetRolledOut(statefulSet), nil
+}
+
+func podTemplateImage(template *corev1.PodTemplateSpec) string {
+	if len(template.Spec.Containers) == 0 {
+		return ""
+	}
+	return template.Spec.Containers[0].Image
+}
+
+// componentImage decides the image of a component. A component is only upgraded once every component
+// before it runs its new image on all replicas and is healthy, until then it keeps the image it runs.
+func (r *SeaweedReconciler) componentImage(m *seaweedv1.Seaweed, name string) (string, error) {
+	components := upgradeComponents(m)
+	index := -1
+	for i, c := range components {
+		if c.name == name {
+			index = i
+		}
+	}
+	if index < 0 {
+		return "", fmt.Errorf("unknown component %s", name)
+	}
+	target := components[index]
+
+	running, _, err := r.workloadRollout(m, target)
+	if err != nil {
+		return "", err
+	}
+	if running == "" || running == target.image {
+		return target.image, nil
+	}
+
+	for _, c := range components[:index] {
+		image, rolledOut, err := r.workloadRollout(m, c)
+		if err != nil {
+			return "", err
+		}
+		if image != c.image || !rolledOut {
+			r.Log.Info("hold back upgrade", "component", name, "waitingFor", c.name)
+			return running, nil
+		}
+	}
+	switch healthy, reason := upgradeHealth(m, components[:index]); healthy {
+	case corev1.ConditionFalse:
+		r.Log.Info("hold back upgrade", "component", name, "reason", reason)
+		return running, nil
+	case corev1.ConditionUnknown:
+		r.Log.Info("upgrade on the readiness of the replicas alone", "component", name, "reason", reason)
+	}
+	return target.image, nil
+}
+
+// upgradeHealth checks the components an upgrade waits for against the last cluster health probed by
+// updateClusterHealth. It is Unknown when the masters could not tell, then the upgrade relies on the readiness
+// of the replicas, and False with the reason when they report a component as unhealthy.
+func upgradeHealth(m *seaweedv1.Seaweed, components []upgradeComponent) (corev1.ConditionStatus, string) {
+	if len(components) == 0 {
+		return corev1.ConditionTrue, ""
+	}
+	health := m.Status.Health
+	switch {
+	case health == nil || health.LastProbeTime == nil:
+		return corev1.ConditionUnknown, "the cluster health has not been probed yet"
+	case time.Since(health.LastProbeTime.Time) > 2*clusterHealthInterval:
+		return corev1.ConditionUnknown, fmt.Sprintf("the cluster health was last probed at %s", health.LastProbeTime.Format(time.RFC3339))
+	case health.RespondingMasters == 0:
+		return corev1.ConditionUnknown, health.Message
+	}
+	for _, c := range components {
+		if c.pool != nil && health.Leader != "" && health.Message != "" {
+			// the leader did not report the topology
+			return corev1.ConditionUnknown, health.Message
+		}
+		if reason := componentUnhealthy(m, c, health); reason != "" {
+			return corev1.ConditionFalse, fmt.Sprintf("%s: %s", c.name, reason)
+		}
+	}
+	return corev1.ConditionTrue, ""
+}
+
+// componentUnhealthy explains why the masters report the component as unhealthy, empty if they do not
+func componentUnhealthy(m *seaweedv1.Seaweed, c upgradeComponent, health *seaweedv1.ClusterHealth) string {
+	switch {
+	case c.name == "master":
+		if health.Leader == "" {
+			return health.Message
+		}
+		if health.RespondingMasters < m.Spec.Master.Replicas {
+			return fmt.Sprintf("%d of %d masters respond", health.RespondingMasters, m.Spec.Master.Replicas)
+		}
+	case c.pool != nil:
+		for i := int32(0); i < c.pool.spec.Replicas; i++ {
+			if server := c.pool.serverAddress(m, i); containsString(health.MissingVolumeServers, server) {
+				return fmt.Sprintf("volume server %s is not heartbeating", server)
+			}
+		}
+	}
+	return ""
+}
+
+// updateUpgradeStatus records the first component in upgrade order which does not run its version yet
+func (r *SeaweedReconciler) updateUpgradeStatus(m *seaweedv1.Seaweed, previous *seaweedv1.SeaweedStatus) error {
+	var upgrade *seaweedv1.UpgradeStatus
+	components := upgradeComponents(m)
+	var waitingFor []upgradeComponent
+	for i, c := range components {
+		componentStatus := componentStatusOf(&m.Status, c.name)
+		version := seaweedv1.ImageVersion(c.image)
+		if componentStatus == nil || componentStatus.Version == "" || componentStatus.Version == version {
+			continue
+		}
+
+		image, _, err := r.workloadRollout(m, c)
+		if err != nil {
+			return err
+		}
+		upgrade = &seaweedv1.UpgradeStatus{
+			Component:   c.name,
+			FromVersion: componentStatus.Version,
+			ToVersion:   version,
+		}
+		waitingFor = components[:i]
+		if image != c.image {
+			upgrade.Message = fmt.Sprintf("waiting for the components before %s to run their new version and be healthy", c.name)
+		} else {
+			upgrade.Message = fmt.Sprintf("%d of %d replicas of %s updated", componentStatus.UpdatedReplicas, componentStatus.Replicas, c.name)
+		}
+		break
+	}
+
+	switch {
+	case upgrade == nil && previous.Upgrade != nil:
+		r.Recorder.Eventf(m, corev1.EventTypeNormal, "UpgradeCompleted", "Upgraded every component")
+	case upgrade != nil && previous.Upgrade == nil:
+		now := metav1.Now()
+		upgrade.StartTime = &now
+		r.Recorder.Eventf(m, corev1.EventTypeNormal, "UpgradeStarted", "Upgrading %s from %s to %s", upgrade.Component, upgrade.FromVersion, upgrade.ToVersion)
+	case upgrade != nil:
+		upgrade.StartTime = previous.Upgrade.StartTime
+		if upgrade.Component != previous.Upgrade.Component {
+			r.Recorder.Eventf(m, corev1.EventTypeNormal, "UpgradeProgressing", "Upgrading %s from %s to %s", upgrade.Component, upgrade.FromVersion, upgrade.ToVersion)
+		}
+	}
+	m.Status.Upgrade = upgrade
+
+	if upgrade == nil {
+		m.Status.RemoveCondition(seaweedv1.UpgradeHealthy)
+		return nil
+	}
+	condition := seaweedv1.SeaweedCondition{
+		Type:               seaweedv1.UpgradeHealthy,
+		ObservedGeneration: m.Generation,
+	}
+	switch healthy, reason := upgradeHealth(m, waitingFor); healthy {
+	case corev1.ConditionTrue:
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = "ComponentsHealthy"
+		condition.Message = fmt.Sprintf("the masters report the components before %s as healthy", upgrade.Component)
+	case corev1.ConditionFalse:
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = "ComponentUnhealthy"
+		condition.Message = fmt.Sprintf("upgrade of %s holds back, %s", upgrade.Component, reason)
+	default:
+		condition.Status = corev1.ConditionUnknown
+		condition.Reason = "HealthUnknown"
+		condition.Message = fmt.Sprintf("upgrade of %s relies on the readiness of the replicas, %s", upgrade.Component, reason)
+	}
+	m.Status.SetCondition(condition)
+	return nil
+}
+
+// componentStatusOf finds the status of an upgrade component
+func componentStatusOf(status *seaweedv1.SeaweedStatus, name string) *seaweedv1.ComponentStatus {
+	switch name {
+	case "master":
+		return &status.Master
+	case "volume":
+		return &status.Volume
+	case "filer":
+		return &status.Filer
+	case "gateway":
+		return &status.Gateway
+	}
+	for i := range status.VolumePools {
+		if "volume-"+status.VolumePools[i].Name == name {
+			return &status.VolumePools[i].ComponentStatus
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+func TestImageVersion(t *testing.T) {
+	for _, tc := range []struct {
+		image, version, want string
+	}{
+		{"chrislusf/seaweedfs", "", "chrislusf/seaweedfs"},
+		{"chrislusf/seaweedfs:2.70", "2.77", "chrislusf/seaweedfs:2.77"},
+		{"registry:5000/seaweedfs", "2.77", "registry:5000/seaweedfs:2.77"},
+		{"registry:5000/seaweedfs@sha256:abc", "2.77", "registry:5000/seaweedfs:2.77"},
+		{"", "2.77", "chrislusf/seaweedfs:2.77"},
+	} {
+		if got := seaweedv1.ImageWithVersion(tc.image, tc.version); got != tc.want {
+			t.Errorf("ImageWithVersion(%q, %q) = %q, want %q", tc.image, tc.version, got, tc.want)
+		}
+	}
+
+	for image, want := range map[string]string{
+		"chrislusf/seaweedfs":                "latest",
+		"chrislusf/seaweedfs:2.77":           "2.77",
+		"registry:5000/seaweedfs":            "latest",
+		"registry:5000/seaweedfs@sha256:abc": "sha256:abc",
+	} {
+		if got := seaweedv1.ImageVersion(image); got != want {
+			t.Errorf("ImageVersion(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
+
+func TestUpgradeComponents(t *testing.T) {
+	masterVersion := "2.76"
+	m := testSeaweed(3, 1)
+	m.Spec.Image = "chrislusf/seaweedfs:2.70"
+	m.Spec.Version = "2.77"
+	m.Spec.Master.Version = &masterVersion
+	m.Spec.VolumePools = []seaweedv1.VolumePoolSpec{{Name: "hot", VolumeSpec: seaweedv1.VolumeSpec{Replicas: 1}}}
+	m.Spec.Filer = &seaweedv1.FilerSpec{Replicas: 1}
+
+	var names, images []string
+	for _, c := range upgradeComponents(m) {
+		names = append(names, c.name)
+		images = append(images, c.image)
+	}
+	wantNames := []string{"master", "volume", "volume-hot", "filer"}
+	wantImages := []string{"chrislusf/seaweedfs:2.76", "chrislusf/seaweedfs:2.77", "chrislusf/seaweedfs:2.77", "chrislusf/seaweedfs:2.77"}
+	if !equalStrings(names, wantNames) || !equalStrings(images, wantImages) {
+		t.Errorf("components = %v %v, want %v %v", names, images, wantNames, wantImages)
+	}
+}
+
+func TestUpgradeHealth(t *testing.T) {
+	m := testSeaweed(3, 2)
+	m.Spec.Filer = &seaweedv1.FilerSpec{Replicas: 1}
+	// the filer waits for the masters and the volume servers
+	components := upgradeComponents(m)[:2]
+	now := metav1.Now()
+	stale := metav1.NewTime(now.Add(-5 * clusterHealthInterval))
+	healthy := &seaweedv1.ClusterHealth{LastProbeTime: &now, Leader: "sw-master-0.sw-master-peer.default:9333", RespondingMasters: 3}
+
+	for _, tc := range []struct {
+		name   string
+		health *seaweedv1.ClusterHealth
+		want   corev1.ConditionStatus
+	}{
+		{"healthy", healthy, corev1.ConditionTrue},
+		{"not probed", nil, corev1.ConditionUnknown},
+		{"stale", &seaweedv1.ClusterHealth{LastProbeTime: &stale, Leader: healthy.Leader, RespondingMasters: 3}, corev1.ConditionUnknown},
+		{"masters unreachable", &seaweedv1.ClusterHealth{LastProbeTime: &now, Message: "no master responded: i/o timeout"}, corev1.ConditionUnknown},
+		{"topology unavailable", &seaweedv1.ClusterHealth{LastProbeTime: &now, Leader: healthy.Leader, RespondingMasters: 3, Message: "can not read the topology"}, corev1.ConditionUnknown},
+		{"no leader", &seaweedv1.ClusterHealth{LastProbeTime: &now, RespondingMasters: 3, Message: "the masters have not elected a leader"}, corev1.ConditionFalse},
+		{"master down", &seaweedv1.ClusterHealth{LastProbeTime: &now, Leader: healthy.Leader, RespondingMasters: 2}, corev1.ConditionFalse},
+		{"volume server missing", &seaweedv1.ClusterHealth{LastProbeTime: &now, Leader: healthy.Leader, RespondingMasters: 3, MissingVolumeServers: []string{"sw-volume-1.sw-volume-peer.default:8444"}}, corev1.ConditionFalse},
+	} {
+		m.Status.Health = tc.health
+		if got, reason := upgradeHealth(m, components); got != tc.want {
+			t.Errorf("%s: %s (%s), want %s", tc.name, got, reason, tc.want)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/controllers/swadmin"
+)
+
+const (
+	defaultOperationTimeout = time.Hour
+
+	// maxOperationOutput is the size of the output tail kept in the status
+	maxOperationOutput = 16 * 1024
+)
+
+// SeaweedOperationReconciler reconciles a SeaweedOperation object
+type SeaweedOperationReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	Admins   *swadmin.Cache
+
+	// AdminTasks is shared with the Seaweed reconciler, it runs one operation or task of the operator
+	// at a time per Seaweed
+	AdminTasks *AdminTaskTracker
+
+	outputsLock sync.Mutex
+	outputs     map[string]*operationOutput
+}
+
+// +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=seaweedoperations,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=seaweedoperations/status,verbs=get;update;patch
+
+// Reconcile runs the commands of the operation in the background and polls them until they finish
+func (r *SeaweedOperationReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("seaweedoperation", req.NamespacedName)
+
+	op := &seaweedv1.SeaweedOperation{}
+	if err := r.Get(ctx, req.NamespacedName, op); err != nil {
+		if errors.IsNotFound(err) {
+			// a deleted operation would hold the cluster with its running attempt forever
+			r.AdminTasks.cancelMatching(req.Namespace, func(name string) bool {
+				return isTaskOfOperation(name, req.Name)
+			})
+			r.forgetOutputs(req.Name)
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	if op.Status.Phase == seaweedv1.SeaweedOperationSucceeded || op.Status.Phase == seaweedv1.SeaweedOperationFailed {
+		return ctrl.Result{}, nil
+	}
+
+	original := op.Status.DeepCopy()
+	result := r.reconcileOperation(op, log)
+	if !apiequality.Semantic.DeepEqual(original, &op.Status) {
+		if err := r.Status().Update(ctx, op); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	return result, nil
+}
+
+func (r *SeaweedOperationReconciler) reconcileOperation(op *seaweedv1.SeaweedOperation, log logr.Logger) ctrl.Result {
+	status := &op.Status
+	if status.Phase == "" {
+		status.Phase = seaweedv1.SeaweedOperationPending
+	}
+
+	seaweedCR := &seaweedv1.Seaweed{}
+	err := r.Get(context.Background(), types.NamespacedName{Namespace: op.Namespace, Name: op.Spec.SeaweedRef}, seaweedCR)
+	if err != nil {
+		status.Message = fmt.Sprintf("can not get Seaweed %s: %v", op.Spec.SeaweedRef, err)
+		return ctrl.Result{RequeueAfter: 10 * time.Second}
+	}
+	key := seaweedKey(seaweedCR)
+
+	timeout := defaultOperationTimeout
+	if op.Spec.TimeoutSeconds != nil {
+		timeout = time.Duration(*op.Spec.TimeoutSeconds) * time.Second
+	}
+
+	taskName := operationTaskName(op, status.Attempts)
+	task := r.AdminTasks.current(key)
+	switch {
+	case task != nil && task.name == taskName && status.Phase == seaweedv1.SeaweedOperationRunning:
+		finished, taskErr := task.finished()
+		if !finished {
+			return ctrl.Result{RequeueAfter: 5 * time.Second}
+		}
+
+		r.AdminTasks.clear(key, task)
+		status.Output = r.takeOutput(taskName)
+		if taskErr == context.DeadlineExceeded {
+			taskErr = fmt.Errorf("timed out after %v", timeout)
+		}
+		if taskErr != nil {
+			r.attemptFailed(op, taskErr, log)
+			return ctrl.Result{RequeueAfter: 10 * time.Second}
+		}
+
+		now := metav1.Now()
+		status.Phase = seaweedv1.SeaweedOperationSucceeded
+		status.CompletionTime = &now
+		status.Error = ""
+		status.Message = fmt.Sprintf("%d commands succeeded in attempt %d", len(op.Spec.Commands), status.Attempts)
+		log.Info("operation succeeded", "attempts", status.Attempts)
+		r.Recorder.Event(op, corev1.EventTypeNormal, "OperationSucceeded", status.Message)
+		return ctrl.Result{}
+
+	case task != nil:
+		status.Message = fmt.Sprintf("waiting for %s to finish on Seaweed %s", task.name, op.Spec.SeaweedRef)
+		return ctrl.Result{RequeueAfter: 10 * time.Second}
+
+	case status.Phase == seaweedv1.SeaweedOperationRunning:
+		// the operator restarted during the attempt
+		r.attemptFailed(op, fmt.Errorf("attempt %d was interrupted", status.Attempts), log)
+		return ctrl.Result{RequeueAfter: 10 * time.Second}
+	}
+
+	status.Attempts++
+	taskName = operationTaskName(op, status.Attempts)
+	output := r.newOutput(taskName)
+	sa := r.Admins.Get(key.String(), adminTarget(seaweedCR))
+	commands := op.Spec.Commands
+	if !r.AdminTasks.start(key, taskName, timeout, log, func(ctx context.Context) error {
+		return sa.WithLock(ctx, func(ctx context.Context) error {
+			for _, cmd := range commands {
+				fmt.Fprintf(output, "> %s\n", cmd)
+				if err := sa.ProcessCommand(ctx, cmd, output); err != nil {
+					if err == context.DeadlineExceeded {
+						return err
+					}
+					return fmt.Errorf("%s: %v", cmd, err)
+				}
+			}
+			return nil
+		})
+	}) {
+		status.Attempts--
+		r.takeOutput(taskName)
+		return ctrl.Result{RequeueAfter: 10 * time.Second}
+	}
+
+	if status.StartTime == nil {
+		now := metav1.Now()
+		status.StartTime = &now
+	}
+	status.Phase = seaweedv1.SeaweedOperationRunning
+	status.Message = fmt.Sprintf("attempt %d of %d is running", status.Attempts, op.Spec.Retries+1)
+	r.Recorder.Event(op, corev1.EventTypeNormal, "OperationStarted", status.Message)
+	return ctrl.Result{RequeueAfter: 5 * time.Second}
+}
+
+// attemptFailed records the error of the attempt, and fails the operation if no retries are left
+func (r *SeaweedOperationReconciler) attemptFailed(op *seaweedv1.SeaweedOperation, err error, log logr.Logger) {
+	status := &op.Status
+	status.Error = err.Error()
+	log.Info("operation attempt failed", "attempt", status.Attempts, "error", status.Error)
+
+	if status.Attempts <= op.Spec.Retries {
+		status.Phase = seaweedv1.SeaweedOperationPending
+		status.Message = fmt.Sprintf("attempt %d failed, retrying", status.Attempts)
+		r.Recorder.Eventf(op, corev1.EventTypeWarning, "OperationRetrying", "Attempt %d failed: %v", status.Attempts, err)
+		return
+	}
+
+	now := metav1.Now()
+	status.Phase = seaweedv1.SeaweedOperationFailed
+	status.CompletionTime = &now
+	status.Message = fmt.Sprintf("failed after %d attempts", status.Attempts)
+	r.Recorder.Eventf(op, corev1.EventTypeWarning, "OperationFailed", "Attempt %d failed: %v", status.Attempts, err)
+}
+
+// operationTaskPrefix starts the admin task names of SeaweedOperations
+const operationTaskPrefix = "SeaweedOperation "
+
+func operationTaskName(op *seaweedv1.SeaweedOperation, attempt int32) string {
+	return fmt.Sprintf("%s%s attempt %d", operationTaskPrefix, op.Name, attempt)
+}
+
+func isOperationTask(name string) bool {
+	return strings.HasPrefix(name, operationTaskPrefix)
+}
+
+// isTaskOfOperation reports whether the task runs an attempt of the named operation
+func isTaskOfOperation(taskName, opName string) bool {
+	return strings.HasPrefix(taskName, operationTaskPrefix+opName+" attempt ")
+}
+
+func (r *SeaweedOperationReconciler) newOutput(taskName string) *operationOutput {
+	r.outputsLock.Lock()
+	defer r.outputsLock.Unlock()
+	if r.outputs == nil {
+		r.outputs = make(map[string]*operationOutput)
+	}
+	output := &operationOutput{}
+	r.outputs[taskName] = output
+	return output
+}
+
+// takeOutput returns the output tail of the task and forgets it
+func (r *SeaweedOperationReconciler) takeOutput(taskName string) string {
+	r.outputsLock.Lock()
+	defer r.outputsLock.Unlock()
+	output, found := r.outputs[taskName]
+	if !found {
+		return ""
+	}
+	delete(r.outputs, taskName)
+	return output.String()
+}
+
+// forgetOutputs drops the output of all attempts of the named operation
+func (r *SeaweedOperationReconciler) forgetOutputs(opName string) {
+	r.outputsLock.Lock()
+	defer r.outputsLock.Unlock()
+	for taskName := range r.outputs {
+		if isTaskOfOperation(taskName, opName) {
+			delete(r.outputs, taskName)
+		}
+	}
+}
+
+// operationOutput collects the command output, it is written by the task and read by the reconciler
+type operationOutput struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (o *operationOutput) Write(p []byte) (int, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return o.buf.Write(p)
+}
+
+// String returns the tail of the output
+func (o *operationOutput) String() string {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	s := o.buf.String()
+	if len(s) <= maxOperationOutput {
+		return s
+	}
+	s = s[len(s)-maxOperationOutput:]
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[i+1:]
+	}
+	return "...\n" + s
+}
+
+func (r *SeaweedOperationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&seaweedv1.SeaweedOperation{}).
+		Complete(r)
+}
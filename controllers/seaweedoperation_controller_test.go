@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/controllers/swadmin"
+)
+
+// newOperationReconciler is a SeaweedOperation reconciler of the operation against the sw Seaweed, running admin
+func newOperationReconciler(op *seaweedv1.SeaweedOperation, admin swadmin.Admin) *SeaweedOperationReconciler {
+	r := newFakeReconciler(testSeaweed(1, 1), op)
+	return &SeaweedOperationReconciler{
+		Client:   r.Client,
+		Log:      r.Log,
+		Scheme:   r.Scheme,
+		Recorder: r.Recorder,
+		Admins: &swadmin.Cache{NewAdmin: func(target swadmin.Target, options swadmin.Options) swadmin.Admin {
+			return admin
+		}},
+		AdminTasks: r.AdminTasks,
+	}
+}
+
+func testOperation(commands ...string) *seaweedv1.SeaweedOperation {
+	return &seaweedv1.SeaweedOperation{
+		ObjectMeta: metav1.ObjectMeta{Name: "fix", Namespace: "default"},
+		Spec:       seaweedv1.SeaweedOperationSpec{SeaweedRef: "sw", Commands: commands},
+	}
+}
+
+// reconcileOperation reconciles the operation until it leaves the Running phase
+func reconcileOperation(t *testing.T, r *SeaweedOperationReconciler) *seaweedv1.SeaweedOperation {
+	t.Helper()
+	key := types.NamespacedName{Namespace: "default", Name: "fix"}
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if _, err := r.Reconcile(ctrl.Request{NamespacedName: key}); err != nil {
+			t.Fatalf("reconcile: %v", err)
+		}
+		op := &seaweedv1.SeaweedOperation{}
+		if err := r.Get(context.Background(), key, op); err != nil {
+			t.Fatalf("get operation: %v", err)
+		}
+		if op.Status.Phase != seaweedv1.SeaweedOperationRunning {
+			return op
+		}
+	}
+	t.Fatalf("operation still running")
+	return nil
+}
+
+func TestSeaweedOperationRetry(t *testing.T) {
+	op := testOperation("volume.fix.replication", "volume.vacuum")
+	op.Spec.Retries = 1
+	admin := swadmin.NewFakeAdmin("sw-master-0.sw-master-peer.default:9333")
+	admin.Errors = map[string]error{"volume.fix.replication": fmt.Errorf("no free volume slots")}
+	r := newOperationReconciler(op, admin)
+
+	op = reconcileOperation(t, r)
+	if op.Status.Phase != seaweedv1.SeaweedOperationPending || op.Status.Attempts != 1 {
+		t.Fatalf("after the first attempt: %+v", op.Status)
+	}
+	if op.Status.Error != "volume.fix.replication: no free volume slots" {
+		t.Errorf("error = %q", op.Status.Error)
+	}
+	if !strings.Contains(op.Status.Output, "> volume.fix.replication") || strings.Contains(op.Status.Output, "volume.vacuum") {
+		t.Errorf("output of the failed attempt = %q", op.Status.Output)
+	}
+
+	admin.Errors = nil
+	op = reconcileOperation(t, r)
+	if op.Status.Phase != seaweedv1.SeaweedOperationSucceeded || op.Status.Attempts != 2 || op.Status.Error != "" {
+		t.Errorf("after the retry: %+v", op.Status)
+	}
+	expected := []string{"lock", "volume.fix.replication", "unlock", "lock", "volume.fix.replication", "volume.vacuum", "unlock"}
+	if commands := admin.Commands(); !reflect.DeepEqual(commands, expected) {
+		t.Errorf("commands = %q, expected %q", commands, expected)
+	}
+	if task := r.AdminTasks.current(types.NamespacedName{Namespace: "default", Name: "sw"}); task != nil {
+		t.Errorf("task %s not cleared", task.name)
+	}
+}
+
+func TestSeaweedOperationTimeout(t *testing.T) {
+	op := testOperation("volume.fix.replication", "volume.vacuum")
+	timeout := int32(1)
+	op.Spec.TimeoutSeconds = &timeout
+	admin := swadmin.NewFakeAdmin("sw-master-0.sw-master-peer.default:9333")
+	admin.OnCommand = func(cmd string) {
+		if cmd == "volume.fix.replication" {
+			time.Sleep(1200 * time.Millisecond)
+		}
+	}
+	r := newOperationReconciler(op, admin)
+
+	op = reconcileOperation(t, r)
+	if op.Status.Phase != seaweedv1.SeaweedOperationFailed || op.Status.Attempts != 1 {
+		t.Errorf("after the timeout: %+v", op.Status)
+	}
+	if op.Status.Error != "timed out after 1s" {
+		t.Errorf("error = %q", op.Status.Error)
+	}
+}
+
+func TestSeaweedOperationInterrupted(t *testing.T) {
+	op := testOperation("volume.fix.replication")
+	op.Spec.Retries = 1
+	op.Status = seaweedv1.SeaweedOperationStatus{Phase: seaweedv1.SeaweedOperationRunning, Attempts: 1}
+	admin := swadmin.NewFakeAdmin("sw-master-0.sw-master-peer.default:9333")
+	r := newOperationReconciler(op, admin)
+
+	// the operator restarted, the attempt has no task
+	key := types.NamespacedName{Namespace: "default", Name: "fix"}
+	if _, err := r.Reconcile(ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if err := r.Get(context.Background(), key, op); err != nil {
+		t.Fatalf("get operation: %v", err)
+	}
+	if op.Status.Phase != seaweedv1.SeaweedOperationPending || op.Status.Error != "attempt 1 was interrupted" {
+		t.Errorf("after the restart: %+v", op.Status)
+	}
+
+	op = reconcileOperation(t, r)
+	if op.Status.Phase != seaweedv1.SeaweedOperationSucceeded || op.Status.Attempts != 2 {
+		t.Errorf("after the retry: %+v", op.Status)
+	}
+}
+
+func TestSeaweedOperationDeleted(t *testing.T) {
+	op := testOperation("volume.fix.replication")
+	admin := swadmin.NewFakeAdmin("sw-master-0.sw-master-peer.default:9333")
+	release := make(chan struct{})
+	defer close(release)
+	admin.OnCommand = func(cmd string) {
+		if cmd == "volume.fix.replication" {
+			<-release
+		}
+	}
+	r := newOperationReconciler(op, admin)
+
+	key := types.NamespacedName{Namespace: "default", Name: "fix"}
+	if _, err := r.Reconcile(ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	seaweed := types.NamespacedName{Namespace: "default", Name: "sw"}
+	if r.AdminTasks.current(seaweed) == nil {
+		t.Fatalf("operation did not start")
+	}
+
+	if err := r.Delete(context.Background(), op); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := r.Reconcile(ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if r.AdminTasks.current(seaweed) != nil {
+		t.Errorf("task of the deleted operation still holds the cluster")
+	}
+	if len(r.outputs) != 0 {
+		t.Errorf("outputs of the deleted operation kept: %v", r.outputs)
+	}
+}
+
+func TestValidateSeaweedOperation(t *testing.T) {
+	op := testOperation("volume.fix.replication", "ec.encode -fullPercent=95")
+	if err := op.ValidateCreate(); err != nil {
+		t.Errorf("valid operation: %v", err)
+	}
+
+	op = testOperation("volume.fix.replication\nunlock", "volume.vacuum; unlock")
+	err := op.ValidateCreate()
+	if err == nil || !strings.Contains(err.Error(), "spec.commands[0] has the control character U+000A") || !strings.Contains(err.Error(), "spec.commands[1] has a semi-colon") {
+		t.Errorf("injected commands: %v", err)
+	}
+	if err := op.ValidateUpdate(testOperation("volume.fix.replication")); err == nil {
+		t.Errorf("injected commands accepted on update")
+	}
+}
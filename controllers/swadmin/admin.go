@@ -0,0 +1,83 @@
+package swadmin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ErrClosed is returned by an Admin after Close
+var ErrClosed = errors.New("seaweed admin is closed")
+
+// Admin runs weed shell commands against the masters of a cluster
+type Admin interface {
+	// Masters returns the master addresses the admin connects to
+	Masters() string
+
+	// ProcessCommand runs one weed shell command and writes its output to output.
+	// Commands failing CheckCommand are refused.
+	ProcessCommand(ctx context.Context, cmd string, output io.Writer) error
+
+	// ProcessCommands runs semi-colon separated commands, stopping at the first error
+	ProcessCommands(ctx context.Context, cmds string, output io.Writer) error
+
+	// WithLock runs fn while holding the exclusive shell lock of the cluster
+	WithLock(ctx context.Context, fn func(ctx context.Context) error) error
+
+	// VolumeList fetches the data centers, racks, volume servers and their volumes from the master leader
+	VolumeList(ctx context.Context) (*Topology, error)
+
+	// ClusterStatus reads the raft state of every master of Masters, in the same order.
+	// The error of a master is set when it does not respond.
+	ClusterStatus(ctx context.Context) ([]*ClusterStatus, []error)
+
+	// Close releases the admin, later calls return ErrClosed
+	Close() error
+}
+
+// Target locates the masters of a cluster
+type Target struct {
+	// Masters are the comma separated host:port addresses of the masters
+	Masters string
+
+	// Namespace, Pods and Container locate the master pods used by ExecAdmin
+	Namespace string
+	Pods      []string
+	Container string
+}
+
+// Options are the timeouts of an Admin
+type Options struct {
+	// ConnectTimeout bounds the wait for the master leader, 10s if zero
+	ConnectTimeout time.Duration
+
+	// CommandTimeout bounds every command on top of the context deadline, unlimited if zero
+	CommandTimeout time.Duration
+}
+
+const defaultConnectTimeout = 10 * time.Second
+
+func (o Options) connectTimeout() time.Duration {
+	if o.ConnectTimeout <= 0 {
+		return defaultConnectTimeout
+	}
+	return o.ConnectTimeout
+}
+
+// CheckCommand refuses a command which weed shell would take for several commands,
+// a newline or any other control character, or a semi-colon
+func CheckCommand(cmd string) error {
+	if strings.ContainsRune(cmd, ';') {
+		return fmt.Errorf("command %q has a semi-colon, run one command at a time", cmd)
+	}
+	for _, c := range cmd {
+		if unicode.IsControl(c) {
+			return fmt.Errorf("command %q has the control character %U", cmd, c)
+		}
+	}
+	return nil
+}
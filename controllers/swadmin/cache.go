@@ -0,0 +1,184 @@
+package swadmin
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/client-go/rest"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Transport selects how an Admin reaches the masters
+type Transport string
+
+const (
+	// TransportAuto uses gRPC if the masters are reachable, pods/exec otherwise
+	TransportAuto Transport = "auto"
+	// TransportGRPC connects to the masters directly
+	TransportGRPC Transport = "grpc"
+	// TransportExec runs weed shell in a master pod
+	TransportExec Transport = "exec"
+)
+
+const (
+	masterGRPCPortDelta = 10000
+	reachableTimeout    = 3 * time.Second
+
+	// transportRecheckInterval is how long TransportAuto keeps its choice before probing the masters again
+	transportRecheckInterval = time.Minute
+)
+
+// Cache keeps one Admin per Seaweed custom resource
+type Cache struct {
+	// Options of the admins created by the cache
+	Options Options
+
+	// Transport of the admins, TransportAuto if empty
+	Transport Transport
+
+	// RestConfig is needed by the pods/exec transport, admins always use gRPC without it
+	RestConfig *rest.Config
+
+	// NewAdmin creates the admin of the target instead of the transports.
+	// Tests replace it to return a FakeAdmin.
+	NewAdmin func(target Target, options Options) Admin
+
+	// Log receives the transport choices of the cache
+	Log logr.Logger
+
+	lock   sync.Mutex
+	admins map[string]*cachedAdmin
+}
+
+// cachedAdmin is an admin with the transport the cache chose for it
+type cachedAdmin struct {
+	admin   Admin
+	exec    bool
+	checked time.Time
+}
+
+// NewCache returns a cache of admins which may exec into pods with the given config
+func NewCache(config *rest.Config, transport Transport) *Cache {
+	return &Cache{
+		RestConfig: config,
+		Transport:  transport,
+		Log:        logf.Log.WithName("swadmin"),
+	}
+}
+
+// Get returns the admin of the custom resource identified by key,
+// replacing the cached one if the master addresses changed or TransportAuto chooses the other transport.
+// The masters are probed without holding the cache lock.
+func (c *Cache) Get(key string, target Target) Admin {
+	c.lock.Lock()
+	cached, found := c.admins[key]
+	if found && cached.admin.Masters() == target.Masters && !c.expired(cached) {
+		c.lock.Unlock()
+		return cached.admin
+	}
+	c.lock.Unlock()
+
+	useExec := c.useExec(target)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.admins == nil {
+		c.admins = make(map[string]*cachedAdmin)
+	}
+	if current, found := c.admins[key]; found {
+		if current.admin.Masters() == target.Masters && (current != cached || current.exec == useExec) {
+			// still the right transport, or another caller replaced the admin meanwhile
+			if current == cached {
+				current.checked = time.Now()
+			}
+			return current.admin
+		}
+		current.admin.Close()
+	}
+
+	created := c.newAdmin(target, useExec)
+	c.admins[key] = created
+	return created.admin
+}
+
+// expired reports whether TransportAuto probes the masters again for the cached admin
+func (c *Cache) expired(cached *cachedAdmin) bool {
+	if c.NewAdmin != nil || c.RestConfig == nil || (c.Transport != "" && c.Transport != TransportAuto) {
+		return false
+	}
+	return time.Since(cached.checked) > transportRecheckInterval
+}
+
+// useExec chooses the transport, probing the masters for TransportAuto
+func (c *Cache) useExec(target Target) bool {
+	if c.NewAdmin != nil {
+		return false
+	}
+	switch c.Transport {
+	case TransportExec:
+		return c.RestConfig != nil
+	case TransportGRPC:
+		return false
+	default:
+		return c.RestConfig != nil && !mastersReachable(target.Masters)
+	}
+}
+
+func (c *Cache) newAdmin(target Target, useExec bool) *cachedAdmin {
+	now := time.Now()
+	if c.NewAdmin != nil {
+		return &cachedAdmin{admin: c.NewAdmin(target, c.Options), checked: now}
+	}
+
+	if useExec {
+		admin, err := NewExecAdmin(c.RestConfig, target, c.Options)
+		if err == nil {
+			c.log().Info("admin execs into the master pods", "masters", target.Masters)
+			return &cachedAdmin{admin: admin, exec: true, checked: now}
+		}
+		c.log().Error(err, "can not exec into the master pods, falling back to gRPC", "masters", target.Masters)
+	}
+	return &cachedAdmin{admin: NewSeaweedAdmin(target.Masters, c.Options), checked: now}
+}
+
+func (c *Cache) log() logr.Logger {
+	if c.Log == nil {
+		return logf.Log.WithName("swadmin")
+	}
+	return c.Log
+}
+
+// Remove closes and forgets the admin of the custom resource identified by key
+func (c *Cache) Remove(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if cached, found := c.admins[key]; found {
+		cached.admin.Close()
+		delete(c.admins, key)
+	}
+}
+
+// mastersReachable reports whether the gRPC port of any master accepts connections
+func mastersReachable(masters string) bool {
+	for _, master := range strings.Split(masters, ",") {
+		host, port, err := net.SplitHostPort(strings.TrimSpace(master))
+		if err != nil {
+			continue
+		}
+		httpPort, err := strconv.Atoi(port)
+		if err != nil {
+			continue
+		}
+		address := net.JoinHostPort(host, strconv.Itoa(httpPort+masterGRPCPortDelta))
+		if conn, err := net.DialTimeout("tcp", address, reachableTimeout); err == nil {
+			conn.Close()
+			return true
+		}
+	}
+	return false
+}
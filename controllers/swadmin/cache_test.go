@@ -0,0 +1,55 @@
+package swadmin
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/rest"
+)
+
+func TestCacheTransportRecheck(t *testing.T) {
+	// the master HTTP port is the gRPC port of the listener minus the port delta
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	grpcPort := listener.Addr().(*net.TCPAddr).Port
+	if grpcPort <= masterGRPCPortDelta {
+		t.Skipf("listener port %d has no master HTTP port", grpcPort)
+	}
+	target := Target{
+		Masters:   "127.0.0.1:" + strconv.Itoa(grpcPort-masterGRPCPortDelta),
+		Namespace: "default",
+		Pods:      []string{"sw-master-0"},
+	}
+
+	cache := NewCache(&rest.Config{Host: "127.0.0.1:1"}, TransportAuto)
+	admin := cache.Get("default/sw", target)
+	defer cache.Remove("default/sw")
+	if _, ok := admin.(*SeaweedAdmin); !ok {
+		t.Fatalf("admin of reachable masters is %T, want gRPC", admin)
+	}
+	if cache.Get("default/sw", target) != admin {
+		t.Errorf("admin is not cached")
+	}
+
+	// the masters become unreachable, the choice is kept until it expires
+	listener.Close()
+	if cache.Get("default/sw", target) != admin {
+		t.Errorf("transport is probed again before it expired")
+	}
+	cache.admins["default/sw"].checked = time.Now().Add(-2 * transportRecheckInterval)
+	execAdmin, ok := cache.Get("default/sw", target).(*ExecAdmin)
+	if !ok {
+		t.Fatalf("admin of unreachable masters is not ExecAdmin")
+	}
+	if admin.(*SeaweedAdmin).err(nil) != ErrClosed {
+		t.Errorf("replaced gRPC admin is not closed")
+	}
+	if cache.Get("default/sw", target) != execAdmin {
+		t.Errorf("exec admin is not cached")
+	}
+}
@@ -0,0 +1,465 @@
+package swadmin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+type sessionKey struct{}
+
+// ExecAdmin is the Admin which runs weed shell in a master pod through pods/exec.
+// It only needs the Kubernetes API, so it works when the operator can not reach the masters directly.
+//
+// Every command runs in its own weed shell session, except inside WithLock: the section keeps one session open,
+// which takes the shell lock once and runs all commands of the section.
+type ExecAdmin struct {
+	target  Target
+	options Options
+	config  *rest.Config
+	client  rest.Interface
+
+	commandSem chan struct{}
+	lockSem    chan struct{}
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+var _ Admin = &ExecAdmin{}
+
+// NewExecAdmin returns an admin which execs into the master pods of the target
+func NewExecAdmin(config *rest.Config, target Target, options Options) (*ExecAdmin, error) {
+	client, err := corev1client.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &ExecAdmin{
+		target:     target,
+		options:    options,
+		config:     config,
+		client:     client.RESTClient(),
+		commandSem: make(chan struct{}, 1),
+		lockSem:    make(chan struct{}, 1),
+		closed:     make(chan struct{}),
+	}, nil
+}
+
+func (ea *ExecAdmin) Masters() string {
+	return ea.target.Masters
+}
+
+func (ea *ExecAdmin) Close() error {
+	ea.closeOnce.Do(func() {
+		close(ea.closed)
+	})
+	return nil
+}
+
+func (ea *ExecAdmin) err(ctx context.Context) error {
+	select {
+	case <-ea.closed:
+		return ErrClosed
+	default:
+	}
+	return ctx.Err()
+}
+
+func (ea *ExecAdmin) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	var cancel context.CancelFunc
+	if ea.options.CommandTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, ea.options.CommandTimeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	go func() {
+		select {
+		case <-ea.closed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// VolumeList runs volume.list and parses its output
+func (ea *ExecAdmin) VolumeList(ctx context.Context) (*Topology, error) {
+	var output bytes.Buffer
+	if err := ea.ProcessCommand(ctx, "volume.list", &output); err != nil {
+		return nil, err
+	}
+	return ParseVolumeList(output.String())
+}
+
+func (ea *ExecAdmin) WithLock(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, cancel := ea.context(ctx)
+	defer cancel()
+
+	select {
+	case ea.lockSem <- struct{}{}:
+	case <-ctx.Done():
+		return ea.err(ctx)
+	}
+
+	session, err := ea.openSession(ctx)
+	if err != nil {
+		<-ea.lockSem
+		return err
+	}
+	// unlock after an abandoned command has returned, without keeping the caller waiting
+	defer func() {
+		go func() {
+			defer func() { <-ea.lockSem }()
+			session.close()
+		}()
+	}()
+	return fn(context.WithValue(ctx, sessionKey{}, session))
+}
+
+// openSession starts weed shell in the first master pod which runs it, and takes the shell lock
+func (ea *ExecAdmin) openSession(ctx context.Context) (*execSession, error) {
+	var lastErr error
+	for _, pod := range ea.target.Pods {
+		executor, err := ea.executor(pod, []string{"/bin/sh", "-c", "exec weed shell -master=" + ea.target.Masters + " 2>&1"}, false)
+		if err != nil {
+			return nil, err
+		}
+		session := newExecSession(func(stdin io.Reader, stdout io.Writer) error {
+			return executor.Stream(remotecommand.StreamOptions{Stdin: stdin, Stdout: stdout})
+		})
+		if err := session.run(ctx, "lock", ioutil.Discard); err != nil {
+			go session.close()
+			if session.started || ctx.Err() != nil {
+				return nil, ea.sessionErr(ctx, err)
+			}
+			lastErr = fmt.Errorf("exec weed shell in pod %s: %v", pod, err)
+			continue
+		}
+		return session, nil
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no master pod to exec into")
+	}
+	return nil, lastErr
+}
+
+// sessionErr is the error of a session command, ErrClosed if the admin was closed meanwhile
+func (ea *ExecAdmin) sessionErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ea.err(ctx)
+	}
+	return err
+}
+
+// ProcessCommands cmds can be semi-colon separated commands
+func (ea *ExecAdmin) ProcessCommands(ctx context.Context, cmds string, output io.Writer) error {
+	for _, c := range strings.Split(cmds, ";") {
+		if err := ea.ProcessCommand(ctx, c, output); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ea *ExecAdmin) ProcessCommand(ctx context.Context, cmd string, output io.Writer) error {
+	if err := CheckCommand(cmd); err != nil {
+		return err
+	}
+	cmd = strings.TrimSpace(cmd)
+	if cmd == "" || cmd == "lock" || cmd == "unlock" {
+		// the lock does not outlive a session, the session of WithLock holds it
+		return nil
+	}
+
+	ctx, cancel := ea.context(ctx)
+	defer cancel()
+
+	if session, _ := ctx.Value(sessionKey{}).(*execSession); session != nil {
+		return ea.sessionErr(ctx, session.run(ctx, cmd, output))
+	}
+	script := cmd + "\nexit\n"
+
+	select {
+	case ea.commandSem <- struct{}{}:
+	case <-ctx.Done():
+		return ea.err(ctx)
+	}
+	// an abandoned command keeps the command slot until its shell exited, without keeping the caller waiting
+	var streams sync.WaitGroup
+	defer func() {
+		go func() {
+			streams.Wait()
+			<-ea.commandSem
+		}()
+	}()
+
+	var lastErr error
+	for _, pod := range ea.target.Pods {
+		started, err := ea.exec(ctx, pod, script, output, &streams)
+		if err == nil || started {
+			return err
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no master pod to exec into")
+	}
+	return lastErr
+}
+
+// exec runs the script in a weed shell of the pod, started reports whether the shell ran at all
+func (ea *ExecAdmin) exec(ctx context.Context, pod, script string, output io.Writer, streams *sync.WaitGroup) (started bool, err error) {
+	executor, err := ea.executor(pod, []string{"weed", "shell", "-master=" + ea.target.Masters}, true)
+	if err != nil {
+		return false, err
+	}
+
+	stdout, stderr, err := stream(ctx, executor, script, streams)
+	if ctx.Err() != nil {
+		return true, ea.err(ctx)
+	}
+	if err != nil {
+		return stdout != "", fmt.Errorf("exec weed shell in pod %s: %v", pod, err)
+	}
+
+	if _, err := io.WriteString(output, cleanShellOutput(stdout)); err != nil {
+		return true, err
+	}
+	return true, shellError(stderr)
+}
+
+// ClusterStatus fetches /cluster/status of every master with wget in the first master pod which runs it
+func (ea *ExecAdmin) ClusterStatus(ctx context.Context) ([]*ClusterStatus, []error) {
+	ctx, cancel := ea.context(ctx)
+	defer cancel()
+
+	masters := strings.Split(ea.target.Masters, ",")
+	// one line per master, the response or the error of wget
+	var script strings.Builder
+	for _, master := range masters {
+		fmt.Fprintf(&script, "wget -q -T %d -O - http://%s/cluster/status 2>&1 | tr -d '\\n'; echo\n", clusterStatusTimeoutSeconds, master)
+	}
+
+	err := fmt.Errorf("no master pod to exec into")
+	for _, pod := range ea.target.Pods {
+		var executor remotecommand.Executor
+		executor, err = ea.executor(pod, []string{"/bin/sh"}, true)
+		if err != nil {
+			break
+		}
+		var stdout string
+		stdout, _, err = stream(ctx, executor, script.String(), nil)
+		if ctx.Err() != nil {
+			err = ea.err(ctx)
+			break
+		}
+		if err == nil {
+			return parseClusterStatuses(stdout, masters)
+		}
+		err = fmt.Errorf("exec in pod %s: %v", pod, err)
+	}
+
+	errs := make([]error, len(masters))
+	for i := range errs {
+		errs[i] = err
+	}
+	return make([]*ClusterStatus, len(masters)), errs
+}
+
+// clusterStatusTimeoutSeconds bounds the wget of one master in ExecAdmin.ClusterStatus
+const clusterStatusTimeoutSeconds = 3
+
+// stream runs the executor with stdin and returns early when ctx is done.
+// The executor is added to running, if not nil, until it returned.
+func stream(ctx context.Context, executor remotecommand.Executor, stdin string, running *sync.WaitGroup) (stdout, stderr string, err error) {
+	stdoutBuf := &bytes.Buffer{}
+	stderrBuf := &bytes.Buffer{}
+	result := make(chan error, 1)
+	if running != nil {
+		running.Add(1)
+	}
+	go func() {
+		if running != nil {
+			defer running.Done()
+		}
+		// remotecommand has no cancellation, an abandoned stream runs until the command exits
+		result <- executor.Stream(remotecommand.StreamOptions{
+			Stdin:  strings.NewReader(stdin),
+			Stdout: stdoutBuf,
+			Stderr: stderrBuf,
+		})
+	}()
+
+	select {
+	case err = <-result:
+		return stdoutBuf.String(), stderrBuf.String(), err
+	case <-ctx.Done():
+		return "", "", ctx.Err()
+	}
+}
+
+// executor runs the command in the master container of the pod, with stdin, stdout and optionally stderr
+func (ea *ExecAdmin) executor(pod string, command []string, stderr bool) (remotecommand.Executor, error) {
+	req := ea.client.Post().
+		Resource("pods").
+		Namespace(ea.target.Namespace).
+		Name(pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: ea.target.Container,
+			Command:   command,
+			Stdin:     true,
+			Stdout:    true,
+			Stderr:    stderr,
+		}, scheme.ParameterCodec)
+
+	return remotecommand.NewSPDYExecutor(ea.config, "POST", req.URL())
+}
+
+// execSession is an interactive weed shell with stderr redirected to stdout.
+// Every command is followed by an unknown command as a marker, whose error ends the output of the command.
+type execSession struct {
+	stdin *io.PipeWriter
+	lines chan string
+
+	// done is closed when the stream ended with streamErr
+	done      chan struct{}
+	streamErr error
+
+	lock    sync.Mutex
+	markers int
+	// started reports whether the shell printed anything, err breaks the session after an abandoned command
+	started bool
+	err     error
+}
+
+// newExecSession runs the stream of a shell in the background
+func newExecSession(stream func(stdin io.Reader, stdout io.Writer) error) *execSession {
+	stdinReader, stdin := io.Pipe()
+	stdoutReader, stdout := io.Pipe()
+	s := &execSession{
+		stdin: stdin,
+		lines: make(chan string, 100),
+		done:  make(chan struct{}),
+	}
+	go func() {
+		// remotecommand has no cancellation, an abandoned stream runs until the shell exits
+		s.streamErr = stream(stdinReader, stdout)
+		stdinReader.Close()
+		stdout.Close()
+		close(s.done)
+	}()
+	go func() {
+		defer close(s.lines)
+		scanner := bufio.NewScanner(stdoutReader)
+		for scanner.Scan() {
+			s.lines <- scanner.Text()
+		}
+		// unblock the stream if the output has a line too long for the scanner
+		stdoutReader.Close()
+	}()
+	return s
+}
+
+// run sends the command to the shell and copies its output until the marker
+func (s *execSession) run(ctx context.Context, cmd string, output io.Writer) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+
+	s.markers++
+	marker := fmt.Sprintf("swadmin-end-%d", s.markers)
+	if _, err := io.WriteString(s.stdin, cmd+"\n"+marker+"\n"); err != nil {
+		// stdin is closed once the stream ended
+		<-s.done
+		s.err = fmt.Errorf("weed shell session ended: %v", s.streamErr)
+		return s.err
+	}
+
+	var errs []string
+	for {
+		select {
+		case line, ok := <-s.lines:
+			if !ok {
+				<-s.done
+				s.err = fmt.Errorf("weed shell session ended: %v", s.streamErr)
+				return s.err
+			}
+			s.started = true
+			for strings.HasPrefix(line, "> ") {
+				line = line[2:]
+			}
+			switch {
+			case line == "unknown command: "+marker:
+				return shellError(strings.Join(errs, "\n"))
+			case strings.HasPrefix(line, "error: ") || strings.HasPrefix(line, "unknown command: "):
+				errs = append(errs, line)
+			case line != "":
+				if _, err := io.WriteString(output, line+"\n"); err != nil {
+					return err
+				}
+			}
+		case <-ctx.Done():
+			// the output of the abandoned command would be taken for the output of the next one
+			s.err = fmt.Errorf("weed shell session abandoned while running %s: %v", cmd, ctx.Err())
+			return s.err
+		}
+	}
+}
+
+// close releases the shell lock and ends the shell once the running command returned
+func (s *execSession) close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	io.WriteString(s.stdin, "unlock\nexit\n")
+	s.stdin.Close()
+	for range s.lines {
+	}
+	<-s.done
+}
+
+// cleanShellOutput drops the banner before the first prompt and the prompts of an interactive weed shell
+func cleanShellOutput(out string) string {
+	if i := strings.Index(out, "> "); i >= 0 {
+		out = out[i:]
+	}
+	var lines []string
+	for _, line := range strings.SplitAfter(out, "\n") {
+		for strings.HasPrefix(line, "> ") {
+			line = line[2:]
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// shellError extracts the errors weed shell printed on stderr
+func shellError(stderr string) error {
+	var errs []string
+	for _, line := range strings.Split(stderr, "\n") {
+		if strings.HasPrefix(line, "error: ") || strings.HasPrefix(line, "unknown command: ") {
+			errs = append(errs, strings.TrimPrefix(line, "error: "))
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(errs, "; "))
+}
@@ -0,0 +1,148 @@
+package swadmin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// fakeShell answers the lines of stdin like weed shell with stderr redirected to stdout
+func fakeShell(commands *[]string) func(stdin io.Reader, stdout io.Writer) error {
+	return func(stdin io.Reader, stdout io.Writer) error {
+		fmt.Fprintln(stdout, "master: sw-master-0.sw-master-peer.default:9333 ")
+		scanner := bufio.NewScanner(stdin)
+		for fmt.Fprint(stdout, "> "); scanner.Scan(); fmt.Fprint(stdout, "> ") {
+			cmd := scanner.Text()
+			switch {
+			case cmd == "exit":
+				return nil
+			case cmd == "volume.list":
+				fmt.Fprint(stdout, "Topology volumeSizeLimit:30000 MB hdd(volume:1/8 active:1 free:7 remote:0)\n")
+			case cmd == "sleep":
+				time.Sleep(time.Second)
+			case cmd == "fail":
+				fmt.Fprint(stdout, "error: failed\n")
+			case strings.HasPrefix(cmd, "swadmin-end-"):
+				fmt.Fprintf(stdout, "unknown command: %s\n", cmd)
+				continue
+			}
+			*commands = append(*commands, cmd)
+		}
+		return scanner.Err()
+	}
+}
+
+func TestExecSession(t *testing.T) {
+	var commands []string
+	session := newExecSession(fakeShell(&commands))
+
+	if err := session.run(context.Background(), "lock", ioutil.Discard); err != nil || !session.started {
+		t.Fatalf("lock: %v", err)
+	}
+	var output bytes.Buffer
+	if err := session.run(context.Background(), "volume.list", &output); err != nil {
+		t.Fatalf("volume.list: %v", err)
+	}
+	if output.String() != "Topology volumeSizeLimit:30000 MB hdd(volume:1/8 active:1 free:7 remote:0)\n" {
+		t.Errorf("volume.list output = %q", output.String())
+	}
+	if err := session.run(context.Background(), "fail", ioutil.Discard); err == nil || err.Error() != "failed" {
+		t.Errorf("fail: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := session.run(ctx, "sleep", ioutil.Discard); err == nil {
+		t.Errorf("sleep is not abandoned")
+	}
+	if err := session.run(context.Background(), "volume.list", ioutil.Discard); err == nil {
+		t.Errorf("session runs commands after an abandoned one")
+	}
+
+	session.close()
+	if want := []string{"lock", "volume.list", "fail", "sleep", "unlock"}; !reflect.DeepEqual(commands, want) {
+		t.Errorf("commands = %v, want %v", commands, want)
+	}
+}
+
+func TestExecSessionNotStarted(t *testing.T) {
+	session := newExecSession(func(stdin io.Reader, stdout io.Writer) error {
+		return fmt.Errorf("pod not found")
+	})
+	err := session.run(context.Background(), "lock", ioutil.Discard)
+	if err == nil || session.started || !strings.Contains(err.Error(), "pod not found") {
+		t.Errorf("run = %v, started = %v", err, session.started)
+	}
+	session.close()
+}
+
+// blockingExecutor streams until release is closed
+type blockingExecutor struct {
+	release chan struct{}
+}
+
+func (e blockingExecutor) Stream(options remotecommand.StreamOptions) error {
+	<-e.release
+	return nil
+}
+
+func TestStreamAbandoned(t *testing.T) {
+	executor := blockingExecutor{release: make(chan struct{})}
+	var running sync.WaitGroup
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, _, err := stream(ctx, executor, "volume.list\nexit\n", &running); err != context.DeadlineExceeded {
+		t.Fatalf("abandoned stream = %v, want context.DeadlineExceeded", err)
+	}
+
+	returned := make(chan struct{})
+	go func() {
+		running.Wait()
+		close(returned)
+	}()
+	select {
+	case <-returned:
+		t.Fatalf("abandoned stream is not counted as running")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(executor.release)
+	select {
+	case <-returned:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("stream still counted as running after it returned")
+	}
+}
+
+func TestCheckCommand(t *testing.T) {
+	for cmd, valid := range map[string]bool{
+		"volume.fix.replication":                 true,
+		"ec.encode -fullPercent=95 -quietFor=1h": true,
+		"fs.configure -locationPrefix=/buckets/": true,
+		"volume.vacuum\nunlock":                  false,
+		"volume.vacuum\runlock":                  false,
+		"volume.vacuum; unlock":                  false,
+		"volume.list\x00":                        false,
+	} {
+		if err := CheckCommand(cmd); (err == nil) != valid {
+			t.Errorf("CheckCommand(%q) = %v", cmd, err)
+		}
+	}
+
+	admin := NewFakeAdmin("sw-master-0.sw-master-peer.default:9333")
+	if err := admin.ProcessCommand(context.Background(), "volume.vacuum\nunlock", ioutil.Discard); err == nil {
+		t.Errorf("command with a newline is run")
+	}
+	if commands := admin.Commands(); len(commands) != 0 {
+		t.Errorf("commands = %q", commands)
+	}
+}
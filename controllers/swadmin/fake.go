@@ -0,0 +1,150 @@
+package swadmin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+// FakeAdmin is an Admin for tests, it records the commands instead of running them
+type FakeAdmin struct {
+	MastersAddress string
+
+	// Topology is returned by VolumeList
+	Topology *Topology
+
+	// ClusterStatuses are returned by ClusterStatus per master address, the other masters do not respond
+	ClusterStatuses map[string]*ClusterStatus
+
+	// Outputs and Errors are returned for commands with a matching prefix
+	Outputs map[string]string
+	Errors  map[string]error
+
+	// OnCommand is called for every command, e.g. to update Topology
+	OnCommand func(cmd string)
+
+	lock     sync.Mutex
+	commands []string
+	closed   bool
+}
+
+var _ Admin = &FakeAdmin{}
+
+// NewFakeAdmin returns a FakeAdmin of the masters with an empty topology
+func NewFakeAdmin(masters string) *FakeAdmin {
+	return &FakeAdmin{
+		MastersAddress: masters,
+		Topology:       &Topology{},
+	}
+}
+
+// Commands returns the commands run so far
+func (f *FakeAdmin) Commands() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]string{}, f.commands...)
+}
+
+// Closed reports whether Close was called
+func (f *FakeAdmin) Closed() bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.closed
+}
+
+func (f *FakeAdmin) Masters() string {
+	return f.MastersAddress
+}
+
+func (f *FakeAdmin) ProcessCommand(ctx context.Context, cmd string, output io.Writer) error {
+	if err := CheckCommand(cmd); err != nil {
+		return err
+	}
+	cmd = strings.TrimSpace(cmd)
+	if cmd == "" {
+		return nil
+	}
+
+	f.lock.Lock()
+	if f.closed {
+		f.lock.Unlock()
+		return ErrClosed
+	}
+	f.commands = append(f.commands, cmd)
+	onCommand := f.OnCommand
+	f.lock.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if onCommand != nil {
+		onCommand(cmd)
+	}
+	for prefix, out := range f.Outputs {
+		if strings.HasPrefix(cmd, prefix) {
+			fmt.Fprint(output, out)
+		}
+	}
+	for prefix, err := range f.Errors {
+		if strings.HasPrefix(cmd, prefix) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *FakeAdmin) ProcessCommands(ctx context.Context, cmds string, output io.Writer) error {
+	for _, c := range strings.Split(cmds, ";") {
+		if err := f.ProcessCommand(ctx, c, output); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *FakeAdmin) WithLock(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := f.ProcessCommand(ctx, "lock", ioutil.Discard); err != nil {
+		return err
+	}
+	defer f.ProcessCommand(context.Background(), "unlock", ioutil.Discard)
+	return fn(ctx)
+}
+
+func (f *FakeAdmin) VolumeList(ctx context.Context) (*Topology, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.closed {
+		return nil, ErrClosed
+	}
+	return f.Topology, ctx.Err()
+}
+
+func (f *FakeAdmin) ClusterStatus(ctx context.Context) ([]*ClusterStatus, []error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	masters := strings.Split(f.MastersAddress, ",")
+	statuses := make([]*ClusterStatus, len(masters))
+	errs := make([]error, len(masters))
+	for i, master := range masters {
+		switch status, found := f.ClusterStatuses[master]; {
+		case f.closed:
+			errs[i] = ErrClosed
+		case !found:
+			errs[i] = fmt.Errorf("%s/cluster/status: connection refused", master)
+		default:
+			statuses[i] = status
+		}
+	}
+	return statuses, errs
+}
+
+func (f *FakeAdmin) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.closed = true
+	return nil
+}
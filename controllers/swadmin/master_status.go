@@ -0,0 +1,83 @@
+package swadmin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ClusterStatus is the raft state a master reports on /cluster/status
+type ClusterStatus struct {
+	IsLeader    bool     `json:"IsLeader,omitempty"`
+	Leader      string   `json:"Leader,omitempty"`
+	Peers       []string `json:"Peers,omitempty"`
+	MaxVolumeID uint32   `json:"MaxVolumeId,omitempty"`
+}
+
+// FetchClusterStatus reads /cluster/status from the master at the host:port address
+func FetchClusterStatus(ctx context.Context, master string) (*ClusterStatus, error) {
+	status := &ClusterStatus{}
+	if err := getMasterJSON(ctx, master, "/cluster/status", status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+func getMasterJSON(ctx context.Context, master, path string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s%s", master, path), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s%s: %s", master, path, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("%s%s: %v", master, path, err)
+	}
+	return nil
+}
+
+// fetchClusterStatuses calls fetch for every master in parallel
+func fetchClusterStatuses(ctx context.Context, masters []string, fetch func(ctx context.Context, master string) (*ClusterStatus, error)) ([]*ClusterStatus, []error) {
+	statuses := make([]*ClusterStatus, len(masters))
+	errs := make([]error, len(masters))
+	var wg sync.WaitGroup
+	for i := range masters {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			statuses[i], errs[i] = fetch(ctx, masters[i])
+		}(i)
+	}
+	wg.Wait()
+	return statuses, errs
+}
+
+// parseClusterStatuses parses one /cluster/status response per line, in the order of the masters
+func parseClusterStatuses(output string, masters []string) ([]*ClusterStatus, []error) {
+	lines := strings.Split(output, "\n")
+	statuses := make([]*ClusterStatus, len(masters))
+	errs := make([]error, len(masters))
+	for i, master := range masters {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) == "" {
+			errs[i] = fmt.Errorf("%s/cluster/status: no response", master)
+			continue
+		}
+		status := &ClusterStatus{}
+		if err := json.Unmarshal([]byte(lines[i]), status); err != nil {
+			errs[i] = fmt.Errorf("%s/cluster/status: %s", master, strings.TrimSpace(lines[i]))
+			continue
+		}
+		statuses[i] = status
+	}
+	return statuses, errs
+}
@@ -0,0 +1,48 @@
+package swadmin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFetchClusterStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cluster/status":
+			fmt.Fprint(w, `{"IsLeader":true,"Leader":"sw-master-0.sw-master-peer.default:9333","Peers":["sw-master-1.sw-master-peer.default:9333"],"MaxVolumeId":7}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	master := strings.TrimPrefix(server.URL, "http://")
+
+	clusterStatus, err := FetchClusterStatus(context.Background(), master)
+	if err != nil {
+		t.Fatalf("cluster status: %v", err)
+	}
+	if !clusterStatus.IsLeader || clusterStatus.MaxVolumeID != 7 || len(clusterStatus.Peers) != 1 {
+		t.Errorf("cluster status = %+v", clusterStatus)
+	}
+}
+
+func TestParseClusterStatuses(t *testing.T) {
+	masters := []string{"sw-master-0.sw-master-peer.default:9333", "sw-master-1.sw-master-peer.default:9333", "sw-master-2.sw-master-peer.default:9333"}
+	output := `{"IsLeader":true,"Peers":["sw-master-1.sw-master-peer.default:9333"],"MaxVolumeId":7}
+wget: can't connect to remote host: Connection refused
+`
+	statuses, errs := parseClusterStatuses(output, masters)
+	if errs[0] != nil || !statuses[0].IsLeader || statuses[0].MaxVolumeID != 7 {
+		t.Errorf("master 0 = %+v, %v", statuses[0], errs[0])
+	}
+	if statuses[1] != nil || errs[1] == nil || !strings.Contains(errs[1].Error(), "Connection refused") {
+		t.Errorf("master 1 = %+v, %v", statuses[1], errs[1])
+	}
+	if statuses[2] != nil || errs[2] == nil {
+		t.Errorf("master 2 = %+v, %v, want no response", statuses[2], errs[2])
+	}
+}
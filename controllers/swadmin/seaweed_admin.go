@@ -1,51 +1,311 @@
 package swadmin
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net"
 	"regexp"
 	"strings"
+	"sync"
 
+	"github.com/chrislusf/seaweedfs/weed/pb"
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
 	"github.com/chrislusf/seaweedfs/weed/shell"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
-type SeaweedAdmin struct {
-	commandReg *regexp.Regexp
+var commandReg = regexp.MustCompile(`'.*?'|".*?"|\S+`)
+
+// connection is the shell environment of a set of masters, shared by all admins of the same masters.
+// The connection loop of the seaweedfs master client can not be stopped through its API,
+// so connections stay in the pool to be reused, and refuse to connect to masters no admin uses anymore.
+type connection struct {
+	masters    string
 	commandEnv *shell.CommandEnv
-	Output     io.Writer
+
+	// connected is closed once the master client found the master leader the first time
+	connected chan struct{}
+
+	// commandSem serializes the commands, lockSem serializes the WithLock sections
+	commandSem chan struct{}
+	lockSem    chan struct{}
 }
 
-func NewSeaweedAdmin(masters string, output io.Writer) *SeaweedAdmin {
+var (
+	connectionsLock sync.Mutex
+	connections     = make(map[string]*connection)
+
+	// dialable counts the admins per master gRPC address, guarded by connectionsLock.
+	// The seaweedfs library caches one gRPC connection per address for all connections, so dialing is allowed per address.
+	dialable = make(map[string]int)
+)
+
+// errConnectionClosed makes the seaweedfs library drop its cached gRPC connection, it looks for "connection closed"
+var errConnectionClosed = errors.New("swadmin: connection closed")
+
+func connect(masters string) *connection {
+	connectionsLock.Lock()
+	defer connectionsLock.Unlock()
+
+	for _, master := range pb.ServerAddresses(masters).ToAddresses() {
+		dialable[master.ToGrpcAddress()]++
+	}
+	if conn, found := connections[masters]; found {
+		return conn
+	}
+
 	var shellOptions shell.ShellOptions
-	shellOptions.GrpcDialOption = grpc.WithInsecure()
+	shellOptions.GrpcDialOption = grpc.WithTransportCredentials(masterCredentials{insecure.NewCredentials()})
 	shellOptions.Masters = &masters
 
-	commandEnv := shell.NewCommandEnv(shellOptions)
-	reg, _ := regexp.Compile(`'.*?'|".*?"|\S+`)
+	conn := &connection{
+		masters:    masters,
+		commandEnv: shell.NewCommandEnv(shellOptions),
+		connected:  make(chan struct{}),
+		commandSem: make(chan struct{}, 1),
+		lockSem:    make(chan struct{}, 1),
+	}
+	go conn.commandEnv.MasterClient.LoopConnectToMaster()
+	go func() {
+		conn.commandEnv.MasterClient.WaitUntilConnected()
+		close(conn.connected)
+	}()
+
+	connections[masters] = conn
+	return conn
+}
+
+// release drops an admin of the connection. With the last admin of a master, the master is refused,
+// and its cached gRPC connection is closed, so the connection loop idles until an admin uses the master again.
+func (conn *connection) release() {
+	connectionsLock.Lock()
+	defer connectionsLock.Unlock()
+
+	for _, master := range pb.ServerAddresses(conn.masters).ToAddresses() {
+		address := master.ToGrpcAddress()
+		if dialable[address]--; dialable[address] > 0 {
+			continue
+		}
+		delete(dialable, address)
+		// refused by the credentials if the connection is not cached
+		pb.WithCachedGrpcClient(func(*grpc.ClientConn) error {
+			return errConnectionClosed
+		}, address, grpc.WithTransportCredentials(masterCredentials{insecure.NewCredentials()}))
+	}
+}
+
+// masterCredentials are insecure transport credentials, which refuse the masters no admin uses anymore
+type masterCredentials struct {
+	credentials.TransportCredentials
+}
+
+func (c masterCredentials) ClientHandshake(ctx context.Context, authority string, rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	connectionsLock.Lock()
+	inUse := dialable[authority] > 0
+	connectionsLock.Unlock()
+	if !inUse {
+		rawConn.Close()
+		return nil, nil, errConnectionClosed
+	}
+	return c.TransportCredentials.ClientHandshake(ctx, authority, rawConn)
+}
+
+func (c masterCredentials) Clone() credentials.TransportCredentials {
+	return masterCredentials{c.TransportCredentials.Clone()}
+}
+
+// SeaweedAdmin is the Admin backed by the weed shell commands of the seaweedfs library
+type SeaweedAdmin struct {
+	masters string
+	options Options
+	conn    *connection
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
 
-	go commandEnv.MasterClient.LoopConnectToMaster()
+var _ Admin = &SeaweedAdmin{}
 
+// NewSeaweedAdmin returns an admin of the masters, connecting in the background
+func NewSeaweedAdmin(masters string, options Options) *SeaweedAdmin {
 	return &SeaweedAdmin{
-		commandEnv: commandEnv,
-		commandReg: reg,
-		Output:     output,
+		masters: masters,
+		options: options,
+		conn:    connect(masters),
+		closed:  make(chan struct{}),
+	}
+}
+
+func (sa *SeaweedAdmin) Masters() string {
+	return sa.masters
+}
+
+// Close cancels the running commands of this admin and fails later calls.
+// The connection to the masters is torn down with the last admin of the same masters.
+func (sa *SeaweedAdmin) Close() error {
+	sa.closeOnce.Do(func() {
+		close(sa.closed)
+		sa.conn.release()
+	})
+	return nil
+}
+
+// context derives a context which is canceled by Close and bounded by the command timeout
+func (sa *SeaweedAdmin) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if sa.options.CommandTimeout > 0 {
+		ctx, cancel := context.WithTimeout(ctx, sa.options.CommandTimeout)
+		return sa.closable(ctx, cancel)
 	}
+	ctx, cancel := context.WithCancel(ctx)
+	return sa.closable(ctx, cancel)
+}
+
+func (sa *SeaweedAdmin) closable(ctx context.Context, cancel context.CancelFunc) (context.Context, context.CancelFunc) {
+	go func() {
+		select {
+		case <-sa.closed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+func (sa *SeaweedAdmin) err(ctx context.Context) error {
+	select {
+	case <-sa.closed:
+		return ErrClosed
+	default:
+	}
+	return ctx.Err()
+}
+
+// waitUntilConnected waits for the master client to find the master leader, up to the connect timeout.
+// Once connected, the library waits for the leader itself when a command needs it.
+func (sa *SeaweedAdmin) waitUntilConnected(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, sa.options.connectTimeout())
+	defer cancel()
+
+	select {
+	case <-sa.conn.connected:
+		return nil
+	case <-ctx.Done():
+		if err := sa.err(ctx); err == ErrClosed {
+			return err
+		}
+		return fmt.Errorf("not connected to masters %s: %v", sa.masters, ctx.Err())
+	}
+}
+
+// acquire takes the semaphore unless ctx is done first
+func (sa *SeaweedAdmin) acquire(ctx context.Context, sem chan struct{}) error {
+	select {
+	case sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return sa.err(ctx)
+	}
+}
+
+// run calls fn in the background and returns early when ctx is done.
+// The seaweedfs library has no cancellation, so an abandoned fn keeps the command slot until it returns,
+// and the following commands, the unlock of WithLock included, wait for it.
+func (sa *SeaweedAdmin) run(ctx context.Context, fn func() error) error {
+	if err := sa.waitUntilConnected(ctx); err != nil {
+		return err
+	}
+	if err := sa.acquire(ctx, sa.conn.commandSem); err != nil {
+		return err
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		defer func() { <-sa.conn.commandSem }()
+		result <- fn()
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return sa.err(ctx)
+	}
+}
+
+func (sa *SeaweedAdmin) VolumeList(ctx context.Context) (*Topology, error) {
+	ctx, cancel := sa.context(ctx)
+	defer cancel()
+
+	var resp *master_pb.VolumeListResponse
+	err := sa.run(ctx, func() error {
+		return sa.conn.commandEnv.MasterClient.WithClient(func(client master_pb.SeaweedClient) error {
+			var err error
+			resp, err = client.VolumeList(ctx, &master_pb.VolumeListRequest{})
+			return err
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return NewTopology(resp.TopologyInfo, resp.VolumeSizeLimitMb), nil
+}
+
+// ClusterStatus queries the masters over HTTP, the gRPC admin is only used when the operator reaches the masters
+func (sa *SeaweedAdmin) ClusterStatus(ctx context.Context) ([]*ClusterStatus, []error) {
+	ctx, cancel := sa.context(ctx)
+	defer cancel()
+	return fetchClusterStatuses(ctx, strings.Split(sa.masters, ","), FetchClusterStatus)
+}
+
+func (sa *SeaweedAdmin) WithLock(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, cancel := sa.context(ctx)
+	defer cancel()
+
+	if err := sa.acquire(ctx, sa.conn.lockSem); err != nil {
+		return err
+	}
+
+	if err := sa.ProcessCommand(ctx, "lock", ioutil.Discard); err != nil {
+		<-sa.conn.lockSem
+		return err
+	}
+	// the unlock waits for the command slot of an abandoned command, so the shell lock is held until it returned.
+	// The caller does not wait for it.
+	defer func() {
+		go func() {
+			defer func() { <-sa.conn.lockSem }()
+			sa.processCommand(context.Background(), "unlock", ioutil.Discard)
+		}()
+	}()
+	return fn(ctx)
 }
 
 // ProcessCommands cmds can be semi-colon separated commands
-func (sa *SeaweedAdmin) ProcessCommands(cmds string) error {
+func (sa *SeaweedAdmin) ProcessCommands(ctx context.Context, cmds string, output io.Writer) error {
 	for _, c := range strings.Split(cmds, ";") {
-		if err := sa.ProcessCommand(c); err != nil {
+		if err := sa.ProcessCommand(ctx, c, output); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (sa *SeaweedAdmin) ProcessCommand(cmd string) error {
-	sa.commandEnv.MasterClient.WaitUntilConnected()
-	cmds := sa.commandReg.FindAllString(cmd, -1)
+func (sa *SeaweedAdmin) ProcessCommand(ctx context.Context, cmd string, output io.Writer) error {
+	ctx, cancel := sa.context(ctx)
+	defer cancel()
+	return sa.processCommand(ctx, cmd, output)
+}
+
+func (sa *SeaweedAdmin) processCommand(ctx context.Context, cmd string, output io.Writer) error {
+	if err := CheckCommand(cmd); err != nil {
+		return err
+	}
+	cmds := commandReg.FindAllString(cmd, -1)
 	if len(cmds) == 0 {
 		return nil
 	}
@@ -58,7 +318,9 @@ func (sa *SeaweedAdmin) ProcessCommand(cmd string) error {
 
 	for _, c := range shell.Commands {
 		if c.Name() == cmds[0] || c.Name() == "fs."+cmds[0] {
-			return c.Do(args, sa.commandEnv, sa.Output)
+			return sa.run(ctx, func() error {
+				return c.Do(args, sa.conn.commandEnv, output)
+			})
 		}
 	}
 
@@ -0,0 +1,90 @@
+package swadmin
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestSeaweedAdminConnectionReleased(t *testing.T) {
+	// nothing listens on the gRPC port 10001
+	const masters = "127.0.0.1:1"
+	options := Options{ConnectTimeout: 200 * time.Millisecond}
+
+	first := NewSeaweedAdmin(masters, options)
+	second := NewSeaweedAdmin(masters, options)
+	if first.conn != second.conn {
+		t.Fatalf("admins of the same masters do not share the connection")
+	}
+	conn := first.conn
+
+	if _, err := first.VolumeList(context.Background()); err == nil || !strings.Contains(err.Error(), "not connected to masters") {
+		t.Errorf("volume list error = %v", err)
+	}
+
+	first.Close()
+	first.Close()
+	if _, err := first.VolumeList(context.Background()); err != ErrClosed {
+		t.Errorf("volume list after close = %v, want ErrClosed", err)
+	}
+	handshake := func() error {
+		client, server := net.Pipe()
+		defer server.Close()
+		creds := masterCredentials{insecure.NewCredentials()}
+		_, _, err := creds.Clone().ClientHandshake(context.Background(), "127.0.0.1:10001", client)
+		return err
+	}
+	if err := handshake(); err != nil {
+		t.Errorf("handshake while an admin uses the masters = %v", err)
+	}
+
+	second.Close()
+	if err := handshake(); err != errConnectionClosed {
+		t.Errorf("handshake after the last admin closed = %v, want errConnectionClosed", err)
+	}
+
+	third := NewSeaweedAdmin(masters, options)
+	defer third.Close()
+	if third.conn != conn {
+		t.Errorf("connection of the masters is not reused")
+	}
+	if err := handshake(); err != nil {
+		t.Errorf("handshake after the masters are used again = %v", err)
+	}
+}
+
+func TestSeaweedAdminAbandonedCommand(t *testing.T) {
+	connected := make(chan struct{})
+	close(connected)
+	sa := &SeaweedAdmin{
+		masters: "127.0.0.1:1",
+		conn: &connection{
+			connected:  connected,
+			commandSem: make(chan struct{}, 1),
+			lockSem:    make(chan struct{}, 1),
+		},
+		closed: make(chan struct{}),
+	}
+	run := func(timeout time.Duration, fn func() error) error {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		return sa.run(ctx, fn)
+	}
+
+	release := make(chan struct{})
+	if err := run(50*time.Millisecond, func() error { <-release; return nil }); err != context.DeadlineExceeded {
+		t.Fatalf("abandoned command = %v, want context.DeadlineExceeded", err)
+	}
+	// the abandoned command still holds the command slot
+	if err := run(50*time.Millisecond, func() error { return nil }); err != context.DeadlineExceeded {
+		t.Errorf("command next to the abandoned one = %v, want context.DeadlineExceeded", err)
+	}
+	close(release)
+	if err := run(5*time.Second, func() error { return nil }); err != nil {
+		t.Errorf("command after the abandoned one returned = %v", err)
+	}
+}
@@ -0,0 +1,379 @@
+package swadmin
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+	"github.com/chrislusf/seaweedfs/weed/storage/erasure_coding"
+)
+
+// Topology is the volume layout reported by the master leader, as printed by volume.list
+type Topology struct {
+	VolumeSizeLimitMB uint64
+	DataCenters       []DataCenter
+}
+
+// DataCenter groups racks
+type DataCenter struct {
+	ID    string
+	Racks []Rack
+}
+
+// Rack groups volume servers
+type Rack struct {
+	ID    string
+	Nodes []DataNode
+}
+
+// DataNode is a volume server, its ID is the host:port it registered with
+type DataNode struct {
+	ID    string
+	Disks []Disk
+}
+
+// Disk is a disk type of a volume server with its volumes and EC shards
+type Disk struct {
+	// Type is "hdd", "ssd" or a custom disk type
+	Type            string
+	VolumeCount     int64
+	MaxVolumeCount  int64
+	FreeVolumeCount int64
+	Volumes         []Volume
+	ECShards        []ECShards
+}
+
+// Volume is one replica of a volume
+type Volume struct {
+	ID               uint32
+	Collection       string
+	Size             uint64
+	FileCount        uint64
+	DeleteCount      uint64
+	DeletedByteCount uint64
+	// ReplicaPlacement in the "xyz" form, e.g. "001"
+	ReplicaPlacement string
+	ReadOnly         bool
+	Version          uint32
+	DiskType         string
+}
+
+// ECShards are the shards of an EC volume held by one disk
+type ECShards struct {
+	VolumeID   uint32
+	Collection string
+	ShardIDs   []uint32
+}
+
+// Nodes returns all volume servers
+func (t *Topology) Nodes() []*DataNode {
+	var nodes []*DataNode
+	for i := range t.DataCenters {
+		for j := range t.DataCenters[i].Racks {
+			rack := &t.DataCenters[i].Racks[j]
+			for k := range rack.Nodes {
+				nodes = append(nodes, &rack.Nodes[k])
+			}
+		}
+	}
+	return nodes
+}
+
+// Node returns the volume server with the given host:port, or nil if it is not registered
+func (t *Topology) Node(id string) *DataNode {
+	for _, node := range t.Nodes() {
+		if node.ID == id {
+			return node
+		}
+	}
+	return nil
+}
+
+// VolumeLocations returns the volume servers holding a replica of the volume
+func (t *Topology) VolumeLocations(id uint32) []string {
+	var locations []string
+	for _, node := range t.Nodes() {
+		for _, v := range node.Volumes() {
+			if v.ID == id {
+				locations = append(locations, node.ID)
+				break
+			}
+		}
+	}
+	return locations
+}
+
+// ECShardLocations maps the shard ids of the EC volume to the volume servers holding them
+func (t *Topology) ECShardLocations(id uint32) map[uint32][]string {
+	locations := make(map[uint32][]string)
+	for _, node := range t.Nodes() {
+		for _, disk := range node.Disks {
+			for _, shards := range disk.ECShards {
+				if shards.VolumeID != id {
+					continue
+				}
+				for _, shardID := range shards.ShardIDs {
+					locations[shardID] = append(locations[shardID], node.ID)
+				}
+			}
+		}
+	}
+	return locations
+}
+
+// Volumes returns the volume replicas on all disks of the node
+func (n *DataNode) Volumes() []Volume {
+	var volumes []Volume
+	for _, disk := range n.Disks {
+		volumes = append(volumes, disk.Volumes...)
+	}
+	return volumes
+}
+
+// VolumeIDs returns the ids of the volumes on the node
+func (n *DataNode) VolumeIDs() []uint32 {
+	var ids []uint32
+	for _, v := range n.Volumes() {
+		ids = append(ids, v.ID)
+	}
+	return ids
+}
+
+// ECShardCount counts the EC shards on all disks of the node
+func (n *DataNode) ECShardCount() int {
+	count := 0
+	for _, disk := range n.Disks {
+		for _, shards := range disk.ECShards {
+			count += len(shards.ShardIDs)
+		}
+	}
+	return count
+}
+
+// NewTopology converts the topology received from the master
+func NewTopology(info *master_pb.TopologyInfo, volumeSizeLimitMB uint64) *Topology {
+	t := &Topology{VolumeSizeLimitMB: volumeSizeLimitMB}
+	if info == nil {
+		return t
+	}
+	for _, dcInfo := range info.DataCenterInfos {
+		dc := DataCenter{ID: dcInfo.Id}
+		for _, rackInfo := range dcInfo.RackInfos {
+			rack := Rack{ID: rackInfo.Id}
+			for _, nodeInfo := range rackInfo.DataNodeInfos {
+				node := DataNode{ID: nodeInfo.Id}
+				for _, diskInfo := range nodeInfo.DiskInfos {
+					node.Disks = append(node.Disks, newDisk(diskInfo))
+				}
+				sort.Slice(node.Disks, func(i, j int) bool { return node.Disks[i].Type < node.Disks[j].Type })
+				rack.Nodes = append(rack.Nodes, node)
+			}
+			dc.Racks = append(dc.Racks, rack)
+		}
+		t.DataCenters = append(t.DataCenters, dc)
+	}
+	return t
+}
+
+func newDisk(info *master_pb.DiskInfo) Disk {
+	disk := Disk{
+		Type:            diskType(info.Type),
+		VolumeCount:     info.VolumeCount,
+		MaxVolumeCount:  info.MaxVolumeCount,
+		FreeVolumeCount: info.FreeVolumeCount,
+	}
+	for _, v := range info.VolumeInfos {
+		disk.Volumes = append(disk.Volumes, Volume{
+			ID:               v.Id,
+			Collection:       v.Collection,
+			Size:             v.Size,
+			FileCount:        v.FileCount,
+			DeleteCount:      v.DeleteCount,
+			DeletedByteCount: v.DeletedByteCount,
+			ReplicaPlacement: fmt.Sprintf("%03d", v.ReplicaPlacement),
+			ReadOnly:         v.ReadOnly,
+			Version:          v.Version,
+			DiskType:         diskType(v.DiskType),
+		})
+	}
+	for _, ec := range info.EcShardInfos {
+		shards := ECShards{VolumeID: ec.Id, Collection: ec.Collection}
+		for _, shardID := range erasure_coding.ShardBits(ec.EcIndexBits).ShardIds() {
+			shards.ShardIDs = append(shards.ShardIDs, uint32(shardID))
+		}
+		disk.ECShards = append(disk.ECShards, shards)
+	}
+	return disk
+}
+
+func diskType(t string) string {
+	if t == "" {
+		return "hdd"
+	}
+	return t
+}
+
+var (
+	fieldReg      = regexp.MustCompile(`(\w+):("(?:[^"\\]|\\.)*"|\[[^\]]*\]|\S+)`)
+	diskHeaderReg = regexp.MustCompile(`^Disk (\S+)\((.*)\)$`)
+)
+
+// ParseVolumeList parses the output of the volume.list command
+func ParseVolumeList(output string) (*Topology, error) {
+	t := &Topology{}
+	var dc *DataCenter
+	var rack *Rack
+	var node *DataNode
+	var disk *Disk
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		// the closing lines of every level carry the statistics
+		if line == "" || strings.Contains(line, "total size:") {
+			continue
+		}
+		kind, rest := line, ""
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			kind, rest = line[:i], line[i+1:]
+		}
+
+		switch kind {
+		case "Topology":
+			fields := parseFields(rest)
+			limit := strings.TrimSuffix(fields["volumeSizeLimit"], "MB")
+			t.VolumeSizeLimitMB, _ = strconv.ParseUint(strings.TrimSpace(limit), 10, 64)
+		case "DataCenter":
+			t.DataCenters = append(t.DataCenters, DataCenter{ID: firstField(rest)})
+			dc, rack, node, disk = &t.DataCenters[len(t.DataCenters)-1], nil, nil, nil
+		case "Rack":
+			if dc == nil {
+				return nil, fmt.Errorf("line %d: rack outside of a data center", lineNumber)
+			}
+			dc.Racks = append(dc.Racks, Rack{ID: firstField(rest)})
+			rack, node, disk = &dc.Racks[len(dc.Racks)-1], nil, nil
+		case "DataNode":
+			if rack == nil {
+				return nil, fmt.Errorf("line %d: data node outside of a rack", lineNumber)
+			}
+			rack.Nodes = append(rack.Nodes, DataNode{ID: firstField(rest)})
+			node, disk = &rack.Nodes[len(rack.Nodes)-1], nil
+		case "Disk":
+			if node == nil {
+				return nil, fmt.Errorf("line %d: disk outside of a data node", lineNumber)
+			}
+			m := diskHeaderReg.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("line %d: unexpected disk %q", lineNumber, line)
+			}
+			fields := parseFields(m[2])
+			newDisk := Disk{Type: m[1]}
+			newDisk.VolumeCount, newDisk.MaxVolumeCount = parseRatio(fields["volume"])
+			newDisk.FreeVolumeCount, _ = strconv.ParseInt(fields["free"], 10, 64)
+			node.Disks = append(node.Disks, newDisk)
+			disk = &node.Disks[len(node.Disks)-1]
+		case "volume":
+			if disk == nil {
+				return nil, fmt.Errorf("line %d: volume outside of a disk", lineNumber)
+			}
+			v, err := parseVolume(parseFields(rest))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+			}
+			disk.Volumes = append(disk.Volumes, v)
+		case "ec":
+			if disk == nil {
+				return nil, fmt.Errorf("line %d: ec shards outside of a disk", lineNumber)
+			}
+			shards, err := parseECShards(parseFields(strings.TrimPrefix(rest, "volume ")))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+			}
+			disk.ECShards = append(disk.ECShards, shards)
+		default:
+			return nil, fmt.Errorf("line %d: unexpected %q", lineNumber, line)
+		}
+	}
+	return t, scanner.Err()
+}
+
+func parseVolume(fields map[string]string) (Volume, error) {
+	id, err := strconv.ParseUint(fields["id"], 10, 32)
+	if err != nil {
+		return Volume{}, fmt.Errorf("volume id %q: %v", fields["id"], err)
+	}
+	v := Volume{
+		ID:               uint32(id),
+		Collection:       fields["collection"],
+		ReadOnly:         fields["read_only"] == "true",
+		ReplicaPlacement: "000",
+		DiskType:         diskType(fields["disk_type"]),
+	}
+	v.Size, _ = strconv.ParseUint(fields["size"], 10, 64)
+	v.FileCount, _ = strconv.ParseUint(fields["file_count"], 10, 64)
+	v.DeleteCount, _ = strconv.ParseUint(fields["delete_count"], 10, 64)
+	v.DeletedByteCount, _ = strconv.ParseUint(fields["deleted_byte_count"], 10, 64)
+	if rp, err := strconv.ParseUint(fields["replica_placement"], 10, 32); err == nil {
+		v.ReplicaPlacement = fmt.Sprintf("%03d", rp)
+	}
+	if version, err := strconv.ParseUint(fields["version"], 10, 32); err == nil {
+		v.Version = uint32(version)
+	}
+	return v, nil
+}
+
+func parseECShards(fields map[string]string) (ECShards, error) {
+	id, err := strconv.ParseUint(fields["id"], 10, 32)
+	if err != nil {
+		return ECShards{}, fmt.Errorf("ec volume id %q: %v", fields["id"], err)
+	}
+	shards := ECShards{VolumeID: uint32(id), Collection: fields["collection"]}
+	for _, s := range strings.Fields(strings.Trim(fields["shards"], "[]")) {
+		shardID, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return ECShards{}, fmt.Errorf("ec shard id %q: %v", s, err)
+		}
+		shards.ShardIDs = append(shards.ShardIDs, uint32(shardID))
+	}
+	return shards, nil
+}
+
+// parseFields parses the key:value pairs of a line, unquoting quoted values
+func parseFields(s string) map[string]string {
+	fields := make(map[string]string)
+	for _, m := range fieldReg.FindAllStringSubmatch(s, -1) {
+		value := m[2]
+		if strings.HasPrefix(value, `"`) {
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				value = unquoted
+			}
+		}
+		fields[m[1]] = value
+	}
+	return fields
+}
+
+// parseRatio parses "3/8" into 3 and 8
+func parseRatio(s string) (int64, int64) {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return 0, 0
+	}
+	a, _ := strconv.ParseInt(parts[0], 10, 64)
+	b, _ := strconv.ParseInt(parts[1], 10, 64)
+	return a, b
+}
+
+func firstField(s string) string {
+	if fields := strings.Fields(s); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
@@ -0,0 +1,74 @@
+package swadmin
+
+import (
+	"reflect"
+	"testing"
+)
+
+const volumeListOutput = `Topology volumeSizeLimit:1024 MB hdd(volume:3/16 active:3 free:13 remote:0)
+  DataCenter dc1 hdd(volume:3/16 active:3 free:13 remote:0)
+    Rack rack1 hdd(volume:3/16 active:3 free:13 remote:0)
+      DataNode sw-volume-0.sw-volume-peer.default:8444 hdd(volume:2/8 active:2 free:6 remote:0)
+        Disk hdd(volume:2/8 active:2 free:6 remote:0)
+          volume id:1  size:1048576  collection:"pics"  file_count:12  delete_count:1  deleted_byte_count:512  replica_placement:1  version:3  modified_at_second:1633000000 
+          volume id:2  size:8  read_only:true  version:3  modified_at_second:1633000000 
+          ec volume id:7 collection:pics shards:[0 1 2 3]
+        Disk hdd total size:1048584 file_count:12 deleted_file:1 deleted_bytes:512
+      DataNode sw-volume-0.sw-volume-peer.default:8444 total size:1048584 file_count:12 deleted_file:1 deleted_bytes:512
+      DataNode sw-volume-1.sw-volume-peer.default:8444 hdd(volume:1/8 active:1 free:7 remote:0)
+        Disk hdd(volume:1/8 active:1 free:7 remote:0)
+          volume id:1  size:1048576  collection:"pics"  file_count:12  replica_placement:1  version:3  modified_at_second:1633000000 
+          ec volume id:7 collection:pics shards:[4 5]
+        Disk hdd total size:1048576 file_count:12 deleted_file:0 deleted_bytes:0
+      DataNode sw-volume-1.sw-volume-peer.default:8444 total size:1048576 file_count:12 deleted_file:0 deleted_bytes:0
+    Rack rack1 total size:2097160 file_count:24 deleted_file:1 deleted_bytes:512
+  DataCenter dc1 total size:2097160 file_count:24 deleted_file:1 deleted_bytes:512
+total size:2097160 file_count:24 deleted_file:1 deleted_bytes:512
+`
+
+func TestParseVolumeList(t *testing.T) {
+	topology, err := ParseVolumeList(volumeListOutput)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if topology.VolumeSizeLimitMB != 1024 {
+		t.Errorf("volume size limit = %d, expected 1024", topology.VolumeSizeLimitMB)
+	}
+	if nodes := topology.Nodes(); len(nodes) != 2 {
+		t.Fatalf("%d nodes, expected 2", len(nodes))
+	}
+
+	node := topology.Node("sw-volume-0.sw-volume-peer.default:8444")
+	if node == nil {
+		t.Fatal("sw-volume-0 not found")
+	}
+	expectedDisk := Disk{
+		Type:            "hdd",
+		VolumeCount:     2,
+		MaxVolumeCount:  8,
+		FreeVolumeCount: 6,
+		Volumes: []Volume{
+			{ID: 1, Collection: "pics", Size: 1048576, FileCount: 12, DeleteCount: 1, DeletedByteCount: 512, ReplicaPlacement: "001", Version: 3, DiskType: "hdd"},
+			{ID: 2, Size: 8, ReplicaPlacement: "000", ReadOnly: true, Version: 3, DiskType: "hdd"},
+		},
+		ECShards: []ECShards{{VolumeID: 7, Collection: "pics", ShardIDs: []uint32{0, 1, 2, 3}}},
+	}
+	if !reflect.DeepEqual(node.Disks, []Disk{expectedDisk}) {
+		t.Errorf("disks = %+v, expected %+v", node.Disks, []Disk{expectedDisk})
+	}
+
+	locations := topology.VolumeLocations(1)
+	if !reflect.DeepEqual(locations, []string{"sw-volume-0.sw-volume-peer.default:8444", "sw-volume-1.sw-volume-peer.default:8444"}) {
+		t.Errorf("volume 1 locations = %q", locations)
+	}
+	if shards := topology.ECShardLocations(7); len(shards) != 6 || shards[5][0] != "sw-volume-1.sw-volume-peer.default:8444" {
+		t.Errorf("ec volume 7 shards = %v", shards)
+	}
+}
+
+func TestParseVolumeListRejectsUnexpectedLines(t *testing.T) {
+	if _, err := ParseVolumeList("volume id:1 size:8\n"); err == nil {
+		t.Error("expected an error for a volume outside of a disk")
+	}
+}
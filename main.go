@@ -30,6 +30,7 @@ import (
 
 	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
 	"github.com/seaweedfs/seaweedfs-operator/controllers"
+	"github.com/seaweedfs/seaweedfs-operator/controllers/swadmin"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -48,10 +49,14 @@ func init() {
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
+	var adminTransport string
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&adminTransport, "admin-transport", string(swadmin.TransportAuto),
+		"How weed shell commands reach the masters: grpc, exec (pods/exec into a master pod), "+
+			"or auto to use grpc when the masters are reachable.")
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
@@ -75,20 +80,42 @@ func main() {
 		os.Exit(1)
 	}
 
+	admins := swadmin.NewCache(mgr.GetConfig(), swadmin.Transport(adminTransport))
+	adminTasks := &controllers.AdminTaskTracker{}
+
 	if err = (&controllers.SeaweedReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("Seaweed"),
-		Scheme: mgr.GetScheme(),
+		Client:     mgr.GetClient(),
+		Log:        ctrl.Log.WithName("controllers").WithName("Seaweed"),
+		Scheme:     mgr.GetScheme(),
+		Recorder:   mgr.GetEventRecorderFor("seaweed-controller"),
+		Admins:     admins,
+		AdminTasks: adminTasks,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Seaweed")
 		os.Exit(1)
 	}
 
+	if err = (&controllers.SeaweedOperationReconciler{
+		Client:     mgr.GetClient(),
+		Log:        ctrl.Log.WithName("controllers").WithName("SeaweedOperation"),
+		Scheme:     mgr.GetScheme(),
+		Recorder:   mgr.GetEventRecorderFor("seaweedoperation-controller"),
+		Admins:     admins,
+		AdminTasks: adminTasks,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "SeaweedOperation")
+		os.Exit(1)
+	}
+
 	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
 		if err = (&seaweedv1.Seaweed{}).SetupWebhookWithManager(mgr); err != nil {
 			setupLog.Error(err, "unable to create webhook", "webhook", "Seaweed")
 			os.Exit(1)
 		}
+		if err = (&seaweedv1.SeaweedOperation{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "SeaweedOperation")
+			os.Exit(1)
+		}
 	}
 	// +kubebuilder:scaffold:builder
 